@@ -1,7 +1,9 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,32 +11,54 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	// provider timezones are resolved even where the host has no zoneinfo
+	_ "time/tzdata"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 
-	"github.com/robfig/cron"
-
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain/admin"
+	"github.com/set-kaung/senior_project_1/internal/domain/availability"
+	"github.com/set-kaung/senior_project_1/internal/domain/category"
+	"github.com/set-kaung/senior_project_1/internal/domain/ledger"
 	"github.com/set-kaung/senior_project_1/internal/domain/listing"
+	"github.com/set-kaung/senior_project_1/internal/domain/message"
+	"github.com/set-kaung/senior_project_1/internal/domain/notification"
 	"github.com/set-kaung/senior_project_1/internal/domain/review"
 	"github.com/set-kaung/senior_project_1/internal/domain/reward"
+	"github.com/set-kaung/senior_project_1/internal/geo"
 	"github.com/set-kaung/senior_project_1/internal/helpers"
+	"github.com/set-kaung/senior_project_1/internal/jobs"
+	"github.com/set-kaung/senior_project_1/internal/mail"
+	"github.com/set-kaung/senior_project_1/internal/outbox"
+	"github.com/set-kaung/senior_project_1/internal/realtime"
 
 	"github.com/set-kaung/senior_project_1/internal/domain/request"
 	"github.com/set-kaung/senior_project_1/internal/domain/user"
 )
 
 type application struct {
-	userHandler    *user.UserHandler
-	listingHandler *listing.ListingHandler
-	requestHandler *request.RequestHandler
-	rewardHandler  *reward.RewardHandler
-	reviewHandler  *review.ReviewHandler
+	userHandler         *user.UserHandler
+	listingHandler      *listing.ListingHandler
+	requestHandler      *request.RequestHandler
+	rewardHandler       *reward.RewardHandler
+	reviewHandler       *review.ReviewHandler
+	ledgerHandler       *ledger.LedgerHandler
+	adminHandler        *admin.AdminHandler
+	categoryHandler     *category.CategoryHandler
+	availabilityHandler *availability.AvailabilityHandler
+	messageHandler      *message.MessageHandler
+	notificationHandler *notification.NotificationHandler
+	jobHandler          *jobs.JobHandler
+	idempotencyStore    internal.IdempotencyStore
+	// eventHub serves GET /events/stream when events are self-hosted
+	eventHub *realtime.Hub
 }
 
 func main() {
@@ -77,44 +101,306 @@ func main() {
 		port = "8080"
 	}
 	tokenReward := os.Getenv("ONETIME_PAYMENT_TOKENS")
-	_, err = strconv.Atoi(tokenReward)
+	signupBonus, err := strconv.Atoi(tokenReward)
 	if err != nil {
 		panic(err)
 	}
 
-	internal.PusherClient = internal.NewPusherClient()
+	reminderLead := 24 * time.Hour
+	if v := os.Getenv("SESSION_REMINDER_LEAD"); v != "" {
+		reminderLead, err = time.ParseDuration(v)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	pendingReminderLeads := []time.Duration{12 * time.Hour, 2 * time.Hour}
+	if v := os.Getenv("PENDING_REMINDER_LEADS"); v != "" {
+		pendingReminderLeads = nil
+		for _, s := range strings.Split(v, ",") {
+			lead, err := time.ParseDuration(strings.TrimSpace(s))
+			if err != nil {
+				panic(err)
+			}
+			if lead <= 0 {
+				log.Fatalf("PENDING_REMINDER_LEADS: %s is not positive", lead)
+			}
+			pendingReminderLeads = append(pendingReminderLeads, lead)
+		}
+	}
+
+	expiry := request.DEFAULT_EXPIRY_POLICY
+	for name, d := range map[string]*time.Duration{
+		"PENDING_TIMEOUT":     &expiry.PendingTimeout,
+		"IN_PROGRESS_TIMEOUT": &expiry.InProgressTimeout,
+		"AUTO_COMPLETE_AFTER": &expiry.AutoCompleteAfter,
+	} {
+		if v := os.Getenv(name); v != "" {
+			*d, err = time.ParseDuration(v)
+			if err != nil {
+				panic(err)
+			}
+			if *d < 0 {
+				log.Fatalf("%s: %s is negative", name, *d)
+			}
+		}
+	}
+
+	ads := user.DEFAULT_AD_POLICY
+	if v := os.Getenv("DAILY_ADS_LIMIT"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			panic(err)
+		}
+		if limit < 0 {
+			log.Fatalf("DAILY_ADS_LIMIT: %d is negative", limit)
+		}
+		ads.DailyLimit = int32(limit)
+	}
+	switch v := os.Getenv("ADS_LIMIT_WINDOW"); v {
+	case "", user.AD_WINDOW_ROLLING, user.AD_WINDOW_CALENDAR:
+		ads.Window = cmp.Or(v, ads.Window)
+	default:
+		log.Fatalf("unknown ADS_LIMIT_WINDOW %q, use rolling or calendar", v)
+	}
+	if v := os.Getenv("ADS_LIMIT_TIMEZONE"); v != "" {
+		ads.Location, err = time.LoadLocation(v)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if v := os.Getenv("AD_REWARD_TOKENS"); v != "" {
+		reward, err := strconv.Atoi(v)
+		if err != nil {
+			panic(err)
+		}
+		if reward <= 0 {
+			log.Fatalf("AD_REWARD_TOKENS: %d is not positive", reward)
+		}
+		ads.Reward = int32(reward)
+	}
+	if v := os.Getenv("AD_REWARDS"); v != "" {
+		ads.Rewards = map[string]int32{}
+		for _, s := range strings.Split(v, ",") {
+			unit, tokens, ok := strings.Cut(strings.TrimSpace(s), "=")
+			if !ok || unit == "" {
+				log.Fatalf("AD_REWARDS: %q is not unit=tokens", s)
+			}
+			reward, err := strconv.Atoi(tokens)
+			if err != nil {
+				panic(err)
+			}
+			if reward <= 0 {
+				log.Fatalf("AD_REWARDS: %s pays %d, which is not positive", unit, reward)
+			}
+			ads.Rewards[unit] = int32(reward)
+		}
+	}
+
+	reportThreshold := 3
+	if v := os.Getenv("LISTING_REPORT_THRESHOLD"); v != "" {
+		reportThreshold, err = strconv.Atoi(v)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	outboxInterval := 2 * time.Second
+	if v := os.Getenv("OUTBOX_POLL_INTERVAL"); v != "" {
+		outboxInterval, err = time.ParseDuration(v)
+		if err != nil {
+			panic(err)
+		}
+	}
 
 	a := &application{}
 
-	psqlUserService := &user.PostgresUserService{DB: dbpool}
-	psqlListingService := &listing.PostgresListingService{DB: dbpool}
-	psqlRequestService := &request.PostgresRequestService{DB: dbpool}
+	var notifier realtime.Notifier
+	var broadcast *realtime.Broadcast
+	switch backend := os.Getenv("NOTIFIER"); {
+	case backend == "sse", backend == "" && !realtime.PusherConfigured():
+		a.eventHub = realtime.NewHub()
+		broadcast = &realtime.Broadcast{DB: dbpool, Hub: a.eventHub}
+		notifier = broadcast
+		log.Println("realtime events are streamed from GET /events/stream")
+	case backend == "pusher", backend == "":
+		notifier, err = realtime.NewPusher()
+		if err != nil {
+			log.Fatalln("failed to configure pusher:", err)
+		}
+	case backend == "memory":
+		notifier = &realtime.Memory{}
+		log.Println("realtime events are recorded in memory and not delivered")
+	default:
+		log.Fatalf("unknown NOTIFIER %q, use pusher, sse or memory", backend)
+	}
+	mailFrom := os.Getenv("MAIL_FROM")
+	var mailer mail.Mailer
+	switch backend := os.Getenv("MAILER"); {
+	case backend == "smtp", backend == "" && mail.SMTPConfigured():
+		mailer, err = mail.NewSMTP()
+		if err != nil {
+			log.Fatalln("failed to configure smtp:", err)
+		}
+	case backend == "file", backend == "":
+		mailer = &mail.FileMailer{Dir: os.Getenv("MAIL_DIR")}
+		if mailFrom == "" {
+			mailFrom = "Ontime <no-reply@localhost>"
+		}
+		log.Println("emails are written to", cmp.Or(os.Getenv("MAIL_DIR"), "stdout"))
+	default:
+		log.Fatalf("unknown MAILER %q, use smtp or file", backend)
+	}
+	dispatcher := &outbox.Dispatcher{
+		DB:       dbpool,
+		Notifier: notifier,
+		Mail:     &mail.Sender{Mailer: mailer, Directory: mail.ClerkDirectory{}, From: mailFrom},
+	}
+
+	psqlUserService := &user.PostgresUserService{DB: dbpool, Geocoder: geo.StaticGeocoder{}, Ads: ads}
+	if os.Getenv("GEOCODER") == "nominatim" {
+		nominatimURL := os.Getenv("NOMINATIM_URL")
+		if nominatimURL == "" {
+			nominatimURL = "https://nominatim.openstreetmap.org"
+		}
+		psqlUserService.Geocoder = &geo.NominatimGeocoder{BaseURL: nominatimURL, UserAgent: "ontime-server"}
+	}
+	psqlListingService := &listing.PostgresListingService{DB: dbpool, ReportThreshold: int32(reportThreshold)}
+	psqlRequestService := &request.PostgresRequestService{DB: dbpool, Expiry: expiry}
 	psqlRewardService := &reward.PostgresRewardService{DB: dbpool}
 	psqlReviewService := &review.PostgresReviewService{DB: dbpool}
+	psqlLedgerService := &ledger.PostgresLedgerService{DB: dbpool}
+	psqlAdminService := &admin.PostgresAdminService{DB: dbpool}
+	psqlCategoryService := &category.PostgresCategoryService{DB: dbpool}
+	psqlAvailabilityService := &availability.PostgresAvailabilityService{DB: dbpool}
+	psqlMessageService := &message.PostgresMessageService{DB: dbpool}
+	psqlNotificationService := &notification.PostgresNotificationService{DB: dbpool}
+	idempotencyStore := &internal.PostgresIdempotencyStore{DB: dbpool}
 
 	a.userHandler = &user.UserHandler{UserService: psqlUserService}
 	a.listingHandler = &listing.ListingHandler{ListingService: psqlListingService}
-	a.requestHandler = &request.RequestHandler{RequestService: psqlRequestService}
+	a.requestHandler = &request.RequestHandler{RequestService: psqlRequestService, PublicURL: os.Getenv("PUBLIC_URL")}
 	a.rewardHandler = &reward.RewardHandler{RewardService: psqlRewardService}
 	a.reviewHandler = &review.ReviewHandler{ReviewService: psqlReviewService}
-	mux := a.routes()
-
-	c := cron.New()
-	err = c.AddFunc("@every 6h", func() {
-		ctx, cancelCron := context.WithTimeout(context.Background(), 2*time.Minute)
-		defer cancelCron()
+	a.ledgerHandler = &ledger.LedgerHandler{LedgerService: psqlLedgerService}
+	a.adminHandler = &admin.AdminHandler{AdminService: psqlAdminService}
+	a.categoryHandler = &category.CategoryHandler{CategoryService: psqlCategoryService}
+	a.availabilityHandler = &availability.AvailabilityHandler{AvailabilityService: psqlAvailabilityService}
+	a.messageHandler = &message.MessageHandler{MessageService: psqlMessageService}
+	a.notificationHandler = &notification.NotificationHandler{NotificationService: psqlNotificationService}
+	a.idempotencyStore = idempotencyStore
 
-		if err := a.requestHandler.RequestService.UpdateExpiredRequests(ctx); err != nil {
-			log.Printf("cron: failed UpdateExpiredRequests: %v", err)
+	instance := os.Getenv("INSTANCE_ID")
+	if instance == "" {
+		hostname, _ := os.Hostname()
+		instance = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	scheduler := &jobs.Scheduler{DB: dbpool, Instance: instance}
+	a.jobHandler = &jobs.JobHandler{Scheduler: scheduler}
+	for _, job := range []jobs.Job{
+		{
+			Name:     "expire-requests",
+			Schedule: "@every 6h",
+			Timeout:  2 * time.Minute,
+			Run: func(ctx context.Context) error {
+				err := a.requestHandler.RequestService.UpdateExpiredRequests(ctx)
+				helpers.WriteToWebHook(fmt.Sprintf("expire-requests executed at %s with err: %v\n", time.Now().Format(time.RFC3339), err), os.Getenv("WEBHOOK_URL"))
+				return err
+			},
+		},
+		{
+			Name:     "lift-suspensions",
+			Schedule: "@hourly",
+			Timeout:  time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := a.userHandler.UserService.LiftExpiredSuspensions(ctx)
+				return err
+			},
+		},
+		{
+			Name:     "send-reminders",
+			Schedule: "@every 15m",
+			Timeout:  time.Minute,
+			Run: func(ctx context.Context) error {
+				_, slotErr := a.requestHandler.RequestService.SendSlotReminders(ctx, reminderLead)
+				_, pendingErr := a.requestHandler.RequestService.SendPendingReminders(ctx, pendingReminderLeads)
+				return errors.Join(slotErr, pendingErr)
+			},
+		},
+		{
+			Name:     "reconcile-ledger",
+			Schedule: "@daily",
+			Timeout:  5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				report, err := a.ledgerHandler.LedgerService.Reconcile(ctx, ledger.ReconcileOptions{
+					SignupBonus: int32(signupBonus),
+					Fix:         os.Getenv("RECONCILE_AUTOFIX") == "true",
+				})
+				helpers.WriteToWebHook(fmt.Sprintf("reconciliation executed at %s: %d users checked, %d drifted, err: %v\n",
+					time.Now().Format(time.RFC3339), report.UsersChecked, len(report.Drifts), err), os.Getenv("WEBHOOK_URL"))
+				return err
+			},
+		},
+		{
+			Name:     "send-digests",
+			Schedule: cmp.Or(os.Getenv("DIGEST_SCHEDULE"), "0 0 8 * * *"),
+			Timeout:  2 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := a.notificationHandler.NotificationService.SendDigests(ctx)
+				return err
+			},
+		},
+		{
+			Name:     "prune-outbox",
+			Schedule: "@daily",
+			Timeout:  time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := dispatcher.Prune(ctx)
+				return err
+			},
+		},
+		{
+			Name:     "prune-idempotency-keys",
+			Schedule: "@daily",
+			Timeout:  time.Minute,
+			Run:      idempotencyStore.Prune,
+		},
+		{
+			Name:     "prune-job-runs",
+			Schedule: "@daily",
+			Timeout:  time.Minute,
+			Run:      scheduler.Prune,
+		},
+	} {
+		if err := scheduler.Add(job); err != nil {
+			log.Fatalf("unable to schedule job: %v", err)
 		}
-		helpers.WriteToWebHook(fmt.Sprintf("cron executed at %s with err: %v\n", time.Now().Format(time.RFC3339), err), os.Getenv("WEBHOOK_URL"))
-
-	})
-	if err != nil {
-		log.Printf("unable to add cron job: %v", err)
 	}
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.Run(schedulerCtx)
+	}()
 
-	c.Start()
+	mux := a.routes()
+
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	dispatchDone := make(chan struct{})
+	go func() {
+		defer close(dispatchDone)
+		dispatcher.Run(dispatchCtx, outboxInterval)
+	}()
+
+	// every instance relays broadcast events to the streams connected to it
+	broadcastCtx, stopBroadcast := context.WithCancel(context.Background())
+	broadcastDone := make(chan struct{})
+	go func() {
+		defer close(broadcastDone)
+		if broadcast != nil {
+			broadcast.Listen(broadcastCtx)
+		}
+	}()
 
 	server := &http.Server{
 		Addr:         ":" + port,
@@ -123,6 +409,9 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if a.eventHub != nil {
+		server.RegisterOnShutdown(a.eventHub.Close)
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
@@ -156,7 +445,12 @@ func main() {
 			os.Getenv("WEBHOOK_URL"),
 		)
 	}
-	c.Stop()
+	stopScheduler()
+	<-schedulerDone
+	stopDispatch()
+	<-dispatchDone
+	stopBroadcast()
+	<-broadcastDone
 	dbpool.Close()
 	log.Println("server stopped gracefully")
 	helpers.WriteToWebHook(
@@ -0,0 +1,109 @@
+// Command migratecategories maps the free-text categories of existing
+// listings onto the category taxonomy. By default it only prints the plan;
+// with -apply it rewrites the matched listings.
+//
+// An alias file lists extra mappings as CSV rows of "free text,slug" for
+// values that automatic matching cannot place, e.g. "Tutor,tutoring".
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/set-kaung/senior_project_1/internal/domain/category"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "rewrite matched listings")
+	aliasFile := flag.String("aliases", "", "CSV file of free text,slug rows")
+	fallback := flag.String("fallback", "", "slug for values nothing else matches")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	err := godotenv.Load()
+	if err != nil {
+		log.Println("Error loading .env file: ", err)
+		log.Println("Using system defaults.")
+	}
+
+	dbURL := os.Getenv("DBURL")
+	if dbURL == "" {
+		log.Fatalln("can't load db url")
+	}
+
+	aliases := map[string]string{}
+	if *aliasFile != "" {
+		aliases, err = readAliases(*aliasFile)
+		if err != nil {
+			log.Fatalln("invalid alias file:", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	dbpool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		log.Fatalf("error creating a pgxpool: %v\n", err)
+	}
+	defer dbpool.Close()
+
+	categoryService := &category.PostgresCategoryService{DB: dbpool}
+	report, err := categoryService.MigrateListingCategories(ctx, category.MigrateOptions{
+		Aliases:  aliases,
+		Fallback: *fallback,
+		Apply:    *apply,
+	})
+	if err != nil {
+		log.Fatalln("migration failed:", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	fmt.Printf("%d values mapped, %d unmapped\n\n", len(report.Mapped), len(report.Unmapped))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FREE TEXT\tSLUG\tLISTINGS\tAPPLIED")
+	for _, m := range report.Mapped {
+		fmt.Fprintf(w, "%q\t%s\t%d\t%t\n", m.FreeText, m.Slug, m.Listings, m.Applied)
+	}
+	for _, m := range report.Unmapped {
+		fmt.Fprintf(w, "%q\t-\t%d\t%t\n", m.FreeText, m.Listings, false)
+	}
+	w.Flush()
+}
+
+func readAliases(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string, len(records))
+	for _, rec := range records {
+		aliases[rec[0]] = strings.TrimSpace(rec[1])
+	}
+	return aliases, nil
+}
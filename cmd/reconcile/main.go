@@ -0,0 +1,83 @@
+// Command reconcile compares every user's token balance against what their
+// payments, ad history, redemptions and sign-up bonus say it should be, and
+// prints the users that drifted. With -fix it posts adjustment entries.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/set-kaung/senior_project_1/internal/domain/ledger"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "post adjustment entries for drifted users")
+	userID := flag.String("user", "", "only reconcile this user")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	err := godotenv.Load()
+	if err != nil {
+		log.Println("Error loading .env file: ", err)
+		log.Println("Using system defaults.")
+	}
+
+	dbURL := os.Getenv("DBURL")
+	if dbURL == "" {
+		log.Fatalln("can't load db url")
+	}
+	signupBonus, err := strconv.Atoi(os.Getenv("ONETIME_PAYMENT_TOKENS"))
+	if err != nil {
+		log.Fatalln("invalid ONETIME_PAYMENT_TOKENS:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	dbpool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		log.Fatalf("error creating a pgxpool: %v\n", err)
+	}
+	defer dbpool.Close()
+
+	ledgerService := &ledger.PostgresLedgerService{DB: dbpool}
+	report, err := ledgerService.Reconcile(ctx, ledger.ReconcileOptions{
+		UserID:      *userID,
+		SignupBonus: int32(signupBonus),
+		Fix:         *fix,
+	})
+	if err != nil {
+		log.Fatalln("reconcile failed:", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	fmt.Printf("reconciled at %s: %d users checked, %d drifted\n\n",
+		report.RanAt.Format(time.RFC3339), report.UsersChecked, len(report.Drifts))
+	if len(report.Drifts) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tBALANCE\tEXPECTED\tDRIFT\tLEDGER DRIFT\tTRANSACTION DRIFT\tCORRECTED")
+	for _, d := range report.Drifts {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%t\n",
+			d.UserID, d.TokenBalance, d.ExpectedBalance, d.BalanceDrift(), d.LedgerDrift(), d.TransactionDrift(), d.Corrected)
+	}
+	w.Flush()
+}
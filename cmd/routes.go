@@ -6,6 +6,7 @@ import (
 
 	clerkhttp "github.com/clerk/clerk-sdk-go/v2/http"
 	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain/admin"
 	"golang.org/x/time/rate"
 )
 
@@ -39,8 +40,23 @@ func (a *application) routes() http.Handler {
 	chain := NewRouteChainer()
 
 	mux.Handle("GET /health", chain.Chain(HealthCheck))
-
-	protected := chain.Append(internal.LogMiddleware, clerkhttp.WithHeaderAuthorization(), internal.AuthMiddleware)
+	mux.Handle("GET /calendar/{token}", chain.Chain(limiter.RateLimitMiddleware(a.requestHandler.HandleGetCalendarFeed)))
+
+	protected := chain.Append(internal.LogMiddleware, clerkhttp.WithHeaderAuthorization(), internal.AuthMiddleware,
+		internal.AccountStatusMiddleware(a.userHandler.UserService.GetAccountStanding))
+
+	// retries of these requests move tokens twice, so clients send an
+	// Idempotency-Key to have them replayed instead
+	idempotent := protected.Append(internal.IdempotencyMiddleware(a.idempotencyStore))
+
+	if a.eventHub != nil {
+		// streams skip LogMiddleware, which buffers the whole response, and
+		// take the token from the query string since EventSource cannot
+		// send headers
+		stream := chain.Append(clerkhttp.WithHeaderAuthorization(clerkhttp.AuthorizationJWTExtractor(internal.StreamTokenExtractor)),
+			internal.AuthMiddleware, internal.AccountStatusMiddleware(a.userHandler.UserService.GetAccountStanding))
+		mux.Handle("GET /events/stream", stream.Chain(a.eventHub.HandleStream))
+	}
 
 	mux.Handle("GET /users/me", protected.Chain(a.userHandler.HandleViewOwnProfile))
 	mux.Handle("GET /users/{id}", protected.Chain(a.userHandler.HandleGetUserByID))
@@ -55,38 +71,85 @@ func (a *application) routes() http.Handler {
 	mux.Handle("GET /users/me/history", protected.Chain(a.userHandler.HandleGetAllHistories))
 	mux.Handle("GET /users/me/redeemed-rewards/{redemptionId}", protected.Chain(a.rewardHandler.HandleGetRedeemedRewardByID))
 	mux.Handle("PUT /notifications/mark-all-read", protected.Chain(a.userHandler.HandleMarkAllAsRead))
+	mux.Handle("GET /notifications/unread-count", protected.Chain(a.userHandler.HandleGetUnreadNotificationCount))
+	mux.Handle("PUT /notifications/{id}/archive", protected.Chain(a.userHandler.HandleArchiveNotification))
+	mux.Handle("DELETE /notifications/{id}/archive", protected.Chain(a.userHandler.HandleUnarchiveNotification))
+	mux.Handle("DELETE /notifications/{id}", protected.Chain(a.userHandler.HandleDeleteNotification))
 	mux.Handle("GET /users/me/completed-transactions/{requestId}", protected.Chain(a.requestHandler.HandleGetCompletedTransaction))
-	mux.Handle("PUT /users/update-signup-payment", protected.Chain(a.userHandler.HandleUpdateSignupPaymentStatus))
+	mux.Handle("PUT /users/update-signup-payment", idempotent.Chain(a.userHandler.HandleUpdateSignupPaymentStatus))
 	mux.Handle("PUT /users/me/about-me", protected.Chain(a.userHandler.HandleUpdateAboutMe))
+	mux.Handle("GET /users/me/location", protected.Chain(a.userHandler.HandleGetLocation))
+	mux.Handle("PUT /users/me/location", protected.Chain(a.userHandler.HandleSetLocation))
+	mux.Handle("GET /users/me/calendar", protected.Chain(a.requestHandler.HandleGetCalendarFeedLink))
+	mux.Handle("POST /users/me/calendar/rotate", protected.Chain(a.requestHandler.HandleRotateCalendarFeed))
+	mux.Handle("GET /users/me/availability", protected.Chain(a.availabilityHandler.HandleGetOwnSchedule))
+	mux.Handle("PUT /users/me/availability", protected.Chain(a.availabilityHandler.HandleSetSchedule))
+	mux.Handle("POST /users/me/availability/exceptions", protected.Chain(a.availabilityHandler.HandleAddException))
+	mux.Handle("DELETE /users/me/availability/exceptions/{id}", protected.Chain(a.availabilityHandler.HandleDeleteException))
 	mux.Handle("GET /users/me/tickets", protected.Chain(a.requestHandler.HandleGetAllUserRequestReports))
+	mux.Handle("GET /users/me/ledger", protected.Chain(a.ledgerHandler.HandleGetOwnStatement))
+	mux.Handle("GET /users/me/notification-preferences", protected.Chain(a.notificationHandler.HandleGetPreferences))
+	mux.Handle("PUT /users/me/notification-preferences", protected.Chain(a.notificationHandler.HandleSetPreferences))
 
 	mux.Handle("GET /services", protected.Chain(a.listingHandler.HandleGetAllListings))
+	mux.Handle("GET /services/search", protected.Chain(a.listingHandler.HandleSearchListings))
 	mux.Handle("GET /services/{id}", protected.Chain(a.listingHandler.HandleGetListingByID))
 	mux.Handle("POST /services/create", protected.Chain(a.listingHandler.HandleCreateListing))
 	mux.Handle("PUT /services/update/{id}", protected.Chain(a.listingHandler.HandleUpdateListing))
 	mux.Handle("POST /services/report/{id}", protected.Chain(a.listingHandler.HandleReportListing))
+	mux.Handle("POST /services/{id}/appeal", protected.Chain(a.listingHandler.HandleAppealListing))
 	mux.Handle("DELETE /services/delete/{id}", protected.Chain(a.listingHandler.HandleDeleteListing))
 	mux.Handle("GET /services/{id}/reviews", protected.Chain(a.listingHandler.HandleGetListingReviews))
+	mux.Handle("GET /services/{id}/slots", protected.Chain(a.availabilityHandler.HandleGetListingSlots))
 
-	mux.Handle("POST /requests/create/{id}", protected.Chain(a.requestHandler.HandleCreateRequest))
+	mux.Handle("POST /requests/create/{id}", idempotent.Chain(a.requestHandler.HandleCreateRequest))
 	mux.Handle("PUT /requests/cancel/{id}", protected.Chain(a.requestHandler.HandleCancelRequest))
 	mux.Handle("POST /requests/accept/{id}", protected.Chain(a.requestHandler.HandleAcceptServiceRequest))
 	mux.Handle("POST /requests/decline/{id}", protected.Chain(a.requestHandler.HandleDeclineServiceRequest))
 	mux.Handle("POST /requests/complete/{id}", protected.Chain(a.requestHandler.HandleCompleteServiceRequest))
 	mux.Handle("GET /requests/{id}", protected.Chain(a.requestHandler.HandleGetRequestByID))
+	mux.Handle("GET /requests/{id}/ics", protected.Chain(a.requestHandler.HandleGetRequestICS))
+	mux.Handle("GET /requests/{id}/messages", protected.Chain(a.messageHandler.HandleGetMessages))
+	mux.Handle("POST /requests/{id}/messages", protected.Chain(a.messageHandler.HandleSendMessage))
+	mux.Handle("POST /requests/{id}/messages/read", protected.Chain(a.messageHandler.HandleMarkRead))
 	mux.Handle("GET /requests/all", protected.Chain(a.requestHandler.HandleGetAllUserRequests))
 	mux.Handle("POST /requests/review/{id}", protected.Chain(a.reviewHandler.HandleSubmitReview))
 	mux.Handle("GET /requests/review/{id}", protected.Chain(a.requestHandler.HandleGetReviewByRequestID))
 	mux.Handle("POST /requests/report/{id}", protected.Chain(a.requestHandler.HandleCreateRequestReport))
 	mux.Handle("GET /requests/report/{id}", protected.Chain(a.requestHandler.HandleGetRequestReport))
+	mux.Handle("POST /requests/report/{id}/messages", protected.Chain(a.requestHandler.HandleAddDisputeMessage))
 
-	mux.Handle("POST /ads/complete", protected.Chain(a.userHandler.HandleAdWatched))
+	mux.Handle("POST /ads/complete", idempotent.Chain(a.userHandler.HandleAdWatched))
 	mux.Handle("GET /ads/watched", protected.Chain(a.userHandler.HandleGetAdsWatched))
 
 	mux.Handle("GET /rewards", protected.Chain(a.rewardHandler.HandleGetAllRewards))
 	mux.Handle("GET /rewards/{id}", protected.Chain(a.rewardHandler.HandleRewardByID))
-	mux.Handle("POST /rewards/redeem/{id}", protected.Chain(a.rewardHandler.HandleRedeemReward))
+	mux.Handle("POST /rewards/redeem/{id}", idempotent.Chain(a.rewardHandler.HandleRedeemReward))
 
 	mux.Handle("GET /reviews/{id}", protected.Chain(a.reviewHandler.HandleGetReviewByID))
+
+	mux.Handle("GET /categories", protected.Chain(a.categoryHandler.HandleGetCategories))
+
+	moderator := protected.Append(internal.RequireRole(a.adminHandler.AdminService.GetUserRoles, admin.ROLE_MODERATOR, admin.ROLE_ADMIN))
+	adminOnly := protected.Append(internal.RequireRole(a.adminHandler.AdminService.GetUserRoles, admin.ROLE_ADMIN))
+
+	mux.Handle("GET /admin/reports", moderator.Chain(a.adminHandler.HandleGetListingReports))
+	mux.Handle("PUT /admin/reports/{id}", moderator.Chain(a.adminHandler.HandleReviewListingReport))
+	mux.Handle("POST /admin/warnings", moderator.Chain(a.adminHandler.HandleIssueWarning))
+	mux.Handle("GET /admin/appeals", moderator.Chain(a.adminHandler.HandleGetListingAppeals))
+	mux.Handle("PUT /admin/appeals/{id}", moderator.Chain(a.adminHandler.HandleDecideAppeal))
+
+	mux.Handle("PUT /admin/users/{id}/status", adminOnly.Chain(a.adminHandler.HandleSetAccountStatus))
+	mux.Handle("POST /admin/users/{id}/roles", adminOnly.Chain(a.adminHandler.HandleGrantRole))
+	mux.Handle("DELETE /admin/users/{id}/roles/{role}", adminOnly.Chain(a.adminHandler.HandleRevokeRole))
+	mux.Handle("GET /admin/tickets", adminOnly.Chain(a.requestHandler.HandleGetTickets))
+	mux.Handle("POST /admin/tickets/{id}/resolve", adminOnly.Chain(a.requestHandler.HandleResolveDispute))
+	mux.Handle("POST /admin/categories", adminOnly.Chain(a.categoryHandler.HandleCreateCategory))
+	mux.Handle("PUT /admin/categories/{id}", adminOnly.Chain(a.categoryHandler.HandleUpdateCategory))
+	mux.Handle("GET /admin/jobs", adminOnly.Chain(a.jobHandler.HandleGetJobs))
+	mux.Handle("GET /admin/jobs/{name}/runs", adminOnly.Chain(a.jobHandler.HandleGetJobRuns))
+	mux.Handle("GET /admin/expiry-policies", adminOnly.Chain(a.requestHandler.HandleGetExpiryPolicies))
+	mux.Handle("PUT /admin/categories/{id}/expiry-policy", adminOnly.Chain(a.requestHandler.HandleSetCategoryExpiryPolicy))
+	mux.Handle("DELETE /admin/categories/{id}/expiry-policy", adminOnly.Chain(a.requestHandler.HandleDeleteCategoryExpiryPolicy))
 	return internal.CORS(mux)
 }
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+// Roles a user can be granted in user_role. Moderators work the listing
+// report queue; admins can also change account status, resolve tickets
+// and manage roles.
+const (
+	ROLE_MODERATOR = string(repository.RoleModerator)
+	ROLE_ADMIN     = string(repository.RoleAdmin)
+)
+
+// Listing report statuses.
+const (
+	REPORT_PENDING   = "pending"
+	REPORT_REVIEWED  = "reviewed"
+	REPORT_DISMISSED = "dismissed"
+)
+
+// Listing appeal statuses. An upheld appeal leaves the warning in place;
+// an overturned one clears it and restores the listing.
+const (
+	APPEAL_PENDING    = "pending"
+	APPEAL_UPHELD     = "upheld"
+	APPEAL_OVERTURNED = "overturned"
+)
+
+// ListingReport is a user's report on a listing as seen in the moderation queue.
+type ListingReport struct {
+	ID               int32     `json:"id"`
+	ListingID        int32     `json:"listing_id"`
+	ListingTitle     string    `json:"listing_title"`
+	ProviderID       string    `json:"provider_id"`
+	ReporterID       string    `json:"reporter_id"`
+	ReporterFullName string    `json:"reporter_full_name"`
+	Datetime         time.Time `json:"datetime"`
+	ReportReason     string    `json:"report_reason"`
+	Status           string    `json:"status"`
+	AdditionalDetail string    `json:"additional_detail"`
+	ReviewedBy       string    `json:"reviewed_by,omitempty"`
+	ReviewedAt       time.Time `json:"reviewed_at,omitzero"`
+}
+
+// Warning is issued against a listing and its provider. A listing holds at
+// most one warning, so a new one replaces the previous. Issuing a warning
+// closes the listing's pending reports; ReportID optionally names one that
+// must still be pending. A severe warning takes the listing down.
+type Warning struct {
+	ID        int32                      `json:"id"`
+	ListingID int32                      `json:"listing_id"`
+	UserID    string                     `json:"user_id"`
+	Severity  repository.WarningSeverity `json:"severity"`
+	Reason    string                     `json:"reason"`
+	ReportID  int32                      `json:"report_id,omitempty"`
+}
+
+// Appeal is a provider's request to lift a warning or takedown on their listing.
+type Appeal struct {
+	ID                int32                      `json:"id"`
+	ListingID         int32                      `json:"listing_id"`
+	ListingTitle      string                     `json:"listing_title"`
+	ListingStatus     string                     `json:"listing_status"`
+	AppellantID       string                     `json:"appellant_id"`
+	AppellantFullName string                     `json:"appellant_full_name"`
+	Reason            string                     `json:"reason"`
+	Status            string                     `json:"status"`
+	CreatedAt         time.Time                  `json:"created_at"`
+	DecidedBy         string                     `json:"decided_by,omitempty"`
+	DecisionNote      string                     `json:"decision_note,omitempty"`
+	DecidedAt         time.Time                  `json:"decided_at,omitzero"`
+	WarningSeverity   repository.WarningSeverity `json:"warning_severity,omitempty"`
+	WarningReason     string                     `json:"warning_reason,omitempty"`
+}
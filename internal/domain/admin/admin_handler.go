@@ -0,0 +1,215 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type AdminHandler struct {
+	AdminService AdminService
+}
+
+func (ah *AdminHandler) HandleGetListingReports(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = REPORT_PENDING
+	}
+	reports, err := ah.AdminService.GetListingReports(r.Context(), status)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, reports, nil)
+}
+
+func (ah *AdminHandler) HandleReviewListingReport(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	reportID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		log.Printf("HandleReviewListingReport: %s \n", err)
+		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
+		return
+	}
+	body := struct {
+		Status string `json:"status"`
+	}{}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("HandleReviewListingReport: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if body.Status != REPORT_REVIEWED && body.Status != REPORT_DISMISSED {
+		helpers.WriteError(w, http.StatusBadRequest, "status must be reviewed or dismissed", nil)
+		return
+	}
+	err = ah.AdminService.ReviewListingReport(r.Context(), int32(reportID), userID, body.Status)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "report updated", nil)
+}
+
+func (ah *AdminHandler) HandleIssueWarning(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	warning := Warning{}
+	if err := json.NewDecoder(r.Body).Decode(&warning); err != nil {
+		log.Printf("HandleIssueWarning: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if warning.Severity != repository.WarningSeverityMild && warning.Severity != repository.WarningSeveritySevere {
+		helpers.WriteError(w, http.StatusBadRequest, "severity must be mild or severe", nil)
+		return
+	}
+	if strings.TrimSpace(warning.Reason) == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "reason is required", nil)
+		return
+	}
+	warningID, err := ah.AdminService.IssueWarning(r.Context(), userID, warning)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	helpers.WriteData(w, http.StatusCreated, map[string]int32{"warning_id": warningID}, nil)
+}
+
+func (ah *AdminHandler) HandleGetListingAppeals(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = APPEAL_PENDING
+	}
+	appeals, err := ah.AdminService.GetListingAppeals(r.Context(), status)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, appeals, nil)
+}
+
+func (ah *AdminHandler) HandleDecideAppeal(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	appealID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		log.Printf("HandleDecideAppeal: %s \n", err)
+		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
+		return
+	}
+	body := struct {
+		Decision string `json:"decision"`
+		Note     string `json:"note"`
+	}{}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("HandleDecideAppeal: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if body.Decision != APPEAL_UPHELD && body.Decision != APPEAL_OVERTURNED {
+		helpers.WriteError(w, http.StatusBadRequest, "decision must be upheld or overturned", nil)
+		return
+	}
+	err = ah.AdminService.DecideAppeal(r.Context(), int32(appealID), userID, body.Decision, strings.TrimSpace(body.Note))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "appeal decided", nil)
+}
+
+func (ah *AdminHandler) HandleSetAccountStatus(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	userID := r.PathValue("id")
+	if userID == adminID {
+		helpers.WriteError(w, http.StatusBadRequest, "cannot change your own account status", nil)
+		return
+	}
+	body := struct {
+		Status         repository.AccountStatus `json:"status"`
+		SuspendedUntil time.Time                `json:"suspended_until"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("HandleSetAccountStatus: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	switch body.Status {
+	case repository.AccountStatusActive, repository.AccountStatusSuspended, repository.AccountStatusBanned:
+	default:
+		helpers.WriteError(w, http.StatusBadRequest, "status must be active, suspended or banned", nil)
+		return
+	}
+	if !body.SuspendedUntil.IsZero() {
+		if body.Status != repository.AccountStatusSuspended {
+			helpers.WriteError(w, http.StatusBadRequest, "suspended_until only applies to suspensions", nil)
+			return
+		}
+		if !body.SuspendedUntil.After(time.Now()) {
+			helpers.WriteError(w, http.StatusBadRequest, "suspended_until must be in the future", nil)
+			return
+		}
+	}
+	err := ah.AdminService.SetAccountStatus(r.Context(), userID, body.Status, body.SuspendedUntil)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "account status updated", nil)
+}
+
+func (ah *AdminHandler) HandleGrantRole(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	body := struct {
+		Role string `json:"role"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("HandleGrantRole: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if body.Role != ROLE_MODERATOR && body.Role != ROLE_ADMIN {
+		helpers.WriteError(w, http.StatusBadRequest, "role must be moderator or admin", nil)
+		return
+	}
+	err := ah.AdminService.GrantRole(r.Context(), r.PathValue("id"), body.Role, adminID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "role granted", nil)
+}
+
+func (ah *AdminHandler) HandleRevokeRole(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	userID := r.PathValue("id")
+	role := r.PathValue("role")
+	if userID == adminID && role == ROLE_ADMIN {
+		helpers.WriteError(w, http.StatusBadRequest, "cannot revoke your own admin role", nil)
+		return
+	}
+	err := ah.AdminService.RevokeRole(r.Context(), userID, role)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "role revoked", nil)
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrNoRecord):
+		helpers.WriteError(w, http.StatusNotFound, "no such record", nil)
+	case errors.Is(err, internal.ErrWarningDowngrade):
+		helpers.WriteError(w, http.StatusConflict, err.Error(), nil)
+	default:
+		helpers.WriteServerError(w, nil)
+	}
+}
@@ -0,0 +1,22 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type AdminService interface {
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	GrantRole(ctx context.Context, userID string, role string, grantedBy string) error
+	RevokeRole(ctx context.Context, userID string, role string) error
+
+	GetListingReports(ctx context.Context, status string) ([]ListingReport, error)
+	ReviewListingReport(ctx context.Context, reportID int32, reviewerID string, status string) error
+	IssueWarning(ctx context.Context, issuerID string, w Warning) (int32, error)
+	GetListingAppeals(ctx context.Context, status string) ([]Appeal, error)
+	DecideAppeal(ctx context.Context, appealID int32, deciderID string, decision string, note string) error
+
+	SetAccountStatus(ctx context.Context, userID string, status repository.AccountStatus, suspendedUntil time.Time) error
+}
@@ -0,0 +1,381 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain"
+	"github.com/set-kaung/senior_project_1/internal/domain/listing"
+	"github.com/set-kaung/senior_project_1/internal/domain/notification"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type PostgresAdminService struct {
+	DB *pgxpool.Pool
+}
+
+func (pas *PostgresAdminService) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	repo := repository.New(pas.DB)
+	dbRoles, err := repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		log.Printf("GetUserRoles: failed to get roles: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	roles := make([]string, len(dbRoles))
+	for i, role := range dbRoles {
+		roles[i] = string(role)
+	}
+	return roles, nil
+}
+
+func (pas *PostgresAdminService) GrantRole(ctx context.Context, userID string, role string, grantedBy string) error {
+	repo := repository.New(pas.DB)
+	err := repo.GrantUserRole(ctx, repository.GrantUserRoleParams{
+		UserID:    userID,
+		Role:      repository.Role(role),
+		GrantedBy: pgtype.Text{String: grantedBy, Valid: true},
+	})
+	if err != nil {
+		log.Printf("GrantRole: failed to grant role: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
+
+func (pas *PostgresAdminService) RevokeRole(ctx context.Context, userID string, role string) error {
+	repo := repository.New(pas.DB)
+	n, err := repo.RevokeUserRole(ctx, repository.RevokeUserRoleParams{
+		UserID: userID,
+		Role:   repository.Role(role),
+	})
+	if err != nil {
+		log.Printf("RevokeRole: failed to revoke role: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if n == 0 {
+		return internal.ErrNoRecord
+	}
+	return nil
+}
+
+func (pas *PostgresAdminService) GetListingReports(ctx context.Context, status string) ([]ListingReport, error) {
+	repo := repository.New(pas.DB)
+	dbReports, err := repo.GetListingReports(ctx, status)
+	if err != nil {
+		log.Printf("GetListingReports: failed to get reports: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	reports := make([]ListingReport, len(dbReports))
+	for i, dbr := range dbReports {
+		reports[i] = ListingReport{
+			ID:               dbr.ID,
+			ListingID:        dbr.ListingID,
+			ListingTitle:     dbr.ListingTitle,
+			ProviderID:       dbr.PostedBy,
+			ReporterID:       dbr.ReporterID,
+			ReporterFullName: dbr.ReporterFullName,
+			Datetime:         dbr.Datetime,
+			ReportReason:     dbr.ReportReason.String,
+			Status:           dbr.Status,
+			AdditionalDetail: dbr.AdditionalDetail.String,
+			ReviewedBy:       dbr.ReviewedBy.String,
+			ReviewedAt:       dbr.ReviewedAt.Time,
+		}
+	}
+	return reports, nil
+}
+
+// ReviewListingReport closes a pending report as reviewed or dismissed.
+// Dismissing the last pending report puts a listing that was hidden for
+// review back up. returns ErrNoRecord if there is no pending report with that id.
+func (pas *PostgresAdminService) ReviewListingReport(ctx context.Context, reportID int32, reviewerID string, status string) error {
+	tx, err := pas.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("ReviewListingReport: failed to begin tx: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pas.DB).WithTx(tx)
+
+	listingID, err := repo.ReviewReport(ctx, repository.ReviewReportParams{
+		Status:     status,
+		ReviewedBy: pgtype.Text{String: reviewerID, Valid: true},
+		ID:         reportID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNoRecord
+		}
+		log.Printf("ReviewListingReport: failed to review report: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+
+	if status == REPORT_DISMISSED {
+		if err = restoreIfCleared(ctx, repo, listingID); err != nil {
+			log.Printf("ReviewListingReport: failed to restore listing: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("ReviewListingReport: failed to commit: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
+
+// restoreIfCleared puts a listing hidden for review back up once no pending
+// reports remain and tells the provider.
+func restoreIfCleared(ctx context.Context, repo *repository.Queries, listingID int32) error {
+	pending, err := repo.CountPendingListingReports(ctx, listingID)
+	if err != nil || pending > 0 {
+		return err
+	}
+	moderation, err := repo.GetListingModeration(ctx, listingID)
+	if err != nil || moderation.Status != listing.LISTING_UNDER_REVIEW {
+		return err
+	}
+	if _, err = repo.RestoreListing(ctx, listingID); err != nil {
+		return err
+	}
+	return notifyProvider(ctx, repo, listingID, moderation.PostedBy, domain.LISTING_RESTORED,
+		fmt.Sprintf("Your listing %s has been reviewed and is visible again.", moderation.Title))
+}
+
+// notifyProvider records a listing event and notifies the listing's provider
+// about it through the channels they chose for the description.
+func notifyProvider(ctx context.Context, repo *repository.Queries, listingID int32, providerID string, description string, message string) error {
+	eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+		TargetID:    listingID,
+		Type:        domain.LISTING_EVENT,
+		Description: description,
+	})
+	if err != nil {
+		return err
+	}
+	return notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  providerID,
+		ActionUserID: pgtype.Text{Valid: false},
+		EventID:      eventID,
+		EventType:    domain.LISTING_EVENT,
+		Description:  description,
+		Message:      message,
+	})
+}
+
+// IssueWarning puts a warning on a listing and lets the provider know.
+// returns ErrNoRecord if the listing does not exist, or if the report named
+// by w.ReportID is not a pending report on that listing, and
+// ErrWarningDowngrade if w is milder than the warning the listing already has.
+func (pas *PostgresAdminService) IssueWarning(ctx context.Context, issuerID string, w Warning) (int32, error) {
+	tx, err := pas.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("IssueWarning: failed to begin tx: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pas.DB).WithTx(tx)
+
+	warningID, err := issueWarning(ctx, repo, issuerID, w)
+	if err != nil {
+		return -1, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("IssueWarning: failed to commit: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	return warningID, nil
+}
+
+func issueWarning(ctx context.Context, repo *repository.Queries, issuerID string, w Warning) (int32, error) {
+	if w.ReportID != 0 {
+		reportListingID, err := repo.ReviewReport(ctx, repository.ReviewReportParams{
+			Status:     REPORT_REVIEWED,
+			ReviewedBy: pgtype.Text{String: issuerID, Valid: true},
+			ID:         w.ReportID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return -1, internal.ErrNoRecord
+			}
+			log.Printf("IssueWarning: failed to review report: %s\n", err)
+			return -1, internal.ErrInternalServerError
+		}
+		// the report must be about the listing being warned, or it would be
+		// closed while its own listing goes unpunished
+		if reportListingID != w.ListingID {
+			return -1, internal.ErrNoRecord
+		}
+	}
+
+	warning, err := repo.UpsertListingWarning(ctx, repository.UpsertListingWarningParams{
+		Severity:  w.Severity,
+		Reason:    w.Reason,
+		ListingID: w.ListingID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return -1, internal.ErrNoRecord
+		}
+		log.Printf("IssueWarning: failed to insert warning: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	if warning.Severity != w.Severity {
+		return -1, internal.ErrWarningDowngrade
+	}
+
+	_, err = repo.ReviewPendingListingReports(ctx, repository.ReviewPendingListingReportsParams{
+		Status:     REPORT_REVIEWED,
+		ReviewedBy: pgtype.Text{String: issuerID, Valid: true},
+		ListingID:  w.ListingID,
+	})
+	if err != nil {
+		log.Printf("IssueWarning: failed to review pending reports: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+
+	description := domain.WARNING_ISSUED
+	message := fmt.Sprintf("Your listing received a %s warning: %s", w.Severity, w.Reason)
+	if w.Severity == repository.WarningSeveritySevere {
+		_, err = repo.UpdateListingStatus(ctx, repository.UpdateListingStatusParams{
+			Status: listing.LISTING_REMOVED,
+			ID:     w.ListingID,
+		})
+		description = domain.LISTING_REMOVED
+		message = fmt.Sprintf("Your listing was taken down after a severe warning: %s. You can appeal this decision.", w.Reason)
+	} else {
+		// a lighter warning clears a pending review but must not undo a
+		// takedown, which only an overturned appeal can lift
+		_, err = repo.UnhideListing(ctx, w.ListingID)
+	}
+	if err != nil {
+		log.Printf("IssueWarning: failed to update listing status: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+
+	err = notifyProvider(ctx, repo, w.ListingID, warning.UserID, description, message)
+	if err != nil {
+		log.Printf("IssueWarning: failed to notify provider: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	return warning.ID, nil
+}
+
+func (pas *PostgresAdminService) GetListingAppeals(ctx context.Context, status string) ([]Appeal, error) {
+	repo := repository.New(pas.DB)
+	dbAppeals, err := repo.GetListingAppeals(ctx, status)
+	if err != nil {
+		log.Printf("GetListingAppeals: failed to get appeals: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	appeals := make([]Appeal, len(dbAppeals))
+	for i, dba := range dbAppeals {
+		appeals[i] = Appeal{
+			ID:                dba.ID,
+			ListingID:         dba.ListingID,
+			ListingTitle:      dba.ListingTitle,
+			ListingStatus:     dba.ListingStatus,
+			AppellantID:       dba.AppellantID,
+			AppellantFullName: dba.AppellantFullName,
+			Reason:            dba.Reason,
+			Status:            dba.Status,
+			CreatedAt:         dba.CreatedAt,
+			DecidedBy:         dba.DecidedBy.String,
+			DecisionNote:      dba.DecisionNote.String,
+			DecidedAt:         dba.DecidedAt.Time,
+			WarningSeverity:   dba.WarningSeverity.WarningSeverity,
+			WarningReason:     dba.WarningReason.String,
+		}
+	}
+	return appeals, nil
+}
+
+// DecideAppeal upholds or overturns a pending appeal. Overturning clears the
+// listing's warning and puts it back up. Either way the provider is told.
+// returns ErrNoRecord if there is no pending appeal with that id.
+func (pas *PostgresAdminService) DecideAppeal(ctx context.Context, appealID int32, deciderID string, decision string, note string) error {
+	tx, err := pas.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("DecideAppeal: failed to begin tx: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pas.DB).WithTx(tx)
+
+	appeal, err := repo.GetListingAppealForUpdate(ctx, appealID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNoRecord
+		}
+		log.Printf("DecideAppeal: failed to get appeal: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if appeal.Status != APPEAL_PENDING {
+		return internal.ErrNoRecord
+	}
+
+	err = repo.DecideListingAppeal(ctx, repository.DecideListingAppealParams{
+		Status:       decision,
+		DecidedBy:    pgtype.Text{String: deciderID, Valid: true},
+		DecisionNote: pgtype.Text{String: note, Valid: note != ""},
+		ID:           appealID,
+	})
+	if err != nil {
+		log.Printf("DecideAppeal: failed to update appeal: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+
+	message := "Your appeal was reviewed and the moderation decision stands."
+	if decision == APPEAL_OVERTURNED {
+		if err = repo.DeleteListingWarning(ctx, appeal.ListingID); err != nil {
+			log.Printf("DecideAppeal: failed to delete warning: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+		if _, err = repo.RestoreListing(ctx, appeal.ListingID); err != nil {
+			log.Printf("DecideAppeal: failed to restore listing: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+		message = "Your appeal was accepted. The warning has been lifted and your listing is visible again."
+	}
+	if note != "" {
+		message = fmt.Sprintf("%s Note from the moderator: %s", message, note)
+	}
+	err = notifyProvider(ctx, repo, appeal.ListingID, appeal.AppellantID, domain.APPEAL_DECIDED, message)
+	if err != nil {
+		log.Printf("DecideAppeal: failed to notify provider: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("DecideAppeal: failed to commit: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
+
+// SetAccountStatus activates, suspends or bans a user. suspendedUntil is
+// only kept for suspensions; a zero value suspends until lifted by hand.
+// returns ErrNoRecord if the user does not exist.
+func (pas *PostgresAdminService) SetAccountStatus(ctx context.Context, userID string, status repository.AccountStatus, suspendedUntil time.Time) error {
+	repo := repository.New(pas.DB)
+	n, err := repo.UpdateUserStatus(ctx, repository.UpdateUserStatusParams{
+		Status:         status,
+		SuspendedUntil: pgtype.Timestamptz{Time: suspendedUntil, Valid: status == repository.AccountStatusSuspended && !suspendedUntil.IsZero()},
+		ID:             userID,
+	})
+	if err != nil {
+		log.Printf("SetAccountStatus: failed to update status: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if n == 0 {
+		return internal.ErrNoRecord
+	}
+	return nil
+}
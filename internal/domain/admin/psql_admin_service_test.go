@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain/listing"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+	"github.com/set-kaung/senior_project_1/internal/repository/repotest"
+)
+
+func TestIssueWarningReport(t *testing.T) {
+	tests := []struct {
+		name            string
+		reportListingID int32
+		reportPending   bool
+		severity        repository.WarningSeverity
+		held            repository.WarningSeverity
+		wantErr         error
+	}{
+		{"report on the listing", 7, true, repository.WarningSeveritySevere, repository.WarningSeveritySevere, nil},
+		{"report on another listing", 8, true, repository.WarningSeveritySevere, repository.WarningSeveritySevere, internal.ErrNoRecord},
+		{"report no longer pending", 7, false, repository.WarningSeveritySevere, repository.WarningSeveritySevere, internal.ErrNoRecord},
+		// the listing already holds a severe warning, which the upsert keeps
+		{"mild after severe", 7, true, repository.WarningSeverityMild, repository.WarningSeveritySevere, internal.ErrWarningDowngrade},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repotest.New()
+			if tt.reportPending {
+				db.Returns("ReviewReport", []any{tt.reportListingID})
+			}
+			db.Returns("UpsertListingWarning", []any{int32(3), "provider", tt.held})
+			db.Returns("UpdateListingStatus", []any{"provider", "Guitar lessons"})
+			db.Returns("InsertEvent", []any{int64(1)})
+
+			id, err := issueWarning(context.Background(), repository.New(db), "moderator", Warning{
+				ListingID: 7,
+				Severity:  tt.severity,
+				Reason:    "spam",
+				ReportID:  11,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("issueWarning() error = %v, want %v", err, tt.wantErr)
+			}
+			if review := db.Calls("ReviewReport"); len(review) != 1 || review[0].Args[2] != int32(11) {
+				t.Errorf("ReviewReport calls = %v, want report 11 reviewed", review)
+			}
+			if tt.wantErr != nil {
+				refused := []string{"UpdateListingStatus", "UnhideListing", "InsertNotification"}
+				if errors.Is(tt.wantErr, internal.ErrNoRecord) {
+					refused = append(refused, "UpsertListingWarning")
+				}
+				for _, query := range refused {
+					if calls := db.Calls(query); len(calls) != 0 {
+						t.Errorf("%s ran for a warning that was refused", query)
+					}
+				}
+				return
+			}
+			if id != 3 {
+				t.Errorf("issueWarning() = %d, want warning 3", id)
+			}
+			update := db.Calls("UpdateListingStatus")
+			if len(update) != 1 || update[0].Args[0] != listing.LISTING_REMOVED || update[0].Args[1] != int32(7) {
+				t.Errorf("UpdateListingStatus calls = %v, want listing 7 removed", update)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package availability
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DEFAULT_SESSION is the slot length of listings without a session_duration.
+const DEFAULT_SESSION = time.Hour
+
+// MAX_RANGE is how far GET /services/{id}/slots looks ahead in one call.
+const MAX_RANGE = 31 * 24 * time.Hour
+
+// Rule is a weekly window in which a provider takes bookings. Start and End
+// are "15:04" wall-clock times in the schedule's timezone and Weekday counts
+// from Sunday. A window cannot cross midnight; End "24:00" runs it up to
+// midnight so overnight hours can continue in the next day's rule.
+type Rule struct {
+	ID      int32        `json:"id,omitempty"`
+	Weekday time.Weekday `json:"weekday"`
+	Start   string       `json:"start"`
+	End     string       `json:"end"`
+}
+
+// Schedule is a provider's weekly availability. Every listing of the provider
+// shares it, so a provider is never booked twice for the same time.
+type Schedule struct {
+	Timezone   string      `json:"timezone"`
+	Rules      []Rule      `json:"rules"`
+	Exceptions []Exception `json:"exceptions"`
+}
+
+// Exception blocks a period the weekly rules would otherwise offer, such as
+// a holiday.
+type Exception struct {
+	ID       int32     `json:"id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// Slot is a bookable session.
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (s Slot) overlaps(o Slot) bool {
+	return s.Start.Before(o.End) && o.Start.Before(s.End)
+}
+
+// Validate checks the timezone and that rules are well formed and do not
+// overlap one another.
+func (s Schedule) Validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "" {
+		return fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	for i, r := range s.Rules {
+		if r.Weekday < time.Sunday || r.Weekday > time.Saturday {
+			return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, errStart := parseClock(r.Start)
+		end, errEnd := parseClock(r.End)
+		if errStart != nil || errEnd != nil {
+			return errors.New("start and end must be times like 09:30")
+		}
+		if start >= end {
+			return fmt.Errorf("rule on %s ends before it starts", r.Weekday)
+		}
+		for _, o := range s.Rules[:i] {
+			if o.Weekday != r.Weekday {
+				continue
+			}
+			oStart, _ := parseClock(o.Start)
+			oEnd, _ := parseClock(o.End)
+			if start < oEnd && oStart < end {
+				return fmt.Errorf("rules on %s overlap", r.Weekday)
+			}
+		}
+	}
+	return nil
+}
+
+// Slots lays slots of length d back to back from the start of every rule
+// window between from and to, in loc. Slots that overlap a blocked period or
+// do not fit within from and to are left out.
+func Slots(loc *time.Location, rules []Rule, blocked []Slot, d time.Duration, from, to time.Time) []Slot {
+	slots := []Slot{}
+	if d <= 0 {
+		return slots
+	}
+	from, to = from.In(loc), to.In(loc)
+	// a window that starts the day before from can still reach into it
+	day := time.Date(from.Year(), from.Month(), from.Day()-1, 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, r := range rules {
+			if r.Weekday != day.Weekday() {
+				continue
+			}
+			start, errStart := parseClock(r.Start)
+			end, errEnd := parseClock(r.End)
+			if errStart != nil || errEnd != nil {
+				continue
+			}
+			windowEnd := atClock(day, end)
+			for s := atClock(day, start); !s.Add(d).After(windowEnd); s = s.Add(d) {
+				slot := Slot{Start: s, End: s.Add(d)}
+				if slot.Start.Before(from) || slot.End.After(to) {
+					continue
+				}
+				if slices.ContainsFunc(blocked, slot.overlaps) {
+					continue
+				}
+				slots = append(slots, slot)
+			}
+		}
+	}
+	slices.SortFunc(slots, func(a, b Slot) int { return a.Start.Compare(b.Start) })
+	return slots
+}
+
+// Fits reports whether a session of length d starting at start is one of
+// the slots the rules offer and nothing blocks it.
+func Fits(loc *time.Location, rules []Rule, blocked []Slot, d time.Duration, start time.Time) bool {
+	slots := Slots(loc, rules, blocked, d, start, start.Add(d))
+	return len(slots) == 1 && slots[0].Start.Equal(start)
+}
+
+// SessionLength is how long a booking of a listing lasts.
+func SessionLength(iv pgtype.Interval) time.Duration {
+	if !iv.Valid || iv.Microseconds <= 0 {
+		return DEFAULT_SESSION
+	}
+	return time.Duration(iv.Microseconds) * time.Microsecond
+}
+
+func parseClock(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatClock(t pgtype.Time) string {
+	d := time.Duration(t.Microseconds) * time.Microsecond
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// atClock is the wall-clock time offset into day, so that windows keep their
+// local hours across daylight saving changes.
+func atClock(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(offset.Hours()), int(offset.Minutes())%60, 0, 0, day.Location())
+}
@@ -0,0 +1,123 @@
+package availability
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+)
+
+type AvailabilityHandler struct {
+	AvailabilityService AvailabilityService
+}
+
+func (ah *AvailabilityHandler) HandleGetOwnSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	s, err := ah.AvailabilityService.GetSchedule(r.Context(), userID)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, s, nil)
+}
+
+func (ah *AvailabilityHandler) HandleSetSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	s := Schedule{}
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		log.Printf("HandleSetSchedule: %s\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if err := s.Validate(); err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := ah.AvailabilityService.SetSchedule(r.Context(), userID, s); err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "availability updated", nil)
+}
+
+func (ah *AvailabilityHandler) HandleAddException(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	e := Exception{}
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		log.Printf("HandleAddException: %s\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if e.StartsAt.IsZero() || !e.StartsAt.Before(e.EndsAt) {
+		helpers.WriteError(w, http.StatusBadRequest, "starts_at must be before ends_at", nil)
+		return
+	}
+	id, err := ah.AvailabilityService.AddException(r.Context(), userID, e)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusCreated, map[string]int32{"id": id}, nil)
+}
+
+func (ah *AvailabilityHandler) HandleDeleteException(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid id", nil)
+		return
+	}
+	err = ah.AvailabilityService.DeleteException(r.Context(), userID, int32(id))
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) {
+			helpers.WriteError(w, http.StatusNotFound, "no such record", nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "exception deleted", nil)
+}
+
+// HandleGetListingSlots lists the free slots of a listing. from defaults to
+// now and to to a week after from.
+func (ah *AvailabilityHandler) HandleGetListingSlots(w http.ResponseWriter, r *http.Request) {
+	listingID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid id", nil)
+		return
+	}
+	from := time.Now()
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, "from must be an RFC 3339 time", nil)
+			return
+		}
+	}
+	to := from.Add(7 * 24 * time.Hour)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, "to must be an RFC 3339 time", nil)
+			return
+		}
+	}
+	if !from.Before(to) || to.Sub(from) > MAX_RANGE {
+		helpers.WriteError(w, http.StatusBadRequest, "to must be after from and at most 31 days later", nil)
+		return
+	}
+	slots, err := ah.AvailabilityService.GetListingSlots(r.Context(), int32(listingID), from, to)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) {
+			helpers.WriteError(w, http.StatusNotFound, "no such listing", nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, slots, nil)
+}
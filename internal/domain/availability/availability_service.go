@@ -0,0 +1,14 @@
+package availability
+
+import (
+	"context"
+	"time"
+)
+
+type AvailabilityService interface {
+	GetSchedule(ctx context.Context, providerID string) (Schedule, error)
+	SetSchedule(ctx context.Context, providerID string, s Schedule) error
+	AddException(ctx context.Context, providerID string, e Exception) (int32, error)
+	DeleteException(ctx context.Context, providerID string, id int32) error
+	GetListingSlots(ctx context.Context, listingID int32, from, to time.Time) ([]Slot, error)
+}
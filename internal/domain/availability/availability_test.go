@@ -0,0 +1,236 @@
+package availability
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestSlots(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	bangkok := mustLoad(t, "Asia/Bangkok")
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		rules    []Rule
+		blocked  []Slot
+		d        time.Duration
+		from, to time.Time
+		want     []string
+	}{
+		{
+			name:  "back to back",
+			loc:   time.UTC,
+			rules: []Rule{{Weekday: time.Monday, Start: "09:00", End: "12:00"}},
+			d:     time.Hour,
+			from:  utc("2026-10-19T00:00:00Z"), to: utc("2026-10-20T00:00:00Z"),
+			want: []string{"2026-10-19T09:00:00Z", "2026-10-19T10:00:00Z", "2026-10-19T11:00:00Z"},
+		},
+		{
+			name:  "session that does not fill the window",
+			loc:   time.UTC,
+			rules: []Rule{{Weekday: time.Monday, Start: "09:00", End: "11:00"}},
+			d:     90 * time.Minute,
+			from:  utc("2026-10-19T00:00:00Z"), to: utc("2026-10-20T00:00:00Z"),
+			want: []string{"2026-10-19T09:00:00Z"},
+		},
+		{
+			name:  "range cut mid-window",
+			loc:   time.UTC,
+			rules: []Rule{{Weekday: time.Monday, Start: "09:00", End: "12:00"}},
+			d:     time.Hour,
+			from:  utc("2026-10-19T09:30:00Z"), to: utc("2026-10-19T12:00:00Z"),
+			want: []string{"2026-10-19T10:00:00Z", "2026-10-19T11:00:00Z"},
+		},
+		{
+			name:  "blocked periods",
+			loc:   time.UTC,
+			rules: []Rule{{Weekday: time.Monday, Start: "09:00", End: "13:00"}},
+			blocked: []Slot{
+				// takes the last minute of 10:00 and half of 11:00
+				{Start: utc("2026-10-19T10:59:00Z"), End: utc("2026-10-19T11:30:00Z")},
+				// ends exactly where 09:00 starts, so it does not block it
+				{Start: utc("2026-10-19T08:00:00Z"), End: utc("2026-10-19T09:00:00Z")},
+			},
+			d:    time.Hour,
+			from: utc("2026-10-19T00:00:00Z"), to: utc("2026-10-20T00:00:00Z"),
+			want: []string{"2026-10-19T09:00:00Z", "2026-10-19T12:00:00Z"},
+		},
+		{
+			name:    "whole day blocked",
+			loc:     time.UTC,
+			rules:   []Rule{{Weekday: time.Monday, Start: "09:00", End: "12:00"}},
+			blocked: []Slot{{Start: utc("2026-10-19T00:00:00Z"), End: utc("2026-10-20T00:00:00Z")}},
+			d:       time.Hour,
+			from:    utc("2026-10-19T00:00:00Z"), to: utc("2026-10-26T00:00:00Z"),
+			want: []string{},
+		},
+		{
+			// a window cannot cross midnight, so overnight hours are two
+			// rules whose slots meet at midnight
+			name: "overnight across two rules",
+			loc:  bangkok,
+			rules: []Rule{
+				{Weekday: time.Monday, Start: "22:00", End: "24:00"},
+				{Weekday: time.Tuesday, Start: "00:00", End: "02:00"},
+			},
+			d:    time.Hour,
+			from: utc("2026-10-19T14:00:00Z"), to: utc("2026-10-19T20:00:00Z"),
+			// 22:00 and 23:00 Monday, then 00:00 and 01:00 Tuesday in Bangkok
+			want: []string{"2026-10-19T15:00:00Z", "2026-10-19T16:00:00Z", "2026-10-19T17:00:00Z", "2026-10-19T18:00:00Z"},
+		},
+		{
+			name:  "window to 23:59 loses the last hour",
+			loc:   time.UTC,
+			rules: []Rule{{Weekday: time.Monday, Start: "22:00", End: "23:59"}},
+			d:     time.Hour,
+			from:  utc("2026-10-19T00:00:00Z"), to: utc("2026-10-20T00:00:00Z"),
+			want: []string{"2026-10-19T22:00:00Z"},
+		},
+		{
+			name:  "window ending at midnight",
+			loc:   time.UTC,
+			rules: []Rule{{Weekday: time.Sunday, Start: "22:00", End: "24:00"}},
+			d:     90 * time.Minute,
+			from:  utc("2026-10-18T22:00:00Z"), to: utc("2026-10-19T06:00:00Z"),
+			want: []string{"2026-10-18T22:00:00Z"},
+		},
+		{
+			// Monday morning in Bangkok is still Sunday in UTC
+			name:  "local day differs from UTC",
+			loc:   bangkok,
+			rules: []Rule{{Weekday: time.Monday, Start: "05:00", End: "06:00"}},
+			d:     time.Hour,
+			from:  utc("2026-10-18T00:00:00Z"), to: utc("2026-10-19T00:00:00Z"),
+			want: []string{"2026-10-18T22:00:00Z"},
+		},
+		{
+			name:  "same local hours either side of spring forward",
+			loc:   newYork,
+			rules: []Rule{{Weekday: time.Sunday, Start: "09:00", End: "10:00"}},
+			d:     time.Hour,
+			from:  utc("2026-03-01T00:00:00Z"), to: utc("2026-03-09T00:00:00Z"),
+			// 09:00 EST, then 09:00 EDT
+			want: []string{"2026-03-01T14:00:00Z", "2026-03-08T13:00:00Z"},
+		},
+		{
+			name:  "window across the spring forward gap",
+			loc:   newYork,
+			rules: []Rule{{Weekday: time.Sunday, Start: "01:00", End: "04:00"}},
+			d:     time.Hour,
+			from:  utc("2026-03-08T00:00:00Z"), to: utc("2026-03-09T00:00:00Z"),
+			// 01:00 EST and 03:00 EDT; the window is two hours long that night
+			want: []string{"2026-03-08T06:00:00Z", "2026-03-08T07:00:00Z"},
+		},
+		{
+			name:  "window across the fall back overlap",
+			loc:   newYork,
+			rules: []Rule{{Weekday: time.Sunday, Start: "01:00", End: "03:00"}},
+			d:     time.Hour,
+			from:  utc("2026-11-01T00:00:00Z"), to: utc("2026-11-02T00:00:00Z"),
+			// 01:00 EDT, 01:00 EST and 02:00 EST; the window is three hours long that night
+			want: []string{"2026-11-01T05:00:00Z", "2026-11-01T06:00:00Z", "2026-11-01T07:00:00Z"},
+		},
+		{
+			name:  "no session length",
+			loc:   time.UTC,
+			rules: []Rule{{Weekday: time.Monday, Start: "09:00", End: "12:00"}},
+			d:     0,
+			from:  utc("2026-10-19T00:00:00Z"), to: utc("2026-10-20T00:00:00Z"),
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := Slots(tt.loc, tt.rules, tt.blocked, tt.d, tt.from, tt.to)
+			got := make([]string, len(slots))
+			for i, s := range slots {
+				got[i] = s.Start.UTC().Format(time.RFC3339)
+				if real := s.End.Sub(s.Start); real != tt.d {
+					t.Errorf("slot at %s lasts %s, want %s", got[i], real, tt.d)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Slots() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Slots() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFits(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	rules := []Rule{
+		{Weekday: time.Sunday, Start: "09:00", End: "12:00"},
+		{Weekday: time.Saturday, Start: "23:00", End: "23:59"},
+	}
+	blocked := []Slot{{Start: utc("2026-03-08T15:00:00Z"), End: utc("2026-03-08T15:30:00Z")}}
+	tests := []struct {
+		name  string
+		start time.Time
+		want  bool
+	}{
+		{"first slot after spring forward", utc("2026-03-08T13:00:00Z"), true},
+		{"local 09:00 by last week's offset", utc("2026-03-08T14:00:00Z"), true},
+		{"blocked", utc("2026-03-08T15:00:00Z"), false},
+		{"off the slot grid", utc("2026-03-08T13:30:00Z"), false},
+		{"runs past the window", utc("2026-03-08T16:30:00Z"), false},
+		{"outside every window", utc("2026-03-09T13:00:00Z"), false},
+		{"does not fit before midnight", utc("2026-03-08T04:00:00Z"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fits(newYork, rules, blocked, time.Hour, tt.start); got != tt.want {
+				t.Errorf("Fits(%s) = %v, want %v", tt.start.In(newYork), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       Schedule
+		wantErr bool
+	}{
+		{"valid", Schedule{Timezone: "Asia/Bangkok", Rules: []Rule{{Weekday: time.Monday, Start: "09:00", End: "12:00"}, {Weekday: time.Monday, Start: "12:00", End: "13:00"}}}, false},
+		{"no timezone", Schedule{}, true},
+		{"unknown timezone", Schedule{Timezone: "Mars/Olympus"}, true},
+		{"crosses midnight", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: time.Friday, Start: "22:00", End: "02:00"}}}, true},
+		{"empty window", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: time.Friday, Start: "09:00", End: "09:00"}}}, true},
+		{"overlapping", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: time.Monday, Start: "09:00", End: "12:00"}, {Weekday: time.Monday, Start: "11:00", End: "13:00"}}}, true},
+		{"bad weekday", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: 7, Start: "09:00", End: "12:00"}}}, true},
+		{"bad clock", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: time.Monday, Start: "9am", End: "12:00"}}}, true},
+		{"to midnight", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: time.Monday, Start: "22:00", End: "24:00"}}}, false},
+		{"from midnight", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: time.Monday, Start: "24:00", End: "24:00"}}}, true},
+		{"past midnight", Schedule{Timezone: "UTC", Rules: []Rule{{Weekday: time.Monday, Start: "22:00", End: "24:30"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.s.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type PostgresAvailabilityService struct {
+	DB *pgxpool.Pool
+}
+
+// GetSchedule returns the provider's weekly rules and their upcoming
+// exceptions. A provider without rules gets an empty UTC schedule.
+func (pas *PostgresAvailabilityService) GetSchedule(ctx context.Context, providerID string) (Schedule, error) {
+	repo := repository.New(pas.DB)
+	dbRules, err := repo.GetAvailabilityRules(ctx, providerID)
+	if err != nil {
+		log.Printf("GetSchedule: failed to get rules: %s\n", err)
+		return Schedule{}, internal.ErrInternalServerError
+	}
+	now := time.Now()
+	dbExceptions, err := repo.GetAvailabilityExceptions(ctx, repository.GetAvailabilityExceptionsParams{
+		ProviderID: providerID,
+		RangeStart: now,
+		RangeEnd:   now.AddDate(1, 0, 0),
+	})
+	if err != nil {
+		log.Printf("GetSchedule: failed to get exceptions: %s\n", err)
+		return Schedule{}, internal.ErrInternalServerError
+	}
+	s := Schedule{Timezone: "UTC", Rules: toRules(dbRules), Exceptions: make([]Exception, len(dbExceptions))}
+	if len(dbRules) > 0 {
+		s.Timezone = dbRules[0].Timezone
+	}
+	for i, e := range dbExceptions {
+		s.Exceptions[i] = Exception{ID: e.ID, StartsAt: e.StartsAt, EndsAt: e.EndsAt, Reason: e.Reason.String}
+	}
+	return s, nil
+}
+
+// SetSchedule replaces the provider's weekly rules. Exceptions and existing
+// bookings are kept. s must have passed Validate.
+func (pas *PostgresAvailabilityService) SetSchedule(ctx context.Context, providerID string, s Schedule) error {
+	tx, err := pas.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("SetSchedule: failed to begin transaction: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pas.DB).WithTx(tx)
+
+	if err = repo.DeleteAvailabilityRules(ctx, providerID); err != nil {
+		log.Printf("SetSchedule: failed to delete rules: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	for _, r := range s.Rules {
+		start, _ := parseClock(r.Start)
+		end, _ := parseClock(r.End)
+		err = repo.InsertAvailabilityRule(ctx, repository.InsertAvailabilityRuleParams{
+			ProviderID: providerID,
+			Weekday:    int16(r.Weekday),
+			StartTime:  pgtype.Time{Microseconds: start.Microseconds(), Valid: true},
+			EndTime:    pgtype.Time{Microseconds: end.Microseconds(), Valid: true},
+			Timezone:   s.Timezone,
+		})
+		if err != nil {
+			log.Printf("SetSchedule: failed to insert rule: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("SetSchedule: failed to commit: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
+
+func (pas *PostgresAvailabilityService) AddException(ctx context.Context, providerID string, e Exception) (int32, error) {
+	repo := repository.New(pas.DB)
+	id, err := repo.InsertAvailabilityException(ctx, repository.InsertAvailabilityExceptionParams{
+		ProviderID: providerID,
+		StartsAt:   e.StartsAt,
+		EndsAt:     e.EndsAt,
+		Reason:     pgtype.Text{String: e.Reason, Valid: e.Reason != ""},
+	})
+	if err != nil {
+		log.Printf("AddException: failed to insert exception: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	return id, nil
+}
+
+// DeleteException returns ErrNoRecord if the provider has no exception with id.
+func (pas *PostgresAvailabilityService) DeleteException(ctx context.Context, providerID string, id int32) error {
+	repo := repository.New(pas.DB)
+	n, err := repo.DeleteAvailabilityException(ctx, repository.DeleteAvailabilityExceptionParams{
+		ID:         id,
+		ProviderID: providerID,
+	})
+	if err != nil {
+		log.Printf("DeleteException: failed to delete exception: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if n == 0 {
+		return internal.ErrNoRecord
+	}
+	return nil
+}
+
+// GetListingSlots returns the free slots of a listing between from and to.
+// Slots in the past are never offered.
+// returns ErrNoRecord if the listing does not exist.
+func (pas *PostgresAvailabilityService) GetListingSlots(ctx context.Context, listingID int32, from, to time.Time) ([]Slot, error) {
+	if now := time.Now(); from.Before(now) {
+		from = now
+	}
+	repo := repository.New(pas.DB)
+	listing, err := repo.GetListingSchedule(ctx, listingID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, internal.ErrNoRecord
+		}
+		log.Printf("GetListingSlots: failed to get listing: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	rules, loc, exceptions, booked, err := loadCalendar(ctx, repo, listing.PostedBy, from, to)
+	if err != nil {
+		log.Printf("GetListingSlots: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	if len(rules) == 0 {
+		return []Slot{}, nil
+	}
+	return Slots(loc, rules, append(exceptions, booked...), SessionLength(listing.SessionDuration), from, to), nil
+}
+
+// Reserve books the slot starting at start for a new request on listingID,
+// inside the caller's transaction. Requests for providers without a
+// schedule carry no slot and start must be nil; for the rest start is
+// required and must be a free slot. Concurrent bookings of the same time
+// are rejected by the database.
+// returns ErrSlotRequired, ErrSlotUnavailable or ErrSlotTaken.
+func Reserve(ctx context.Context, repo *repository.Queries, requestID, listingID int32, start *time.Time) (*Slot, error) {
+	listing, err := repo.GetListingSchedule(ctx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("get listing schedule: %w", err)
+	}
+	if start == nil {
+		rules, err := repo.GetAvailabilityRules(ctx, listing.PostedBy)
+		if err != nil {
+			return nil, fmt.Errorf("get rules: %w", err)
+		}
+		if len(rules) > 0 {
+			return nil, internal.ErrSlotRequired
+		}
+		return nil, nil
+	}
+	if !start.After(time.Now()) {
+		return nil, internal.ErrSlotUnavailable
+	}
+	d := SessionLength(listing.SessionDuration)
+	rules, loc, exceptions, booked, err := loadCalendar(ctx, repo, listing.PostedBy, *start, start.Add(d))
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 || !Fits(loc, rules, exceptions, d, *start) {
+		return nil, internal.ErrSlotUnavailable
+	}
+	if !Fits(loc, rules, booked, d, *start) {
+		return nil, internal.ErrSlotTaken
+	}
+	slot := Slot{Start: start.In(loc), End: start.Add(d).In(loc)}
+	err = repo.InsertRequestSlot(ctx, repository.InsertRequestSlotParams{
+		RequestID:  requestID,
+		ProviderID: listing.PostedBy,
+		StartsAt:   slot.Start,
+		EndsAt:     slot.End,
+	})
+	if err != nil {
+		var pgerr *pgconn.PgError
+		if errors.As(err, &pgerr) && pgerr.Code == "23P01" {
+			return nil, internal.ErrSlotTaken
+		}
+		return nil, fmt.Errorf("insert slot: %w", err)
+	}
+	return &slot, nil
+}
+
+// loadCalendar reads what decides a provider's free slots between from and
+// to: the weekly rules in their timezone, and the exceptions and bookings
+// that block parts of them.
+func loadCalendar(ctx context.Context, repo *repository.Queries, providerID string, from, to time.Time) (rules []Rule, loc *time.Location, exceptions, booked []Slot, err error) {
+	dbRules, err := repo.GetAvailabilityRules(ctx, providerID)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("get rules: %w", err)
+	}
+	if len(dbRules) == 0 {
+		return nil, nil, nil, nil, nil
+	}
+	loc, err = time.LoadLocation(dbRules[0].Timezone)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("load timezone: %w", err)
+	}
+	dbExceptions, err := repo.GetAvailabilityExceptions(ctx, repository.GetAvailabilityExceptionsParams{
+		ProviderID: providerID,
+		RangeStart: from,
+		RangeEnd:   to,
+	})
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("get exceptions: %w", err)
+	}
+	dbBooked, err := repo.GetBookedSlots(ctx, repository.GetBookedSlotsParams{
+		ProviderID: providerID,
+		RangeStart: from,
+		RangeEnd:   to,
+	})
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("get booked slots: %w", err)
+	}
+	exceptions = make([]Slot, len(dbExceptions))
+	for i, e := range dbExceptions {
+		exceptions[i] = Slot{Start: e.StartsAt, End: e.EndsAt}
+	}
+	booked = make([]Slot, len(dbBooked))
+	for i, b := range dbBooked {
+		booked[i] = Slot{Start: b.StartsAt, End: b.EndsAt}
+	}
+	return toRules(dbRules), loc, exceptions, booked, nil
+}
+
+func toRules(dbRules []repository.AvailabilityRule) []Rule {
+	rules := make([]Rule, len(dbRules))
+	for i, r := range dbRules {
+		rules[i] = Rule{
+			ID:      r.ID,
+			Weekday: time.Weekday(r.Weekday),
+			Start:   formatClock(r.StartTime),
+			End:     formatClock(r.EndTime),
+		}
+	}
+	return rules
+}
@@ -0,0 +1,79 @@
+package category
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Category is a node in the listing taxonomy. Listings reference categories
+// by slug. ListingCount covers active listings in the category and all of
+// its subcategories.
+type Category struct {
+	ID           int32      `json:"id"`
+	Slug         string     `json:"slug"`
+	Name         string     `json:"name"`
+	ParentID     int32      `json:"parent_id,omitempty"`
+	Icon         string     `json:"icon,omitempty"`
+	SortOrder    int32      `json:"sort_order"`
+	ListingCount int64      `json:"listing_count"`
+	Children     []Category `json:"children,omitempty"`
+}
+
+// MigrateOptions controls MigrateListingCategories. Aliases maps free-text
+// values to category slugs and wins over automatic matching. Fallback, when
+// set, is the slug given to values nothing else matches. Nothing is written
+// unless Apply is set.
+type MigrateOptions struct {
+	Aliases  map[string]string
+	Fallback string
+	Apply    bool
+}
+
+// Mapping is one free-text category value and the slug it maps onto.
+type Mapping struct {
+	FreeText string `json:"free_text"`
+	Slug     string `json:"slug,omitempty"`
+	Listings int64  `json:"listings"`
+	Applied  bool   `json:"applied"`
+}
+
+type MigrationReport struct {
+	Mapped   []Mapping `json:"mapped"`
+	Unmapped []Mapping `json:"unmapped"`
+}
+
+// Slugify lowercases s and joins its words with dashes, so "Home Repair "
+// and "home-repair" both become "home-repair".
+func Slugify(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// buildTree nests categories under their parents and rolls listing counts
+// up to every ancestor. Categories keep the order they were given in.
+func buildTree(categories []Category) []Category {
+	children := make(map[int32][]int, len(categories))
+	for i, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], i)
+	}
+	var build func(parentID int32) []Category
+	build = func(parentID int32) []Category {
+		var nodes []Category
+		for _, i := range children[parentID] {
+			node := categories[i]
+			node.Children = build(node.ID)
+			for _, child := range node.Children {
+				node.ListingCount += child.ListingCount
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	roots := build(0)
+	if roots == nil {
+		roots = []Category{}
+	}
+	return roots
+}
@@ -0,0 +1,83 @@
+package category
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+)
+
+type CategoryHandler struct {
+	CategoryService CategoryService
+}
+
+func (ch *CategoryHandler) HandleGetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := ch.CategoryService.GetCategoryTree(r.Context())
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, categories, nil)
+}
+
+func (ch *CategoryHandler) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	c := Category{}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		log.Printf("HandleCreateCategory: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if Slugify(c.Name) == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "name is required", nil)
+		return
+	}
+	id, err := ch.CategoryService.CreateCategory(r.Context(), c)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	helpers.WriteData(w, http.StatusCreated, map[string]int32{"id": id}, nil)
+}
+
+func (ch *CategoryHandler) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		log.Printf("HandleUpdateCategory: %s \n", err)
+		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
+		return
+	}
+	c := Category{}
+	if err = json.NewDecoder(r.Body).Decode(&c); err != nil {
+		log.Printf("HandleUpdateCategory: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "name is required", nil)
+		return
+	}
+	c.ID = int32(id)
+	if err = ch.CategoryService.UpdateCategory(r.Context(), c); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "category updated", nil)
+}
+
+func writeCategoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrNoRecord):
+		helpers.WriteError(w, http.StatusNotFound, "no such record", nil)
+	case errors.Is(err, internal.ErrDuplicateID):
+		helpers.WriteError(w, http.StatusConflict, "a category with this slug already exists", nil)
+	case errors.Is(err, internal.ErrInvalidCategory):
+		helpers.WriteError(w, http.StatusBadRequest, "parent category does not exist or would create a cycle", nil)
+	default:
+		helpers.WriteServerError(w, nil)
+	}
+}
@@ -0,0 +1,10 @@
+package category
+
+import "context"
+
+type CategoryService interface {
+	GetCategoryTree(ctx context.Context) ([]Category, error)
+	CreateCategory(ctx context.Context, c Category) (int32, error)
+	UpdateCategory(ctx context.Context, c Category) error
+	MigrateListingCategories(ctx context.Context, opts MigrateOptions) (MigrationReport, error)
+}
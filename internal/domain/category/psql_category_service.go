@@ -0,0 +1,199 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type PostgresCategoryService struct {
+	DB *pgxpool.Pool
+}
+
+func (pcs *PostgresCategoryService) GetCategoryTree(ctx context.Context) ([]Category, error) {
+	repo := repository.New(pcs.DB)
+	dbCategories, err := repo.GetCategoriesWithListingCounts(ctx)
+	if err != nil {
+		log.Printf("GetCategoryTree: failed to get categories: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	categories := make([]Category, len(dbCategories))
+	for i, dbc := range dbCategories {
+		categories[i] = Category{
+			ID:           dbc.ID,
+			Slug:         dbc.Slug,
+			Name:         dbc.Name,
+			ParentID:     dbc.ParentID.Int32,
+			Icon:         dbc.Icon.String,
+			SortOrder:    dbc.SortOrder,
+			ListingCount: dbc.ListingCount,
+		}
+	}
+	return buildTree(categories), nil
+}
+
+// CreateCategory adds a category, deriving its slug from the name when none
+// is given. returns ErrDuplicateID if the slug is taken and
+// ErrInvalidCategory if the parent does not exist.
+func (pcs *PostgresCategoryService) CreateCategory(ctx context.Context, c Category) (int32, error) {
+	slug := Slugify(c.Slug)
+	if slug == "" {
+		slug = Slugify(c.Name)
+	}
+	repo := repository.New(pcs.DB)
+	id, err := repo.InsertCategory(ctx, repository.InsertCategoryParams{
+		Slug:      slug,
+		Name:      strings.TrimSpace(c.Name),
+		ParentID:  pgtype.Int4{Int32: c.ParentID, Valid: c.ParentID != 0},
+		Icon:      pgtype.Text{String: c.Icon, Valid: c.Icon != ""},
+		SortOrder: c.SortOrder,
+	})
+	if err != nil {
+		return -1, categoryWriteError("CreateCategory", err)
+	}
+	return id, nil
+}
+
+// UpdateCategory changes a category's name, parent, icon and position.
+// Slugs never change since listings refer to them. returns ErrNoRecord if
+// the category does not exist and ErrInvalidCategory if the new parent is
+// missing or would make the category its own ancestor.
+func (pcs *PostgresCategoryService) UpdateCategory(ctx context.Context, c Category) error {
+	repo := repository.New(pcs.DB)
+	if c.ParentID != 0 {
+		dbCategories, err := repo.GetCategories(ctx)
+		if err != nil {
+			log.Printf("UpdateCategory: failed to get categories: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+		parents := make(map[int32]int32, len(dbCategories))
+		for _, dbc := range dbCategories {
+			parents[dbc.ID] = dbc.ParentID.Int32
+		}
+		for id := c.ParentID; id != 0; id = parents[id] {
+			if id == c.ID {
+				return internal.ErrInvalidCategory
+			}
+		}
+	}
+	n, err := repo.UpdateCategory(ctx, repository.UpdateCategoryParams{
+		Name:      strings.TrimSpace(c.Name),
+		ParentID:  pgtype.Int4{Int32: c.ParentID, Valid: c.ParentID != 0},
+		Icon:      pgtype.Text{String: c.Icon, Valid: c.Icon != ""},
+		SortOrder: c.SortOrder,
+		ID:        c.ID,
+	})
+	if err != nil {
+		return categoryWriteError("UpdateCategory", err)
+	}
+	if n == 0 {
+		return internal.ErrNoRecord
+	}
+	return nil
+}
+
+func categoryWriteError(caller string, err error) error {
+	var pgerr *pgconn.PgError
+	if errors.As(err, &pgerr) {
+		switch pgerr.Code {
+		case "23505":
+			return internal.ErrDuplicateID
+		case "23503":
+			return internal.ErrInvalidCategory
+		}
+	}
+	log.Printf("%s: failed to write category: %s\n", caller, err)
+	return internal.ErrInternalServerError
+}
+
+// MigrateListingCategories maps listing categories that are not slugs of the
+// taxonomy onto it. A value matches a category whose slug or slugified name
+// equals the slugified value, ignoring a trailing "s". With opts.Apply the
+// matched listings are rewritten in one transaction.
+func (pcs *PostgresCategoryService) MigrateListingCategories(ctx context.Context, opts MigrateOptions) (MigrationReport, error) {
+	report := MigrationReport{Mapped: []Mapping{}, Unmapped: []Mapping{}}
+	tx, err := pcs.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("MigrateListingCategories: failed to begin tx: %s\n", err)
+		return report, internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pcs.DB).WithTx(tx)
+
+	dbCategories, err := repo.GetCategories(ctx)
+	if err != nil {
+		log.Printf("MigrateListingCategories: failed to get categories: %s\n", err)
+		return report, internal.ErrInternalServerError
+	}
+	known := make(map[string]string, 2*len(dbCategories))
+	for _, dbc := range dbCategories {
+		known[strings.TrimSuffix(Slugify(dbc.Name), "s")] = dbc.Slug
+		known[strings.TrimSuffix(dbc.Slug, "s")] = dbc.Slug
+	}
+	aliases := make(map[string]string, len(opts.Aliases))
+	for freeText, slug := range opts.Aliases {
+		if !isKnownSlug(dbCategories, slug) {
+			log.Printf("MigrateListingCategories: alias %q points at unknown slug %q\n", freeText, slug)
+			return report, internal.ErrInvalidCategory
+		}
+		aliases[Slugify(freeText)] = slug
+	}
+	if opts.Fallback != "" && !isKnownSlug(dbCategories, opts.Fallback) {
+		return report, internal.ErrInvalidCategory
+	}
+
+	unmapped, err := repo.GetUnmappedListingCategories(ctx)
+	if err != nil {
+		log.Printf("MigrateListingCategories: failed to get listing categories: %s\n", err)
+		return report, internal.ErrInternalServerError
+	}
+	for _, u := range unmapped {
+		m := Mapping{FreeText: u.Category, Listings: u.ListingCount}
+		key := Slugify(u.Category)
+		if slug, ok := aliases[key]; ok {
+			m.Slug = slug
+		} else if slug, ok := known[strings.TrimSuffix(key, "s")]; ok {
+			m.Slug = slug
+		} else {
+			m.Slug = opts.Fallback
+		}
+		if m.Slug == "" {
+			report.Unmapped = append(report.Unmapped, m)
+			continue
+		}
+		if opts.Apply {
+			_, err = repo.RemapListingCategory(ctx, repository.RemapListingCategoryParams{
+				Slug:     m.Slug,
+				FreeText: m.FreeText,
+			})
+			if err != nil {
+				log.Printf("MigrateListingCategories: failed to remap %q to %q: %s\n", m.FreeText, m.Slug, err)
+				return report, internal.ErrInternalServerError
+			}
+			m.Applied = true
+		}
+		report.Mapped = append(report.Mapped, m)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("MigrateListingCategories: failed to commit: %s\n", err)
+		return report, internal.ErrInternalServerError
+	}
+	return report, nil
+}
+
+func isKnownSlug(categories []repository.Category, slug string) bool {
+	for _, c := range categories {
+		if c.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
@@ -15,6 +15,17 @@ const (
 	REQUEST_EXPIRED     = "expired"
 	CANCELLED_REQUEST   = "cancelled"
 	REVIEWED_REQUEST    = "reviewed"
+	DISPUTE_OPENED      = "dispute_opened"
+	DISPUTE_RESPONDED   = "dispute_responded"
+	DISPUTE_RESOLVED    = "dispute_resolved"
+	WARNING_ISSUED      = "warning_issued"
+	LISTING_HIDDEN      = "listing_hidden"
+	LISTING_REMOVED     = "listing_removed"
+	LISTING_RESTORED    = "listing_restored"
+	APPEAL_DECIDED      = "appeal_decided"
+	SESSION_REMINDER    = "session_reminder"
+	PENDING_REMINDER    = "pending_reminder"
+	AUTO_COMPLETED      = "auto_completed"
 
 	USER_DO_NOT_EXIST = "no_provider"
 )
@@ -0,0 +1,141 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+// Journal entry kinds. Every token movement in the system is recorded
+// under exactly one of these.
+const (
+	ENTRY_OPENING_BALANCE   = "opening_balance"
+	ENTRY_REQUEST_ESCROW    = "request_escrow"
+	ENTRY_REQUEST_RELEASE   = "request_release"
+	ENTRY_REQUEST_REFUND    = "request_refund"
+	ENTRY_AD_REWARD         = "ad_reward"
+	ENTRY_REWARD_REDEMPTION = "reward_redemption"
+	ENTRY_SIGNUP_BONUS      = "signup_bonus"
+	ENTRY_ADJUSTMENT        = "reconciliation_adjustment"
+	ENTRY_DISPUTE_SPLIT     = "dispute_split"
+)
+
+// Reference types point a journal entry back at the row that caused it.
+const (
+	REF_SERVICE_REQUEST = "service_request"
+	REF_AD_WATCH        = "ads_watching_history"
+	REF_REWARD          = "reward"
+	REF_USER            = "user"
+)
+
+// Account identifies a ledger account by its stable code. Accounts are
+// created lazily the first time an entry touches them.
+type Account struct {
+	Code   string
+	Type   repository.LedgerAccountType
+	UserID string
+}
+
+var (
+	Escrow     = Account{Code: "system:escrow", Type: repository.LedgerAccountTypeEscrow}
+	SystemMint = Account{Code: "system:mint", Type: repository.LedgerAccountTypeSystemMint}
+	RewardSink = Account{Code: "system:reward_sink", Type: repository.LedgerAccountTypeRewardSink}
+)
+
+func UserWallet(userID string) Account {
+	return Account{
+		Code:   "user:" + userID,
+		Type:   repository.LedgerAccountTypeUserWallet,
+		UserID: userID,
+	}
+}
+
+// Line is a signed movement on one account. Positive amounts increase the
+// account balance, negative amounts decrease it.
+type Line struct {
+	Account Account
+	Amount  int32
+}
+
+// Entry is a balanced journal entry: the amounts of its lines sum to zero.
+type Entry struct {
+	Kind          string
+	Memo          string
+	ReferenceType string
+	ReferenceID   int32
+	Lines         []Line
+}
+
+// Move returns the two lines that move amount tokens from one account to another.
+func Move(from, to Account, amount int32) []Line {
+	return []Line{
+		{Account: from, Amount: -amount},
+		{Account: to, Amount: amount},
+	}
+}
+
+// StatementLine is one movement on a user's wallet as shown to the user.
+type StatementLine struct {
+	EntryID        int64     `json:"entry_id"`
+	Kind           string    `json:"kind"`
+	Memo           string    `json:"memo"`
+	ReferenceType  string    `json:"reference_type,omitempty"`
+	ReferenceID    int32     `json:"reference_id,omitempty"`
+	Amount         int32     `json:"amount"`
+	CounterAccount string    `json:"counter_account"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Statement struct {
+	Balance int32           `json:"balance"`
+	Lines   []StatementLine `json:"lines"`
+}
+
+type ReconcileOptions struct {
+	// UserID limits the run to one user when set.
+	UserID string
+	// SignupBonus is the number of tokens a paid sign-up was awarded.
+	SignupBonus int32
+	// Fix posts adjustment entries that bring drifted users back in line.
+	Fix bool
+}
+
+// Drift compares what a user's balance is against what their history says it
+// should be. ExpectedBalance is rebuilt from payments, ad history, redemptions
+// and the sign-up bonus; PaymentNet and TransactionNet are the request-related
+// part of that history as seen by the payment and transaction tables.
+type Drift struct {
+	UserID          string `json:"user_id"`
+	TokenBalance    int32  `json:"token_balance"`
+	ExpectedBalance int32  `json:"expected_balance"`
+	LedgerBalance   int32  `json:"ledger_balance"`
+	HasWallet       bool   `json:"has_wallet"`
+	PaymentNet      int32  `json:"payment_net"`
+	TransactionNet  int32  `json:"transaction_net"`
+	Corrected       bool   `json:"corrected"`
+}
+
+func (d Drift) BalanceDrift() int32 {
+	return d.TokenBalance - d.ExpectedBalance
+}
+
+func (d Drift) LedgerDrift() int32 {
+	if !d.HasWallet {
+		return 0
+	}
+	return d.LedgerBalance - d.TokenBalance
+}
+
+func (d Drift) TransactionDrift() int32 {
+	return d.TransactionNet - d.PaymentNet
+}
+
+func (d Drift) IsZero() bool {
+	return d.BalanceDrift() == 0 && d.LedgerDrift() == 0 && d.TransactionDrift() == 0
+}
+
+type DriftReport struct {
+	RanAt        time.Time `json:"ran_at"`
+	UsersChecked int       `json:"users_checked"`
+	Drifts       []Drift   `json:"drifts"`
+}
@@ -0,0 +1,22 @@
+package ledger
+
+import (
+	"net/http"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+)
+
+type LedgerHandler struct {
+	LedgerService LedgerService
+}
+
+func (lh *LedgerHandler) HandleGetOwnStatement(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	statement, err := lh.LedgerService.GetUserStatement(r.Context(), userID)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, statement, nil)
+}
@@ -0,0 +1,8 @@
+package ledger
+
+import "context"
+
+type LedgerService interface {
+	GetUserStatement(ctx context.Context, userID string) (Statement, error)
+	Reconcile(ctx context.Context, opts ReconcileOptions) (DriftReport, error)
+}
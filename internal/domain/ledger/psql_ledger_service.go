@@ -0,0 +1,324 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type PostgresLedgerService struct {
+	DB *pgxpool.Pool
+}
+
+// Post records a balanced journal entry using the caller's repository, so it
+// commits or rolls back together with the rest of the caller's transaction.
+// user.token_balance is kept as a cached projection of the wallet accounts and
+// is updated here; nothing else should write to it.
+// returns ErrInsufficientBalance if a wallet would go below zero
+func Post(ctx context.Context, repo *repository.Queries, e Entry) (int64, error) {
+	// accounts have to exist before any wallet balance moves so that a
+	// lazily opened wallet snapshots the balance from before this entry
+	accountIDs, err := prepare(ctx, repo, e)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, l := range e.Lines {
+		if l.Account.Type != repository.LedgerAccountTypeUserWallet {
+			continue
+		}
+		if l.Amount < 0 {
+			rows, err := repo.DebitUserBalance(ctx, repository.DebitUserBalanceParams{
+				Amount: -l.Amount,
+				UserID: l.Account.UserID,
+			})
+			if err != nil {
+				return -1, err
+			}
+			if rows != 1 {
+				return -1, internal.ErrInsufficientBalance
+			}
+		} else {
+			_, err := repo.CreditUserBalance(ctx, repository.CreditUserBalanceParams{
+				Amount: l.Amount,
+				UserID: l.Account.UserID,
+			})
+			if err != nil {
+				return -1, err
+			}
+		}
+	}
+
+	return insertEntry(ctx, repo, e, accountIDs)
+}
+
+// record writes a balanced entry without touching user.token_balance. It is
+// only used to bring the ledger back in line with balances during reconciliation.
+func record(ctx context.Context, repo *repository.Queries, e Entry) (int64, error) {
+	accountIDs, err := prepare(ctx, repo, e)
+	if err != nil {
+		return -1, err
+	}
+	return insertEntry(ctx, repo, e, accountIDs)
+}
+
+// prepare checks that the entry balances and returns the ids of its accounts.
+func prepare(ctx context.Context, repo *repository.Queries, e Entry) ([]int32, error) {
+	if len(e.Lines) < 2 {
+		return nil, internal.ErrUnbalancedEntry
+	}
+	var sum int64
+	for _, l := range e.Lines {
+		if l.Amount == 0 {
+			return nil, internal.ErrUnbalancedEntry
+		}
+		sum += int64(l.Amount)
+	}
+	if sum != 0 {
+		return nil, internal.ErrUnbalancedEntry
+	}
+
+	accountIDs := make([]int32, len(e.Lines))
+	for i, l := range e.Lines {
+		id, err := ensureAccount(ctx, repo, l.Account)
+		if err != nil {
+			return nil, err
+		}
+		accountIDs[i] = id
+	}
+	return accountIDs, nil
+}
+
+func insertEntry(ctx context.Context, repo *repository.Queries, e Entry, accountIDs []int32) (int64, error) {
+	entryID, err := repo.InsertJournalEntry(ctx, repository.InsertJournalEntryParams{
+		Kind:          e.Kind,
+		Memo:          e.Memo,
+		ReferenceType: pgtype.Text{String: e.ReferenceType, Valid: e.ReferenceType != ""},
+		ReferenceID:   pgtype.Int4{Int32: e.ReferenceID, Valid: e.ReferenceType != ""},
+	})
+	if err != nil {
+		return -1, err
+	}
+	for i, l := range e.Lines {
+		err = repo.InsertJournalLine(ctx, repository.InsertJournalLineParams{
+			EntryID:   entryID,
+			AccountID: accountIDs[i],
+			Amount:    l.Amount,
+		})
+		if err != nil {
+			return -1, err
+		}
+	}
+	return entryID, nil
+}
+
+// ensureAccount returns the id of the account, creating it if needed. A newly
+// created wallet is opened with the user's current token_balance, minted from
+// the system, so the ledger agrees with balances that predate it.
+func ensureAccount(ctx context.Context, repo *repository.Queries, a Account) (int32, error) {
+	id, err := repo.InsertLedgerAccount(ctx, repository.InsertLedgerAccountParams{
+		Code:   a.Code,
+		Type:   a.Type,
+		UserID: pgtype.Text{String: a.UserID, Valid: a.UserID != ""},
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return -1, err
+		}
+		account, err := repo.GetLedgerAccountByCode(ctx, a.Code)
+		if err != nil {
+			return -1, err
+		}
+		return account.ID, nil
+	}
+
+	if a.Type != repository.LedgerAccountTypeUserWallet {
+		return id, nil
+	}
+	balance, err := repo.GetUserTokenBalance(ctx, a.UserID)
+	if err != nil {
+		return -1, err
+	}
+	if balance == 0 {
+		return id, nil
+	}
+	mintID, err := ensureAccount(ctx, repo, SystemMint)
+	if err != nil {
+		return -1, err
+	}
+	_, err = insertEntry(ctx, repo, Entry{
+		Kind:          ENTRY_OPENING_BALANCE,
+		Memo:          "Opening balance",
+		ReferenceType: REF_USER,
+		Lines:         Move(SystemMint, a, balance),
+	}, []int32{mintID, id})
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (pls *PostgresLedgerService) GetUserStatement(ctx context.Context, userID string) (Statement, error) {
+	tx, err := pls.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("GetUserStatement: failed to begin tx: %s\n", err)
+		return Statement{}, internal.ErrInternalServerError
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			log.Printf("GetUserStatement: failed to rollback tx: %s\n", err)
+		}
+	}()
+	repo := repository.New(pls.DB).WithTx(tx)
+
+	accountID, err := ensureAccount(ctx, repo, UserWallet(userID))
+	if err != nil {
+		log.Printf("GetUserStatement: failed to open wallet account: %s\n", err)
+		return Statement{}, internal.ErrInternalServerError
+	}
+	balance, err := repo.GetLedgerAccountBalance(ctx, accountID)
+	if err != nil {
+		log.Printf("GetUserStatement: failed to get wallet balance: %s\n", err)
+		return Statement{}, internal.ErrInternalServerError
+	}
+	dbLines, err := repo.GetUserLedgerEntries(ctx, pgtype.Text{String: userID, Valid: true})
+	if err != nil {
+		log.Printf("GetUserStatement: failed to get ledger entries: %s\n", err)
+		return Statement{}, internal.ErrInternalServerError
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("GetUserStatement: failed to commit: %s\n", err)
+		return Statement{}, internal.ErrInternalServerError
+	}
+
+	statement := Statement{Balance: balance, Lines: make([]StatementLine, len(dbLines))}
+	for i, l := range dbLines {
+		statement.Lines[i] = StatementLine{
+			EntryID:        l.EntryID,
+			Kind:           l.Kind,
+			Memo:           l.Memo,
+			ReferenceType:  l.ReferenceType.String,
+			ReferenceID:    l.ReferenceID.Int32,
+			Amount:         l.Amount,
+			CounterAccount: l.CounterAccountCode,
+			CreatedAt:      l.CreatedAt,
+		}
+	}
+	return statement, nil
+}
+
+func toDrift(row repository.GetBalanceComponentsRow, signupBonus int32) Drift {
+	paymentNet := row.TokensEarned + row.TokensRefunded - row.TokensEscrowed
+	expected := paymentNet + row.AdTokens - row.RewardsSpent
+	if row.IsPaid {
+		expected += signupBonus
+	}
+	return Drift{
+		UserID:          row.UserID,
+		TokenBalance:    row.TokenBalance,
+		ExpectedBalance: expected,
+		LedgerBalance:   row.LedgerBalance,
+		HasWallet:       row.WalletAccountID.Valid,
+		PaymentNet:      paymentNet,
+		TransactionNet:  row.TransactionNet,
+	}
+}
+
+// Reconcile recomputes every user's expected balance from their history and
+// reports the users whose balance, wallet account or transaction history
+// disagree with it. With opts.Fix set, each drifted user is corrected in its
+// own transaction; transaction history is only ever reported, not rewritten.
+func (pls *PostgresLedgerService) Reconcile(ctx context.Context, opts ReconcileOptions) (DriftReport, error) {
+	report := DriftReport{RanAt: time.Now()}
+	repo := repository.New(pls.DB)
+	rows, err := repo.GetBalanceComponents(ctx, pgtype.Text{String: opts.UserID, Valid: opts.UserID != ""})
+	if err != nil {
+		log.Printf("Reconcile: failed to get balance components: %s\n", err)
+		return DriftReport{}, internal.ErrInternalServerError
+	}
+	report.UsersChecked = len(rows)
+	report.Drifts = []Drift{}
+
+	for _, row := range rows {
+		drift := toDrift(row, opts.SignupBonus)
+		if drift.IsZero() {
+			continue
+		}
+		if opts.Fix && (drift.BalanceDrift() != 0 || drift.LedgerDrift() != 0) {
+			fixed, err := pls.correct(ctx, drift.UserID, opts.SignupBonus)
+			if err != nil {
+				log.Printf("Reconcile: failed to correct user %s: %s\n", drift.UserID, err)
+			} else {
+				drift = fixed
+			}
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
+	return report, nil
+}
+
+// correct locks the user's balance, recomputes their drift and posts the
+// adjustment entries that bring both the ledger and token_balance back to the
+// expected balance. The returned drift describes the user before correction.
+func (pls *PostgresLedgerService) correct(ctx context.Context, userID string, signupBonus int32) (Drift, error) {
+	tx, err := pls.DB.Begin(ctx)
+	if err != nil {
+		return Drift{}, err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			log.Printf("correct: failed to rollback tx: %s\n", err)
+		}
+	}()
+	repo := repository.New(pls.DB).WithTx(tx)
+
+	_, err = repo.LockUserBalance(ctx, userID)
+	if err != nil {
+		return Drift{}, err
+	}
+	rows, err := repo.GetBalanceComponents(ctx, pgtype.Text{String: userID, Valid: true})
+	if err != nil {
+		return Drift{}, err
+	}
+	if len(rows) != 1 {
+		return Drift{}, pgx.ErrNoRows
+	}
+	drift := toDrift(rows[0], signupBonus)
+	wallet := UserWallet(userID)
+
+	if ledgerDrift := drift.LedgerDrift(); ledgerDrift != 0 {
+		_, err = record(ctx, repo, Entry{
+			Kind:          ENTRY_ADJUSTMENT,
+			Memo:          "Ledger brought in line with balance",
+			ReferenceType: REF_USER,
+			Lines:         Move(SystemMint, wallet, -ledgerDrift),
+		})
+		if err != nil {
+			return Drift{}, err
+		}
+	}
+	if balanceDrift := drift.BalanceDrift(); balanceDrift != 0 {
+		_, err = Post(ctx, repo, Entry{
+			Kind:          ENTRY_ADJUSTMENT,
+			Memo:          "Balance corrected by reconciliation",
+			ReferenceType: REF_USER,
+			Lines:         Move(SystemMint, wallet, -balanceDrift),
+		})
+		if err != nil {
+			return Drift{}, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return Drift{}, err
+	}
+	drift.Corrected = true
+	return drift, nil
+}
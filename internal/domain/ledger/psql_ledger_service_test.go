@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+	"github.com/set-kaung/senior_project_1/internal/repository/repotest"
+)
+
+func TestPostRejectsUnbalancedEntries(t *testing.T) {
+	alice, bob := UserWallet("alice"), UserWallet("bob")
+	tests := []struct {
+		name  string
+		lines []Line
+	}{
+		{"no lines", nil},
+		{"one line", []Line{{Account: alice, Amount: 5}}},
+		{"one line of zero", []Line{{Account: alice, Amount: 0}}},
+		{"does not sum to zero", []Line{{Account: alice, Amount: -5}, {Account: Escrow, Amount: 4}}},
+		{"both sides positive", []Line{{Account: alice, Amount: 5}, {Account: bob, Amount: 5}}},
+		{"zero line that balances", []Line{{Account: alice, Amount: -5}, {Account: Escrow, Amount: 5}, {Account: bob, Amount: 0}}},
+		{"three lines off by one", []Line{{Account: alice, Amount: -10}, {Account: Escrow, Amount: 6}, {Account: bob, Amount: 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repotest.New()
+			_, err := Post(context.Background(), repository.New(db), Entry{Kind: ENTRY_REQUEST_ESCROW, Lines: tt.lines})
+			if !errors.Is(err, internal.ErrUnbalancedEntry) {
+				t.Fatalf("Post() error = %v, want ErrUnbalancedEntry", err)
+			}
+			if calls := db.Calls(""); len(calls) != 0 {
+				t.Errorf("an unbalanced entry touched the database: %v", calls)
+			}
+		})
+	}
+}
+
+func TestPost(t *testing.T) {
+	entry := Entry{
+		Kind:          ENTRY_REQUEST_ESCROW,
+		Memo:          "Escrow for request",
+		ReferenceType: REF_SERVICE_REQUEST,
+		ReferenceID:   3,
+		Lines:         append(Move(UserWallet("alice"), Escrow, 5), Move(Escrow, UserWallet("bob"), 2)...),
+	}
+	newDB := func(debited int64) *repotest.DB {
+		db := repotest.New()
+		db.Returns("InsertLedgerAccount", []any{int32(1)})
+		db.Returns("GetUserTokenBalance", []any{int32(0)})
+		db.Affects("DebitUserBalance", debited)
+		db.Returns("CreditUserBalance", []any{int32(7)})
+		db.Returns("InsertJournalEntry", []any{int64(42)})
+		return db
+	}
+
+	t.Run("balanced", func(t *testing.T) {
+		db := newDB(1)
+		id, err := Post(context.Background(), repository.New(db), entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != 42 {
+			t.Errorf("Post() = %d, want the journal entry id 42", id)
+		}
+		debits := db.Calls("DebitUserBalance")
+		if len(debits) != 1 || debits[0].Args[0] != int32(5) || debits[0].Args[1] != "alice" {
+			t.Errorf("DebitUserBalance calls = %v, want 5 from alice", debits)
+		}
+		credits := db.Calls("CreditUserBalance")
+		if len(credits) != 1 || credits[0].Args[0] != int32(2) || credits[0].Args[1] != "bob" {
+			t.Errorf("CreditUserBalance calls = %v, want 2 to bob", credits)
+		}
+		lines := db.Calls("InsertJournalLine")
+		if len(lines) != len(entry.Lines) {
+			t.Fatalf("inserted %d journal lines, want %d", len(lines), len(entry.Lines))
+		}
+		for i, l := range lines {
+			if l.Args[0] != int64(42) || l.Args[2] != entry.Lines[i].Amount {
+				t.Errorf("journal line %d = %v, want %d on entry 42", i, l.Args, entry.Lines[i].Amount)
+			}
+		}
+	})
+
+	t.Run("insufficient balance", func(t *testing.T) {
+		db := newDB(0)
+		_, err := Post(context.Background(), repository.New(db), entry)
+		if !errors.Is(err, internal.ErrInsufficientBalance) {
+			t.Fatalf("Post() error = %v, want ErrInsufficientBalance", err)
+		}
+		if calls := db.Calls("InsertJournalEntry"); len(calls) != 0 {
+			t.Errorf("recorded an entry that overdraws a wallet")
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package listing
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/geo"
+)
+
+// listingCursor marks the last listing of a page by its sort value and id,
+// which together give listings a total order for every sort key. Near is
+// the point the page was searched around, since distances and the near
+// filter only hold for that point.
+type listingCursor struct {
+	Sort      string
+	SortValue float64
+	ID        int32
+	Near      *geo.Point
+}
+
+func (c listingCursor) encode() string {
+	raw := fmt.Sprintf("%s:%s:%d", c.Sort, formatFloat(c.SortValue), c.ID)
+	if c.Near != nil {
+		raw += fmt.Sprintf(":%s:%s", formatFloat(c.Near.Latitude), formatFloat(c.Near.Longitude))
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// decodeListingCursor parses a cursor handed out for the given sort around
+// near, which is nil when the listings were not searched around a point.
+// returns ErrInvalidCursor if it is malformed or was made for another sort
+// or point.
+func decodeListingCursor(cursor string, sort string, near *geo.Point) (listingCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return listingCursor{}, internal.ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if (len(parts) != 3 && len(parts) != 5) || parts[0] != sort {
+		return listingCursor{}, internal.ErrInvalidCursor
+	}
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return listingCursor{}, internal.ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 32)
+	if err != nil {
+		return listingCursor{}, internal.ErrInvalidCursor
+	}
+	if (len(parts) == 5) != (near != nil) {
+		return listingCursor{}, internal.ErrInvalidCursor
+	}
+	if near != nil {
+		lat, err := strconv.ParseFloat(parts[3], 64)
+		if err != nil || lat != near.Latitude {
+			return listingCursor{}, internal.ErrInvalidCursor
+		}
+		lng, err := strconv.ParseFloat(parts[4], 64)
+		if err != nil || lng != near.Longitude {
+			return listingCursor{}, internal.ErrInvalidCursor
+		}
+	}
+	return listingCursor{Sort: sort, SortValue: value, ID: int32(id), Near: near}, nil
+}
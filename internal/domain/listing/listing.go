@@ -4,9 +4,34 @@ import (
 	"time"
 
 	"github.com/set-kaung/senior_project_1/internal/domain/user"
+	"github.com/set-kaung/senior_project_1/internal/geo"
 	"github.com/set-kaung/senior_project_1/internal/repository"
 )
 
+const (
+	LISTING_ACTIVE       = "active"
+	LISTING_INACTIVE     = "inactive"
+	LISTING_UNDER_REVIEW = "under_review"
+	LISTING_REMOVED      = "removed"
+)
+
+// Sort keys for browsing listings. newest and rating sort descending,
+// price and distance sort cheapest and closest first. relevance only applies
+// to search and distance only to browsing near a point; each is the default
+// of its mode.
+const (
+	SORT_NEWEST    = "newest"
+	SORT_RATING    = "rating"
+	SORT_PRICE     = "price"
+	SORT_RELEVANCE = "relevance"
+	SORT_DISTANCE  = "distance"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE     = 100
+)
+
 type Listing struct {
 	ID              int32         `json:"id"`
 	Title           string        `json:"title"`
@@ -23,6 +48,19 @@ type Listing struct {
 	ContactMethod   string        `json:"contact_method"`
 	AvgRating       float32       `json:"avg_rating"`
 	Warning         Warning       `json:"warning,omitzero"`
+	// Snippet is set on search results: matched description text,
+	// HTML-escaped, with the matching words wrapped in <mark> tags.
+	Snippet  string    `json:"snippet,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	// DistanceKm is set when browsing near a point.
+	DistanceKm float64 `json:"distance_km,omitempty"`
+}
+
+// Location is where a listing's service is offered. ServiceRadiusKm, when
+// set, limits near-me results to users within that distance.
+type Location struct {
+	geo.Point
+	ServiceRadiusKm float64 `json:"service_radius_km,omitempty"`
 }
 
 type ListingReport struct {
@@ -43,3 +81,28 @@ type Warning struct {
 	Reason    string                     `json:"reason"`
 	ListingID int32                      `json:"listing_id"`
 }
+
+// ListingQuery filters and pages through the listings a user can browse.
+// Zero values leave a filter unset. Cursor is the NextCursor of the previous
+// page and is only valid with the same Sort. Near, or the user's own
+// location when NearMe is set, restricts results to listings whose service
+// radius and MaxDistanceKm both reach that point.
+type ListingQuery struct {
+	Near          *geo.Point
+	NearMe        bool
+	MaxDistanceKm float64
+	Category      string
+	MinReward     int32
+	MaxReward     int32
+	MinRating     float64
+	PostedAfter   time.Time
+	PostedBefore  time.Time
+	Sort          string
+	Cursor        string
+	Limit         int32
+}
+
+type ListingPage struct {
+	Listings   []Listing `json:"listings"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
@@ -3,13 +3,18 @@ package listing
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/set-kaung/senior_project_1/internal"
 
 	"github.com/set-kaung/senior_project_1/internal/domain/user"
+	"github.com/set-kaung/senior_project_1/internal/geo"
 	"github.com/set-kaung/senior_project_1/internal/helpers"
 )
 
@@ -27,11 +32,19 @@ func (lh *ListingHandler) HandleCreateListing(w http.ResponseWriter, r *http.Req
 		return
 	}
 	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	if err = validateLocation(listingRequest.Location); err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
 
 	listingRequest.Provider = user.User{ID: userID}
 
 	_, err = lh.ListingService.CreateListing(r.Context(), listingRequest)
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidCategory) {
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
 		log.Println("listing_handler -> HandleViewOwnProfile: ", err)
 		helpers.WriteError(w, http.StatusInternalServerError, "error creating listing", nil)
 		return
@@ -61,13 +74,113 @@ func (lh *ListingHandler) HandleGetListingByID(w http.ResponseWriter, r *http.Re
 func (lh *ListingHandler) HandleGetAllListings(w http.ResponseWriter, r *http.Request) {
 	id, _ := r.Context().Value(internal.UserIDContextKey).(string)
 
-	listings, err := lh.ListingService.GetAllListings(r.Context(), id)
+	query, err := parseListingQuery(r.URL.Query())
 	if err != nil {
-		log.Println("listing_handler -> HandleViewOwnProfile: ", err)
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if query.Sort == SORT_RELEVANCE {
+		helpers.WriteError(w, http.StatusBadRequest, "sort must be newest, rating, price or distance", nil)
+		return
+	}
+	if err = parseNearQuery(r.URL.Query(), &query); err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	page, err := lh.ListingService.GetAllListings(r.Context(), id, query)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidCursor) || errors.Is(err, internal.ErrNoLocation) || errors.Is(err, internal.ErrInvalidCategory) {
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		log.Println("listing_handler -> HandleGetAllListings: ", err)
 		helpers.WriteError(w, http.StatusInternalServerError, "user not found", nil)
 		return
 	}
-	helpers.WriteData(w, http.StatusOK, listings, nil)
+	helpers.WriteData(w, http.StatusOK, page, nil)
+}
+
+func (lh *ListingHandler) HandleSearchListings(w http.ResponseWriter, r *http.Request) {
+	id, _ := r.Context().Value(internal.UserIDContextKey).(string)
+
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "q is required", nil)
+		return
+	}
+	query, err := parseListingQuery(r.URL.Query())
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if query.Sort == SORT_DISTANCE {
+		helpers.WriteError(w, http.StatusBadRequest, "sort must be relevance, newest, rating or price", nil)
+		return
+	}
+	if err = parseNearQuery(r.URL.Query(), &query); err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	page, err := lh.ListingService.SearchListings(r.Context(), id, text, query)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidCursor) || errors.Is(err, internal.ErrNoLocation) || errors.Is(err, internal.ErrInvalidCategory) {
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, page, nil)
+}
+
+// parseListingQuery reads the filter, sort and paging parameters of GET /services.
+func parseListingQuery(values url.Values) (ListingQuery, error) {
+	q := ListingQuery{
+		Category: values.Get("category"),
+		Sort:     values.Get("sort"),
+		Cursor:   values.Get("cursor"),
+	}
+	switch q.Sort {
+	case "", SORT_NEWEST, SORT_RATING, SORT_PRICE, SORT_RELEVANCE, SORT_DISTANCE:
+	default:
+		return q, errors.New("sort must be newest, rating, price, relevance or distance")
+	}
+	for name, dst := range map[string]*int32{
+		"min_reward": &q.MinReward,
+		"max_reward": &q.MaxReward,
+		"limit":      &q.Limit,
+	} {
+		if v := values.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || n < 0 {
+				return q, fmt.Errorf("%s must be a non-negative integer", name)
+			}
+			*dst = int32(n)
+		}
+	}
+	if q.MaxReward > 0 && q.MinReward > q.MaxReward {
+		return q, errors.New("min_reward cannot be greater than max_reward")
+	}
+	if v := values.Get("min_rating"); v != "" {
+		rating, err := strconv.ParseFloat(v, 64)
+		if err != nil || rating < 0 || rating > 5 {
+			return q, errors.New("min_rating must be between 0 and 5")
+		}
+		q.MinRating = rating
+	}
+	for name, dst := range map[string]*time.Time{
+		"posted_after":  &q.PostedAfter,
+		"posted_before": &q.PostedBefore,
+	} {
+		if v := values.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return q, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*dst = t
+		}
+	}
+	return q, nil
 }
 
 func (lh *ListingHandler) HandleGetOwnListings(w http.ResponseWriter, r *http.Request) {
@@ -107,10 +220,18 @@ func (lh *ListingHandler) HandleUpdateListing(w http.ResponseWriter, r *http.Req
 		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
 		return
 	}
+	if err = validateLocation(listingRequest.Location); err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
 	listingRequest.Provider.ID = userID
 	listingRequest.ID = int32(id)
 	lid, err := lh.ListingService.UpdateListing(r.Context(), listingRequest)
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidCategory) {
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
 		log.Printf("listing_handler -> HandleUpdateListing: failed to update listing: %v\n", err)
 		helpers.WriteServerError(w, nil)
 		return
@@ -149,6 +270,45 @@ func (lh *ListingHandler) HandleReportListing(w http.ResponseWriter, r *http.Req
 	helpers.WriteSuccess(w, http.StatusCreated, "report submitted successfully", nil)
 }
 
+func (lh *ListingHandler) HandleAppealListing(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	listingID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		log.Printf("listing_handler -> HandleAppealListing: failed to parse integer %v\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "unprocessable entity", nil)
+		return
+	}
+	body := struct {
+		Reason string `json:"reason"`
+	}{}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("listing_handler -> HandleAppealListing: failed to decode json: %v\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if strings.TrimSpace(body.Reason) == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "reason is required", nil)
+		return
+	}
+	appealID, err := lh.ListingService.AppealListing(r.Context(), int32(listingID), userID, body.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErrNoRecord):
+			helpers.WriteError(w, http.StatusNotFound, "no such record", nil)
+		case errors.Is(err, internal.ErrUnauthorized):
+			helpers.WriteError(w, http.StatusUnauthorized, "you can only appeal your own listing", nil)
+		case errors.Is(err, internal.ErrNothingToAppeal):
+			helpers.WriteError(w, http.StatusConflict, err.Error(), nil)
+		case errors.Is(err, internal.ErrDuplicateID):
+			helpers.WriteError(w, http.StatusConflict, "an appeal for this listing is already pending", nil)
+		default:
+			helpers.WriteServerError(w, nil)
+		}
+		return
+	}
+	helpers.WriteData(w, http.StatusCreated, map[string]int32{"appeal_id": appealID}, nil)
+}
+
 func (lh *ListingHandler) HandleDeleteListing(w http.ResponseWriter, r *http.Request) {
 	pathID := r.PathValue("id")
 	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
@@ -181,3 +341,50 @@ func (lh *ListingHandler) HandleGetListingReviews(w http.ResponseWriter, r *http
 	}
 	helpers.WriteData(w, http.StatusOK, reviews, nil)
 }
+
+// parseNearQuery reads the near-me parameters of GET /services and
+// GET /services/search: either
+// near=me for the user's saved location or an explicit lat and lng, plus an
+// optional max_distance_km.
+func parseNearQuery(values url.Values, q *ListingQuery) error {
+	lat, lng := values.Get("lat"), values.Get("lng")
+	switch {
+	case values.Get("near") == "me":
+		q.NearMe = true
+	case values.Get("near") != "":
+		return errors.New("near must be me")
+	case lat != "" || lng != "":
+		p := geo.Point{}
+		var errLat, errLng error
+		p.Latitude, errLat = strconv.ParseFloat(lat, 64)
+		p.Longitude, errLng = strconv.ParseFloat(lng, 64)
+		if errLat != nil || errLng != nil || !p.Valid() {
+			return errors.New("lat and lng must both be valid coordinates")
+		}
+		q.Near = &p
+	}
+	if v := values.Get("max_distance_km"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || d <= 0 {
+			return errors.New("max_distance_km must be a positive number")
+		}
+		q.MaxDistanceKm = d
+	}
+	if !q.NearMe && q.Near == nil && (q.Sort == SORT_DISTANCE || q.MaxDistanceKm > 0) {
+		return errors.New("distance sorting and max_distance_km need near=me or lat and lng")
+	}
+	return nil
+}
+
+func validateLocation(loc *Location) error {
+	if loc == nil {
+		return nil
+	}
+	if !loc.Valid() {
+		return errors.New("latitude must be within -90 and 90, longitude within -180 and 180")
+	}
+	if loc.ServiceRadiusKm < 0 {
+		return errors.New("service_radius_km cannot be negative")
+	}
+	return nil
+}
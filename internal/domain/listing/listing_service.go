@@ -8,11 +8,13 @@ import (
 
 type ListingService interface {
 	CreateListing(context.Context, Listing) (int32, error)
-	GetAllListings(context.Context, string) ([]Listing, error)
+	GetAllListings(ctx context.Context, userID string, q ListingQuery) (ListingPage, error)
+	SearchListings(ctx context.Context, userID string, text string, q ListingQuery) (ListingPage, error)
 	GetListingByID(context.Context, int32, string) (Listing, error)
 	GetListingsByUserID(context.Context, string) ([]Listing, error)
 	UpdateListing(context.Context, Listing) (int32, error)
 	DeleteListing(context.Context, int32, string) error
 	ReportListing(ctx context.Context, lr ListingReport) error
+	AppealListing(ctx context.Context, listingID int32, appellantID string, reason string) (int32, error)
 	GetListingReviews(ctx context.Context, listingID int32) ([]review.Review, error)
 }
@@ -2,30 +2,100 @@ package listing
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"html"
 	"log"
+	"math"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain"
+	"github.com/set-kaung/senior_project_1/internal/domain/notification"
 	"github.com/set-kaung/senior_project_1/internal/domain/review"
 	"github.com/set-kaung/senior_project_1/internal/domain/user"
+	"github.com/set-kaung/senior_project_1/internal/geo"
 	"github.com/set-kaung/senior_project_1/internal/repository"
 )
 
 type PostgresListingService struct {
 	DB *pgxpool.Pool
+	// ReportThreshold is the number of pending reports that hides a
+	// listing until a moderator looks at it. Zero disables auto-hiding.
+	ReportThreshold int32
 }
 
-func (pls *PostgresListingService) GetAllListings(ctx context.Context, postedBy string) ([]Listing, error) {
-	repo := repository.New(pls.DB)
-	dbListings, err := repo.GetAllListings(ctx, postedBy)
+// GetAllListings returns one page of the active listings userID can request,
+// ordered by q.Sort. q.Category matches the category and its subcategories.
+// returns ErrInvalidCursor if q.Cursor cannot be used, ErrInvalidCategory if
+// q.Category is not in the taxonomy and ErrNoLocation if q.NearMe is set but
+// the user has no location.
+func (pls *PostgresListingService) GetAllListings(ctx context.Context, userID string, q ListingQuery) (ListingPage, error) {
+	return getAllListings(ctx, repository.New(pls.DB), userID, q)
+}
+
+func getAllListings(ctx context.Context, repo *repository.Queries, userID string, q ListingQuery) (ListingPage, error) {
+	if q.Category != "" {
+		category, err := resolveCategory(ctx, repo, q.Category)
+		if err != nil {
+			return ListingPage{}, err
+		}
+		q.Category = category
+	}
+	if err := resolveNearMe(ctx, repo, userID, &q); err != nil {
+		return ListingPage{}, err
+	}
+	if q.Sort == "" {
+		q.Sort = SORT_NEWEST
+		if q.Near != nil {
+			q.Sort = SORT_DISTANCE
+		}
+	}
+	if q.Limit <= 0 || q.Limit > MAX_PAGE_SIZE {
+		q.Limit = DEFAULT_PAGE_SIZE
+	}
+	params := repository.GetAllListingsParams{
+		Sort:              q.Sort,
+		PostedBy:          userID,
+		Category:          pgtype.Text{String: q.Category, Valid: q.Category != ""},
+		MinReward:         pgtype.Int4{Int32: q.MinReward, Valid: q.MinReward > 0},
+		MaxReward:         pgtype.Int4{Int32: q.MaxReward, Valid: q.MaxReward > 0},
+		MinProviderRating: pgtype.Float8{Float64: q.MinRating, Valid: q.MinRating > 0},
+		PostedAfter:       pgtype.Timestamptz{Time: q.PostedAfter, Valid: !q.PostedAfter.IsZero()},
+		PostedBefore:      pgtype.Timestamptz{Time: q.PostedBefore, Valid: !q.PostedBefore.IsZero()},
+		MaxDistanceKm:     pgtype.Float8{Float64: q.MaxDistanceKm, Valid: q.MaxDistanceKm > 0},
+		// one extra row tells us whether there is a next page
+		PageSize: q.Limit + 1,
+	}
+	if q.Near != nil {
+		params.NearLat = pgtype.Float8{Float64: q.Near.Latitude, Valid: true}
+		params.NearLng = pgtype.Float8{Float64: q.Near.Longitude, Valid: true}
+	}
+	if q.Cursor != "" {
+		cursor, err := decodeListingCursor(q.Cursor, q.Sort, q.Near)
+		if err != nil {
+			return ListingPage{}, err
+		}
+		params.CursorValue = pgtype.Float8{Float64: cursor.SortValue, Valid: true}
+		params.CursorID = pgtype.Int4{Int32: cursor.ID, Valid: true}
+	}
+
+	dbListings, err := repo.GetAllListings(ctx, params)
 	if err != nil {
 		log.Println("psql_listing_service -> GetAllListings: err getting all listings : ", err)
-		return nil, err
+		return ListingPage{}, err
+	}
+	page := ListingPage{}
+	if len(dbListings) > int(q.Limit) {
+		dbListings = dbListings[:q.Limit]
+		last := dbListings[len(dbListings)-1]
+		page.NextCursor = listingCursor{Sort: q.Sort, SortValue: last.SortValue, ID: last.ID, Near: q.Near}.encode()
 	}
 	listings := make([]Listing, len(dbListings))
 	for i := range len(dbListings) {
@@ -59,9 +129,171 @@ func (pls *PostgresListingService) GetAllListings(ctx context.Context, postedBy
 			ContactMethod:   dbListing.ContactMethod.String,
 			AvgRating:       avgRating,
 		}
+		if dbListing.Latitude.Valid && dbListing.Longitude.Valid {
+			loc := &Location{
+				Point:           geo.Point{Latitude: dbListing.Latitude.Float64, Longitude: dbListing.Longitude.Float64},
+				ServiceRadiusKm: dbListing.ServiceRadiusKm.Float64,
+			}
+			listings[i].Location = loc
+			if q.Near != nil {
+				listings[i].DistanceKm = math.Round(geo.DistanceKm(*q.Near, loc.Point)*10) / 10
+			}
+		}
 	}
+	page.Listings = listings
 
-	return listings, nil
+	return page, nil
+}
+
+// resolveNearMe sets q.Near to the user's saved location when q.NearMe is set.
+func resolveNearMe(ctx context.Context, repo *repository.Queries, userID string, q *ListingQuery) error {
+	if !q.NearMe {
+		return nil
+	}
+	dbLocation, err := repo.GetUserLocation(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNoLocation
+		}
+		log.Printf("psql_listing_service -> resolveNearMe: failed to get user location: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	q.Near = &geo.Point{Latitude: dbLocation.Latitude, Longitude: dbLocation.Longitude}
+	return nil
+}
+
+// SearchListings returns one page of the active listings userID can request
+// that match every word of text as a prefix, with the same filters as
+// GetAllListings. Results are ordered by relevance unless q.Sort says otherwise.
+// returns ErrInvalidCursor if q.Cursor cannot be used, ErrInvalidCategory if
+// q.Category is not in the taxonomy and ErrNoLocation if q.NearMe is set but
+// the user has no location.
+func (pls *PostgresListingService) SearchListings(ctx context.Context, userID string, text string, q ListingQuery) (ListingPage, error) {
+	return searchListings(ctx, repository.New(pls.DB), userID, text, q)
+}
+
+func searchListings(ctx context.Context, repo *repository.Queries, userID string, text string, q ListingQuery) (ListingPage, error) {
+	if q.Category != "" {
+		category, err := resolveCategory(ctx, repo, q.Category)
+		if err != nil {
+			return ListingPage{}, err
+		}
+		q.Category = category
+	}
+	if err := resolveNearMe(ctx, repo, userID, &q); err != nil {
+		return ListingPage{}, err
+	}
+	if q.Sort == "" {
+		q.Sort = SORT_RELEVANCE
+	}
+	if q.Limit <= 0 || q.Limit > MAX_PAGE_SIZE {
+		q.Limit = DEFAULT_PAGE_SIZE
+	}
+	params := repository.SearchListingsParams{
+		Sort:              q.Sort,
+		Query:             prefixQuery(text),
+		PostedBy:          userID,
+		Category:          pgtype.Text{String: q.Category, Valid: q.Category != ""},
+		MinReward:         pgtype.Int4{Int32: q.MinReward, Valid: q.MinReward > 0},
+		MaxReward:         pgtype.Int4{Int32: q.MaxReward, Valid: q.MaxReward > 0},
+		MinProviderRating: pgtype.Float8{Float64: q.MinRating, Valid: q.MinRating > 0},
+		PostedAfter:       pgtype.Timestamptz{Time: q.PostedAfter, Valid: !q.PostedAfter.IsZero()},
+		PostedBefore:      pgtype.Timestamptz{Time: q.PostedBefore, Valid: !q.PostedBefore.IsZero()},
+		MaxDistanceKm:     pgtype.Float8{Float64: q.MaxDistanceKm, Valid: q.MaxDistanceKm > 0},
+		PageSize:          q.Limit + 1,
+	}
+	if params.Query == "" {
+		return ListingPage{Listings: []Listing{}}, nil
+	}
+	if q.Near != nil {
+		params.NearLat = pgtype.Float8{Float64: q.Near.Latitude, Valid: true}
+		params.NearLng = pgtype.Float8{Float64: q.Near.Longitude, Valid: true}
+	}
+	if q.Cursor != "" {
+		cursor, err := decodeListingCursor(q.Cursor, q.Sort, q.Near)
+		if err != nil {
+			return ListingPage{}, err
+		}
+		params.CursorValue = pgtype.Float8{Float64: cursor.SortValue, Valid: true}
+		params.CursorID = pgtype.Int4{Int32: cursor.ID, Valid: true}
+	}
+
+	dbListings, err := repo.SearchListings(ctx, params)
+	if err != nil {
+		log.Printf("psql_listing_service -> SearchListings: failed to search listings: %s\n", err)
+		return ListingPage{}, internal.ErrInternalServerError
+	}
+	page := ListingPage{}
+	if len(dbListings) > int(q.Limit) {
+		dbListings = dbListings[:q.Limit]
+		last := dbListings[len(dbListings)-1]
+		page.NextCursor = listingCursor{Sort: q.Sort, SortValue: last.SortValue, ID: last.ID, Near: q.Near}.encode()
+	}
+	page.Listings = make([]Listing, len(dbListings))
+	for i, dbListing := range dbListings {
+		avgRating := float32(0)
+		if dbListing.TotalRatingCount != 0 {
+			avgRating = float32(dbListing.TotalRatings) / float32(dbListing.TotalRatingCount)
+		}
+		page.Listings[i] = Listing{
+			ID:          dbListing.ID,
+			Title:       dbListing.Title,
+			Description: dbListing.Description,
+			TokenReward: dbListing.TokenReward,
+			PostedAt:    dbListing.PostedAt,
+			Category:    dbListing.Category,
+			Provider: user.User{
+				ID:       dbListing.Uid,
+				FullName: dbListing.FullName,
+			},
+			ImageURL:        dbListing.ImageUrl.String,
+			Status:          dbListing.Status,
+			SessionDuration: time.Duration(dbListing.SessionDuration.Microseconds) * time.Microsecond,
+			ContactMethod:   dbListing.ContactMethod.String,
+			AvgRating:       avgRating,
+			Snippet:         renderSnippet(dbListing.Snippet),
+		}
+		if dbListing.Latitude.Valid && dbListing.Longitude.Valid {
+			loc := &Location{
+				Point:           geo.Point{Latitude: dbListing.Latitude.Float64, Longitude: dbListing.Longitude.Float64},
+				ServiceRadiusKm: dbListing.ServiceRadiusKm.Float64,
+			}
+			page.Listings[i].Location = loc
+			if q.Near != nil {
+				page.Listings[i].DistanceKm = math.Round(geo.DistanceKm(*q.Near, loc.Point)*10) / 10
+			}
+		}
+	}
+	return page, nil
+}
+
+// snippetStart and snippetStop are what SearchListings marks matches with in
+// place of <mark> tags, so the description can be escaped on its own.
+const (
+	snippetStart = "\uE000"
+	snippetStop  = "\uE001"
+)
+
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+// renderSnippet HTML-escapes a search snippet and wraps its matches in <mark>
+// tags. Listing authors write descriptions, so nothing in them may reach
+// clients as markup.
+func renderSnippet(snippet string) string {
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
+
+// prefixQuery turns free text into a tsquery matching every word as a prefix,
+// so "guitar less" becomes "guitar:* & less:*". Anything other than letters
+// and digits is dropped so user input cannot break the tsquery syntax.
+func prefixQuery(text string) string {
+	terms := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
 }
 
 func (pls *PostgresListingService) CreateListing(ctx context.Context, listing Listing) (int32, error) {
@@ -72,10 +304,14 @@ func (pls *PostgresListingService) CreateListing(ctx context.Context, listing Li
 	}
 	defer tx.Rollback(ctx)
 	repo := repository.New(pls.DB).WithTx(tx)
+	category, err := resolveCategory(ctx, repo, listing.Category)
+	if err != nil {
+		return -1, err
+	}
 	createListingParams := repository.InsertListingParams{}
 	createListingParams.Title = listing.Title
 	createListingParams.Description = listing.Description
-	createListingParams.Category = listing.Category
+	createListingParams.Category = category
 	createListingParams.TokenReward = listing.TokenReward
 	createListingParams.PostedBy = listing.Provider.ID
 	createListingParams.ImageUrl = pgtype.Text{String: listing.ImageURL, Valid: listing.ImageURL != ""}
@@ -86,6 +322,18 @@ func (pls *PostgresListingService) CreateListing(ctx context.Context, listing Li
 		log.Printf("ListingService -> CreateListing : error creating listing: %s\n", err)
 		return -1, internal.ErrInternalServerError
 	}
+	if listing.Location != nil {
+		err = upsertListingLocation(ctx, repo, id, *listing.Location)
+	} else {
+		err = repo.CopyUserLocationToListing(ctx, repository.CopyUserLocationToListingParams{
+			ListingID: id,
+			UserID:    listing.Provider.ID,
+		})
+	}
+	if err != nil {
+		log.Printf("ListingService -> CreateListing : error saving listing location: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
 	err = tx.Commit(ctx)
 	if err != nil {
 		log.Printf("ListingService -> CreateListing : error commiting: %s\n", err)
@@ -94,6 +342,30 @@ func (pls *PostgresListingService) CreateListing(ctx context.Context, listing Li
 	return id, nil
 }
 
+func upsertListingLocation(ctx context.Context, repo *repository.Queries, listingID int32, loc Location) error {
+	return repo.UpsertListingLocation(ctx, repository.UpsertListingLocationParams{
+		ListingID:       listingID,
+		Latitude:        loc.Latitude,
+		Longitude:       loc.Longitude,
+		ServiceRadiusKm: pgtype.Float8{Float64: loc.ServiceRadiusKm, Valid: loc.ServiceRadiusKm > 0},
+	})
+}
+
+// resolveCategory turns the category a client sent, either a slug or a
+// category name in any case, into the slug stored on the listing.
+// returns ErrInvalidCategory if it is not part of the taxonomy.
+func resolveCategory(ctx context.Context, repo *repository.Queries, category string) (string, error) {
+	slug, err := repo.ResolveCategorySlug(ctx, strings.ToLower(strings.TrimSpace(category)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", internal.ErrInvalidCategory
+		}
+		log.Printf("listing_service -> resolveCategory: failed to resolve category: %s\n", err)
+		return "", internal.ErrInternalServerError
+	}
+	return slug, nil
+}
+
 func (pls *PostgresListingService) GetListingsByUserID(ctx context.Context, postedBy string) ([]Listing, error) {
 	repo := repository.New(pls.DB)
 	dbListings, err := repo.GetUserListings(ctx, postedBy)
@@ -212,12 +484,16 @@ func (pls *PostgresListingService) UpdateListing(ctx context.Context, listing Li
 	}
 	defer tx.Rollback(ctx)
 	repo := repository.New(pls.DB).WithTx(tx)
+	category, err := resolveCategory(ctx, repo, listing.Category)
+	if err != nil {
+		return -1, err
+	}
 	rowsAffected, err := repo.UpdateListing(ctx, repository.UpdateListingParams{
 		ID:              listing.ID,
 		PostedBy:        listing.Provider.ID,
 		Title:           listing.Title,
 		Description:     listing.Description,
-		Category:        listing.Category,
+		Category:        category,
 		TokenReward:     listing.TokenReward,
 		ImageUrl:        pgtype.Text{String: listing.ImageURL, Valid: listing.ImageURL != ""},
 		SessionDuration: pgtype.Interval{Microseconds: listing.SessionDuration.Microseconds(), Valid: true},
@@ -230,6 +506,12 @@ func (pls *PostgresListingService) UpdateListing(ctx context.Context, listing Li
 	if rowsAffected == 0 {
 		return -1, internal.ErrUnauthorized
 	}
+	if listing.Location != nil {
+		if err = upsertListingLocation(ctx, repo, listing.ID, *listing.Location); err != nil {
+			log.Printf("listing_service -> UpdateListing: failed to save listing location: %v\n", err)
+			return -1, internal.ErrInternalServerError
+		}
+	}
 	if err = tx.Commit(ctx); err != nil {
 		log.Printf("listing_service -> UpdateListing: failed to commit: %v\n", err)
 		return -1, internal.ErrInternalServerError
@@ -264,6 +546,42 @@ func (pls *PostgresListingService) ReportListing(ctx context.Context, lr Listing
 		log.Printf("listing_service -> ReportListing: failed to insert report: %s\n", err)
 		return internal.ErrInternalServerError
 	}
+
+	if pls.ReportThreshold > 0 {
+		hiddenListing, err := repo.HideReportedListing(ctx, repository.HideReportedListingParams{
+			ID:        lr.ListingID,
+			Threshold: pls.ReportThreshold,
+		})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("listing_service -> ReportListing: failed to hide listing: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+		if err == nil {
+			provider := hiddenListing.PostedBy
+			eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+				TargetID:    lr.ListingID,
+				Type:        domain.LISTING_EVENT,
+				Description: domain.LISTING_HIDDEN,
+			})
+			if err != nil {
+				log.Printf("listing_service -> ReportListing: failed to insert event: %s\n", err)
+				return internal.ErrInternalServerError
+			}
+			err = notification.Send(ctx, repo, notification.Notification{
+				RecipientID:  provider,
+				ActionUserID: pgtype.Text{Valid: false},
+				EventID:      eventID,
+				EventType:    domain.LISTING_EVENT,
+				Description:  domain.LISTING_HIDDEN,
+				Message:      fmt.Sprintf("Your listing %s has been hidden pending review after several reports.", hiddenListing.Title),
+			})
+			if err != nil {
+				log.Printf("listing_service -> ReportListing: failed to insert notification: %s\n", err)
+				return internal.ErrInternalServerError
+			}
+		}
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		log.Printf("ReportListing: failed to commit: %v\n", err)
 		return internal.ErrInternalServerError
@@ -271,6 +589,53 @@ func (pls *PostgresListingService) ReportListing(ctx context.Context, lr Listing
 	return nil
 }
 
+// AppealListing lets a provider contest a warning or takedown on their listing.
+// returns ErrUnauthorized if the listing belongs to someone else,
+// ErrNothingToAppeal if it carries no warning and is not hidden, and
+// ErrDuplicateID if an appeal is already pending.
+func (pls *PostgresListingService) AppealListing(ctx context.Context, listingID int32, appellantID string, reason string) (int32, error) {
+	tx, err := pls.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Printf("listing_service -> AppealListing: failed to start transaction: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pls.DB).WithTx(tx)
+
+	moderation, err := repo.GetListingModeration(ctx, listingID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return -1, internal.ErrNoRecord
+		}
+		log.Printf("listing_service -> AppealListing: failed to get listing: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	if moderation.PostedBy != appellantID {
+		return -1, internal.ErrUnauthorized
+	}
+	if !moderation.WarningID.Valid && moderation.Status != LISTING_UNDER_REVIEW && moderation.Status != LISTING_REMOVED {
+		return -1, internal.ErrNothingToAppeal
+	}
+
+	appealID, err := repo.InsertListingAppeal(ctx, repository.InsertListingAppealParams{
+		ListingID:   listingID,
+		AppellantID: appellantID,
+		Reason:      reason,
+	})
+	if err != nil {
+		if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "23505" {
+			return -1, internal.ErrDuplicateID
+		}
+		log.Printf("listing_service -> AppealListing: failed to insert appeal: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("AppealListing: failed to commit: %v\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	return appealID, nil
+}
+
 func (p *PostgresListingService) GetListingReviews(ctx context.Context, listingID int32) ([]review.Review, error) {
 	repo := repository.New(p.DB)
 	dbReviews, err := repo.GetListingReviews(ctx, listingID)
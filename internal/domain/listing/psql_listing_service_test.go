@@ -0,0 +1,227 @@
+package listing
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/geo"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+	"github.com/set-kaung/senior_project_1/internal/repository/repotest"
+)
+
+func TestRenderSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		want    string
+	}{
+		{"plain", "guitar " + snippetStart + "lessons" + snippetStop + " for adults", "guitar <mark>lessons</mark> for adults"},
+		{"script", "<script>alert(1)</script> " + snippetStart + "piano" + snippetStop, "&lt;script&gt;alert(1)&lt;/script&gt; <mark>piano</mark>"},
+		{"injected mark", "<mark onmouseover=x>" + snippetStart + "math" + snippetStop + "</mark>", "&lt;mark onmouseover=x&gt;<mark>math</mark>&lt;/mark&gt;"},
+		{"entities", `Tom & Jerry's "art"`, "Tom &amp; Jerry&#39;s &#34;art&#34;"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderSnippet(tt.snippet); got != tt.want {
+				t.Errorf("renderSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"guitar less", "guitar:* & less:*"},
+		{"  c++ & 'drop' | !x  ", "c:* & drop:* & x:*"},
+		{"café 3d", "café:* & 3d:*"},
+		{"&|!():*", ""},
+	}
+	for _, tt := range tests {
+		if got := prefixQuery(tt.text); got != tt.want {
+			t.Errorf("prefixQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// listingRow is a GetAllListings row for a listing at lat, lng.
+func listingRow(id int32, lat, lng, sortValue float64) []any {
+	return []any{
+		id, "Guitar lessons", "", int32(5), "provider", time.Time{}, "music", nil, "active", nil, nil,
+		"provider", "Pat Provider", int64(0), int64(0),
+		pgtype.Float8{Float64: lat, Valid: true}, pgtype.Float8{Float64: lng, Valid: true}, pgtype.Float8{Float64: 10, Valid: true},
+		sortValue,
+	}
+}
+
+func TestGetAllListingsNearMe(t *testing.T) {
+	ctx := context.Background()
+	home := geo.Point{Latitude: 13.7367, Longitude: 100.5232}
+	userLocation := func(db *repotest.DB, p geo.Point) {
+		db.Returns("GetUserLocation", []any{"u1", p.Latitude, p.Longitude, nil, "geocoded", nil})
+	}
+
+	t.Run("no location", func(t *testing.T) {
+		db := repotest.New()
+		_, err := getAllListings(ctx, repository.New(db), "u1", ListingQuery{NearMe: true})
+		if !errors.Is(err, internal.ErrNoLocation) {
+			t.Fatalf("getAllListings() error = %v, want ErrNoLocation", err)
+		}
+	})
+
+	db := repotest.New()
+	userLocation(db, home)
+	// one degree of latitude north, then two
+	db.Returns("GetAllListings", listingRow(7, 14.7367, 100.5232, 111.19), listingRow(3, 15.7367, 100.5232, 222.39))
+	page, err := getAllListings(ctx, repository.New(db), "u1", ListingQuery{NearMe: true, MaxDistanceKm: 250, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := db.Calls("GetAllListings")
+	if len(calls) != 1 {
+		t.Fatalf("GetAllListings called %d times, want 1", len(calls))
+	}
+	args := calls[0].Args
+	if args[0] != SORT_DISTANCE {
+		t.Errorf("sort = %v, want %q by default near the user", args[0], SORT_DISTANCE)
+	}
+	if want := (pgtype.Float8{Float64: home.Latitude, Valid: true}); args[1] != want {
+		t.Errorf("near_lat = %v, want %v", args[1], want)
+	}
+	if want := (pgtype.Float8{Float64: home.Longitude, Valid: true}); args[2] != want {
+		t.Errorf("near_lng = %v, want %v", args[2], want)
+	}
+	if want := (pgtype.Float8{Float64: 250, Valid: true}); args[10] != want {
+		t.Errorf("max_distance_km = %v, want %v", args[10], want)
+	}
+	if args[13] != int32(2) {
+		t.Errorf("page_size = %v, want one more than the limit", args[13])
+	}
+
+	if len(page.Listings) != 1 || page.Listings[0].ID != 7 {
+		t.Fatalf("listings = %+v, want only listing 7", page.Listings)
+	}
+	if got := page.Listings[0].DistanceKm; got != 111.2 {
+		t.Errorf("DistanceKm = %v, want 111.2", got)
+	}
+	cursor, err := decodeListingCursor(page.NextCursor, SORT_DISTANCE, &home)
+	if err != nil {
+		t.Fatalf("next cursor %q does not decode around the user: %v", page.NextCursor, err)
+	}
+	if cursor.ID != 7 || cursor.SortValue != 111.19 {
+		t.Errorf("next cursor = %+v, want listing 7 at 111.19", cursor)
+	}
+
+	t.Run("next page", func(t *testing.T) {
+		db := repotest.New()
+		userLocation(db, home)
+		_, err := getAllListings(ctx, repository.New(db), "u1", ListingQuery{NearMe: true, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := db.Calls("GetAllListings")[0].Args
+		if args[11] != (pgtype.Float8{Float64: 111.19, Valid: true}) || args[12] != (pgtype.Int4{Int32: 7, Valid: true}) {
+			t.Errorf("cursor args = %v, %v, want 111.19, 7", args[11], args[12])
+		}
+	})
+
+	t.Run("user moved", func(t *testing.T) {
+		db := repotest.New()
+		userLocation(db, geo.Point{Latitude: 18.7883, Longitude: 98.9853})
+		_, err := getAllListings(ctx, repository.New(db), "u1", ListingQuery{NearMe: true, Cursor: page.NextCursor})
+		if !errors.Is(err, internal.ErrInvalidCursor) {
+			t.Fatalf("getAllListings() error = %v, want ErrInvalidCursor", err)
+		}
+		if calls := db.Calls("GetAllListings"); len(calls) != 0 {
+			t.Errorf("listings were queried with a cursor from another point")
+		}
+	})
+}
+
+func TestSearchListingsNearMe(t *testing.T) {
+	ctx := context.Background()
+	home := geo.Point{Latitude: 13.7367, Longitude: 100.5232}
+
+	t.Run("no location", func(t *testing.T) {
+		db := repotest.New()
+		_, err := searchListings(ctx, repository.New(db), "u1", "guitar", ListingQuery{NearMe: true})
+		if !errors.Is(err, internal.ErrNoLocation) {
+			t.Fatalf("searchListings() error = %v, want ErrNoLocation", err)
+		}
+	})
+
+	db := repotest.New()
+	db.Returns("GetUserLocation", []any{"u1", home.Latitude, home.Longitude, nil, "geocoded", nil})
+	db.Returns("SearchListings", append(listingRow(7, 14.7367, 100.5232, 0.5), "guitar"), append(listingRow(3, 15.7367, 100.5232, 0.4), "guitar"))
+	page, err := searchListings(ctx, repository.New(db), "u1", "guitar", ListingQuery{NearMe: true, MaxDistanceKm: 250, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := db.Calls("SearchListings")[0].Args
+	if args[0] != SORT_RELEVANCE {
+		t.Errorf("sort = %v, want %q near the user too", args[0], SORT_RELEVANCE)
+	}
+	if want := (pgtype.Float8{Float64: home.Latitude, Valid: true}); args[9] != want {
+		t.Errorf("near_lat = %v, want %v", args[9], want)
+	}
+	if want := (pgtype.Float8{Float64: home.Longitude, Valid: true}); args[10] != want {
+		t.Errorf("near_lng = %v, want %v", args[10], want)
+	}
+	if want := (pgtype.Float8{Float64: 250, Valid: true}); args[11] != want {
+		t.Errorf("max_distance_km = %v, want %v", args[11], want)
+	}
+
+	if len(page.Listings) != 1 || page.Listings[0].DistanceKm != 111.2 {
+		t.Fatalf("listings = %+v, want listing 7 at 111.2 km", page.Listings)
+	}
+	if _, err := decodeListingCursor(page.NextCursor, SORT_RELEVANCE, &home); err != nil {
+		t.Errorf("next cursor %q does not decode around the user: %v", page.NextCursor, err)
+	}
+	if _, err := decodeListingCursor(page.NextCursor, SORT_RELEVANCE, nil); !errors.Is(err, internal.ErrInvalidCursor) {
+		t.Errorf("next cursor decodes without a point, error = %v", err)
+	}
+}
+
+func TestDecodeListingCursor(t *testing.T) {
+	home := &geo.Point{Latitude: 13.7367, Longitude: 100.5232}
+	away := &geo.Point{Latitude: 13.7367, Longitude: 100.5233}
+	near := listingCursor{Sort: SORT_DISTANCE, SortValue: 1.5, ID: 9, Near: home}.encode()
+	newest := listingCursor{Sort: SORT_NEWEST, SortValue: 1700000000, ID: 4}.encode()
+	tests := []struct {
+		name    string
+		cursor  string
+		sort    string
+		near    *geo.Point
+		wantErr bool
+	}{
+		{"near", near, SORT_DISTANCE, home, false},
+		{"other point", near, SORT_DISTANCE, away, true},
+		{"point dropped", near, SORT_DISTANCE, nil, true},
+		{"point added", newest, SORT_NEWEST, home, true},
+		{"plain", newest, SORT_NEWEST, nil, false},
+		{"other sort", newest, SORT_PRICE, nil, true},
+		{"not base64", "%%%", SORT_NEWEST, nil, true},
+		{"bad id", base64.RawURLEncoding.EncodeToString([]byte("newest:1:x")), SORT_NEWEST, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeListingCursor(tt.cursor, tt.sort, tt.near)
+			if tt.wantErr && !errors.Is(err, internal.ErrInvalidCursor) {
+				t.Errorf("decodeListingCursor() error = %v, want ErrInvalidCursor", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("decodeListingCursor() error = %v", err)
+			}
+		})
+	}
+}
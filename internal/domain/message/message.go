@@ -0,0 +1,36 @@
+package message
+
+import "time"
+
+const (
+	DEFAULT_PAGE_SIZE = 30
+	MAX_PAGE_SIZE     = 100
+	// MAX_BODY_LENGTH is the longest message in characters.
+	MAX_BODY_LENGTH = 2000
+)
+
+// Events pushed to the other party of a thread.
+const (
+	EVENT_NEW_MESSAGE   = "new-message"
+	EVENT_MESSAGES_READ = "messages-read"
+)
+
+// Message is a note between the requester and provider of a request.
+// ReadAt is set once the other party has opened the thread after it was sent.
+type Message struct {
+	ID             int32      `json:"id"`
+	RequestID      int32      `json:"request_id"`
+	SenderID       string     `json:"sender_id"`
+	SenderFullName string     `json:"sender_full_name"`
+	Body           string     `json:"body"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at"`
+	IsMine         bool       `json:"is_mine"`
+}
+
+// MessagePage holds messages newest first. NextCursor fetches the older
+// messages and is empty on the oldest page.
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
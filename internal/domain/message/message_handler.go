@@ -0,0 +1,106 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+)
+
+type MessageHandler struct {
+	MessageService MessageService
+}
+
+func (mh *MessageHandler) HandleSendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	requestID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid id", nil)
+		return
+	}
+	body := struct {
+		Body string `json:"body"`
+	}{}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("HandleSendMessage: %s\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	body.Body = strings.TrimSpace(body.Body)
+	if body.Body == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "body is required", nil)
+		return
+	}
+	if utf8.RuneCountInString(body.Body) > MAX_BODY_LENGTH {
+		helpers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("body must be at most %d characters", MAX_BODY_LENGTH), nil)
+		return
+	}
+	m, err := mh.MessageService.SendMessage(r.Context(), int32(requestID), userID, body.Body)
+	if err != nil {
+		writeMessageError(w, err)
+		return
+	}
+	helpers.WriteData(w, http.StatusCreated, m, nil)
+}
+
+// HandleGetMessages lists a request's thread newest first. limit defaults to
+// DEFAULT_PAGE_SIZE and cursor is the next_cursor of the previous page.
+func (mh *MessageHandler) HandleGetMessages(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	requestID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid id", nil)
+		return
+	}
+	var limit int64
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.ParseInt(v, 10, 32)
+		if err != nil || limit < 0 {
+			helpers.WriteError(w, http.StatusBadRequest, "limit must be a non-negative integer", nil)
+			return
+		}
+	}
+	page, err := mh.MessageService.GetMessages(r.Context(), int32(requestID), userID, r.URL.Query().Get("cursor"), int32(limit))
+	if err != nil {
+		writeMessageError(w, err)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, page, nil)
+}
+
+func (mh *MessageHandler) HandleMarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	requestID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid id", nil)
+		return
+	}
+	n, err := mh.MessageService.MarkRead(r.Context(), int32(requestID), userID)
+	if err != nil {
+		writeMessageError(w, err)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, map[string]int64{"marked": n}, nil)
+}
+
+func writeMessageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrNoRecord):
+		helpers.WriteError(w, http.StatusNotFound, "no such request", nil)
+	case errors.Is(err, internal.ErrUnauthorized):
+		helpers.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+	case errors.Is(err, internal.ErrThreadClosed):
+		helpers.WriteError(w, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, internal.ErrInvalidCursor):
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+	default:
+		helpers.WriteServerError(w, nil)
+	}
+}
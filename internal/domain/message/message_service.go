@@ -0,0 +1,9 @@
+package message
+
+import "context"
+
+type MessageService interface {
+	SendMessage(ctx context.Context, requestID int32, senderID string, body string) (Message, error)
+	GetMessages(ctx context.Context, requestID int32, userID string, cursor string, limit int32) (MessagePage, error)
+	MarkRead(ctx context.Context, requestID int32, userID string) (int64, error)
+}
@@ -0,0 +1,224 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/outbox"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type PostgresMessageService struct {
+	DB *pgxpool.Pool
+}
+
+// thread is what userID may do with the messages of a request.
+type thread struct {
+	// other is the party to notify about userID's actions. It is empty for
+	// admins, who only read.
+	other    string
+	canWrite bool
+}
+
+// access decides whether userID may see the thread of requestID. The two
+// parties always can, and may write while the request is active or a dispute
+// on it is open. Admins can read, but not write, while a dispute is open.
+// returns ErrNoRecord if the request does not exist and ErrUnauthorized if
+// userID may not see it.
+func access(ctx context.Context, repo *repository.Queries, requestID int32, userID string) (thread, error) {
+	a, err := repo.GetMessageThreadAccess(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return thread{}, internal.ErrNoRecord
+		}
+		return thread{}, fmt.Errorf("get thread access: %w", err)
+	}
+	open := a.Activity == repository.ServiceActivityActive || a.DisputeOpen
+	switch userID {
+	case a.RequesterID:
+		return thread{other: a.ProviderID, canWrite: open}, nil
+	case a.ProviderID:
+		return thread{other: a.RequesterID, canWrite: open}, nil
+	}
+	if !a.DisputeOpen {
+		return thread{}, internal.ErrUnauthorized
+	}
+	roles, err := repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return thread{}, fmt.Errorf("get user roles: %w", err)
+	}
+	if !slices.Contains(roles, repository.RoleAdmin) {
+		return thread{}, internal.ErrUnauthorized
+	}
+	return thread{}, nil
+}
+
+// SendMessage posts body to the thread of requestID and notifies the other
+// party on their Pusher channel.
+// returns ErrNoRecord, ErrUnauthorized or ErrThreadClosed.
+func (pms *PostgresMessageService) SendMessage(ctx context.Context, requestID int32, senderID string, body string) (Message, error) {
+	tx, err := pms.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("SendMessage: failed to begin transaction: %s\n", err)
+		return Message{}, internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pms.DB).WithTx(tx)
+
+	t, err := access(ctx, repo, requestID, senderID)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) || errors.Is(err, internal.ErrUnauthorized) {
+			return Message{}, err
+		}
+		log.Printf("SendMessage: %s\n", err)
+		return Message{}, internal.ErrInternalServerError
+	}
+	if t.other == "" {
+		return Message{}, internal.ErrUnauthorized
+	}
+	if !t.canWrite {
+		return Message{}, internal.ErrThreadClosed
+	}
+	row, err := repo.InsertRequestMessage(ctx, repository.InsertRequestMessageParams{
+		RequestID: requestID,
+		SenderID:  senderID,
+		Body:      body,
+	})
+	if err != nil {
+		log.Printf("SendMessage: failed to insert message: %s\n", err)
+		return Message{}, internal.ErrInternalServerError
+	}
+	err = outbox.Enqueue(ctx, repo, t.other, EVENT_NEW_MESSAGE, map[string]int32{
+		"request_id": requestID,
+		"message_id": row.ID,
+	})
+	if err != nil {
+		log.Printf("SendMessage: %s\n", err)
+		return Message{}, internal.ErrInternalServerError
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("SendMessage: failed to commit: %s\n", err)
+		return Message{}, internal.ErrInternalServerError
+	}
+	return Message{
+		ID:        row.ID,
+		RequestID: requestID,
+		SenderID:  senderID,
+		Body:      body,
+		CreatedAt: row.CreatedAt,
+		IsMine:    true,
+	}, nil
+}
+
+// GetMessages returns a page of the thread of requestID, newest first.
+// cursor is the next_cursor of the previous page, or empty for the newest.
+// returns ErrNoRecord, ErrUnauthorized or ErrInvalidCursor.
+func (pms *PostgresMessageService) GetMessages(ctx context.Context, requestID int32, userID string, cursor string, limit int32) (MessagePage, error) {
+	if limit <= 0 || limit > MAX_PAGE_SIZE {
+		limit = DEFAULT_PAGE_SIZE
+	}
+	params := repository.GetRequestMessagesParams{
+		RequestID: requestID,
+		// one extra row tells us whether there is a next page
+		PageSize: limit + 1,
+	}
+	if cursor != "" {
+		before, err := strconv.ParseInt(cursor, 10, 32)
+		if err != nil || before <= 0 {
+			return MessagePage{}, internal.ErrInvalidCursor
+		}
+		params.Before = pgtype.Int4{Int32: int32(before), Valid: true}
+	}
+	repo := repository.New(pms.DB)
+	if _, err := access(ctx, repo, requestID, userID); err != nil {
+		if errors.Is(err, internal.ErrNoRecord) || errors.Is(err, internal.ErrUnauthorized) {
+			return MessagePage{}, err
+		}
+		log.Printf("GetMessages: %s\n", err)
+		return MessagePage{}, internal.ErrInternalServerError
+	}
+	rows, err := repo.GetRequestMessages(ctx, params)
+	if err != nil {
+		log.Printf("GetMessages: failed to get messages: %s\n", err)
+		return MessagePage{}, internal.ErrInternalServerError
+	}
+	page := MessagePage{}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		page.NextCursor = strconv.FormatInt(int64(rows[len(rows)-1].ID), 10)
+	}
+	page.Messages = make([]Message, len(rows))
+	for i, row := range rows {
+		m := Message{
+			ID:             row.ID,
+			RequestID:      row.RequestID,
+			SenderID:       row.SenderID,
+			SenderFullName: row.SenderFullName,
+			Body:           row.Body,
+			CreatedAt:      row.CreatedAt,
+			IsMine:         row.SenderID == userID,
+		}
+		if row.ReadAt.Valid {
+			m.ReadAt = &row.ReadAt.Time
+		}
+		page.Messages[i] = m
+	}
+	return page, nil
+}
+
+// MarkRead marks the messages userID received on requestID as read and
+// tells the sender, so their client can show the receipt. Admins reading a
+// disputed thread leave it unread.
+// returns the number of messages marked, ErrNoRecord or ErrUnauthorized.
+func (pms *PostgresMessageService) MarkRead(ctx context.Context, requestID int32, userID string) (int64, error) {
+	tx, err := pms.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("MarkRead: failed to begin transaction: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pms.DB).WithTx(tx)
+
+	t, err := access(ctx, repo, requestID, userID)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) || errors.Is(err, internal.ErrUnauthorized) {
+			return 0, err
+		}
+		log.Printf("MarkRead: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	if t.other == "" {
+		return 0, nil
+	}
+	n, err := repo.MarkRequestMessagesRead(ctx, repository.MarkRequestMessagesReadParams{
+		RequestID: requestID,
+		SenderID:  userID,
+	})
+	if err != nil {
+		log.Printf("MarkRead: failed to mark messages: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	err = outbox.Enqueue(ctx, repo, t.other, EVENT_MESSAGES_READ, map[string]int32{
+		"request_id": requestID,
+	})
+	if err != nil {
+		log.Printf("MarkRead: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("MarkRead: failed to commit: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	return n, nil
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/set-kaung/senior_project_1/internal/domain"
+	"github.com/set-kaung/senior_project_1/internal/mail"
+	"github.com/set-kaung/senior_project_1/internal/outbox"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+// TOPICS lists the event descriptions users can set preferences for, by
+// event type.
+var TOPICS = map[string][]string{
+	domain.REQUEST_EVENT: {
+		domain.INITIATE_REQUEST,
+		domain.ACCEPT_REQUEST,
+		domain.DECLINE_REQUEST,
+		domain.CONFIRM_COMPLETION,
+		domain.REQUEST_EXPIRED,
+		domain.CANCELLED_REQUEST,
+		domain.DISPUTE_OPENED,
+		domain.DISPUTE_RESPONDED,
+		domain.DISPUTE_RESOLVED,
+		domain.SESSION_REMINDER,
+		domain.PENDING_REMINDER,
+		domain.AUTO_COMPLETED,
+		domain.USER_DO_NOT_EXIST,
+	},
+	domain.REVIEW_EVENT: {
+		domain.REVIEWED_REQUEST,
+	},
+	domain.LISTING_EVENT: {
+		domain.WARNING_ISSUED,
+		domain.LISTING_HIDDEN,
+		domain.LISTING_REMOVED,
+		domain.LISTING_RESTORED,
+		domain.APPEAL_DECIDED,
+	},
+}
+
+// MANDATORY are the descriptions about moderation and money that always
+// reach the in-app list, whatever the preferences say. Their push and
+// email channels can still be turned off.
+var MANDATORY = []string{
+	domain.WARNING_ISSUED,
+	domain.LISTING_REMOVED,
+	domain.DISPUTE_RESOLVED,
+	domain.USER_DO_NOT_EXIST,
+}
+
+// Channels says how a notification reaches its recipient. Push and Email
+// deliver the in-app notification, so they need InApp; a preference with
+// every channel off mutes the topic. Email sends urgent notifications right
+// away and puts the rest in the daily digest.
+type Channels struct {
+	InApp bool `json:"in_app"`
+	Push  bool `json:"push"`
+	Email bool `json:"email"`
+}
+
+// DEFAULT_CHANNELS applies to topics the user has no preference for.
+var DEFAULT_CHANNELS = Channels{InApp: true, Push: true, Email: true}
+
+// Preference sets the channels for an event type, or for a single
+// description of it when Description is set.
+type Preference struct {
+	EventType   string `json:"event_type"`
+	Description string `json:"description,omitempty"`
+	Channels
+}
+
+// Validate checks that p names a known topic and a consistent set of
+// channels.
+func (p Preference) Validate() error {
+	descriptions, ok := TOPICS[p.EventType]
+	if !ok {
+		return fmt.Errorf("unknown event_type %q", p.EventType)
+	}
+	if p.Description != "" && !slices.Contains(descriptions, p.Description) {
+		return fmt.Errorf("unknown description %q for event_type %q", p.Description, p.EventType)
+	}
+	if !p.InApp && (p.Push || p.Email) {
+		return errors.New("push and email need in_app")
+	}
+	return nil
+}
+
+// Notification is a message to one user about an event. An Urgent one is
+// emailed straight away instead of waiting for the daily digest.
+type Notification struct {
+	RecipientID  string
+	ActionUserID pgtype.Text
+	EventID      int64
+	EventType    string
+	Description  string
+	Message      string
+	Urgent       bool
+}
+
+// Send routes n through the channels its recipient chose for its topic,
+// inside the caller's transaction: the in-app row is written, and the
+// realtime push and email queued, only if the recipient wants them.
+func Send(ctx context.Context, repo *repository.Queries, n Notification) error {
+	c, err := channelsFor(ctx, repo, n.RecipientID, n.EventType, n.Description)
+	if err != nil {
+		return err
+	}
+	if !c.InApp {
+		return nil
+	}
+	_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
+		Message:         n.Message,
+		RecipientUserID: n.RecipientID,
+		ActionUserID:    n.ActionUserID,
+		EventID:         n.EventID,
+		EmailPending:    c.Email && !n.Urgent,
+	})
+	if err != nil {
+		return fmt.Errorf("insert notification: %w", err)
+	}
+	if c.Email && n.Urgent {
+		err = outbox.Email(ctx, repo, mail.Email{
+			UserID:   n.RecipientID,
+			Template: n.EventType,
+			Notices: []mail.Notice{{
+				EventType:   n.EventType,
+				Description: n.Description,
+				Message:     n.Message,
+				At:          time.Now(),
+			}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if c.Push {
+		return outbox.Notify(ctx, repo, n.RecipientID)
+	}
+	return nil
+}
+
+// channelsFor resolves the channels of a topic for userID: a preference for
+// the description, else one for the event type, else DEFAULT_CHANNELS.
+func channelsFor(ctx context.Context, repo *repository.Queries, userID, eventType, description string) (Channels, error) {
+	row, err := repo.GetNotificationPreference(ctx, repository.GetNotificationPreferenceParams{
+		UserID:      userID,
+		EventType:   eventType,
+		Description: description,
+	})
+	c := Channels{InApp: row.InApp, Push: row.Push, Email: row.Email}
+	if errors.Is(err, pgx.ErrNoRows) {
+		c, err = DEFAULT_CHANNELS, nil
+	}
+	if err != nil {
+		return Channels{}, fmt.Errorf("get notification preference: %w", err)
+	}
+	if slices.Contains(MANDATORY, description) {
+		c.InApp = true
+	}
+	return c, nil
+}
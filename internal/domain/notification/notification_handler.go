@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+)
+
+type NotificationHandler struct {
+	NotificationService NotificationService
+}
+
+// HandleGetPreferences returns the user's preferences along with the topics
+// they can set and the channels used where no preference applies.
+func (nh *NotificationHandler) HandleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	prefs, err := nh.NotificationService.GetPreferences(r.Context(), userID)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, map[string]any{
+		"preferences": prefs,
+		"topics":      TOPICS,
+		"mandatory":   MANDATORY,
+		"default":     DEFAULT_CHANNELS,
+	}, nil)
+}
+
+func (nh *NotificationHandler) HandleSetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	body := struct {
+		Preferences []Preference `json:"preferences"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("HandleSetPreferences: %s\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	seen := map[Preference]bool{}
+	for _, p := range body.Preferences {
+		if err := p.Validate(); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		topic := Preference{EventType: p.EventType, Description: p.Description}
+		if seen[topic] {
+			helpers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("duplicate preference for %s %s", p.EventType, p.Description), nil)
+			return
+		}
+		seen[topic] = true
+	}
+	if err := nh.NotificationService.SetPreferences(r.Context(), userID, body.Preferences); err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "notification preferences updated", nil)
+}
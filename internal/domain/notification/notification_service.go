@@ -0,0 +1,9 @@
+package notification
+
+import "context"
+
+type NotificationService interface {
+	GetPreferences(ctx context.Context, userID string) ([]Preference, error)
+	SetPreferences(ctx context.Context, userID string, prefs []Preference) error
+	SendDigests(ctx context.Context) (int, error)
+}
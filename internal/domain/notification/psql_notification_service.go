@@ -0,0 +1,121 @@
+package notification
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/mail"
+	"github.com/set-kaung/senior_project_1/internal/outbox"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+type PostgresNotificationService struct {
+	DB *pgxpool.Pool
+}
+
+// GetPreferences returns the preferences the user has set. Topics without
+// one use DEFAULT_CHANNELS.
+func (pns *PostgresNotificationService) GetPreferences(ctx context.Context, userID string) ([]Preference, error) {
+	repo := repository.New(pns.DB)
+	rows, err := repo.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		log.Printf("GetPreferences: failed to get preferences: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	prefs := make([]Preference, len(rows))
+	for i, row := range rows {
+		prefs[i] = Preference{
+			EventType:   row.EventType,
+			Description: row.Description,
+			Channels:    Channels{InApp: row.InApp, Push: row.Push, Email: row.Email},
+		}
+	}
+	return prefs, nil
+}
+
+// SetPreferences replaces all of the user's preferences. Every preference
+// must have passed Validate and name a different topic.
+func (pns *PostgresNotificationService) SetPreferences(ctx context.Context, userID string, prefs []Preference) error {
+	tx, err := pns.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("SetPreferences: failed to begin transaction: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pns.DB).WithTx(tx)
+
+	if err = repo.DeleteNotificationPreferences(ctx, userID); err != nil {
+		log.Printf("SetPreferences: failed to delete preferences: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	for _, p := range prefs {
+		err = repo.InsertNotificationPreference(ctx, repository.InsertNotificationPreferenceParams{
+			UserID:      userID,
+			EventType:   p.EventType,
+			Description: p.Description,
+			InApp:       p.InApp,
+			Push:        p.Push,
+			Email:       p.Email,
+		})
+		if err != nil {
+			log.Printf("SetPreferences: failed to insert preference: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("SetPreferences: failed to commit: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
+
+// SendDigests queues one digest email per user listing their unread
+// notifications that are waiting for one, and returns how many it queued.
+// Notifications read since they were sent are dropped from the digest.
+func (pns *PostgresNotificationService) SendDigests(ctx context.Context) (int, error) {
+	tx, err := pns.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("SendDigests: failed to begin transaction: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(pns.DB).WithTx(tx)
+
+	rows, err := repo.GetPendingEmailNotifications(ctx)
+	if err != nil {
+		log.Printf("SendDigests: failed to get pending notifications: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	ids := make([]int32, len(rows))
+	digests := []mail.Email{}
+	for i, row := range rows {
+		ids[i] = row.ID
+		if len(digests) == 0 || digests[len(digests)-1].UserID != row.RecipientUserID {
+			digests = append(digests, mail.Email{UserID: row.RecipientUserID, Template: mail.TEMPLATE_DIGEST})
+		}
+		d := &digests[len(digests)-1]
+		d.Notices = append(d.Notices, mail.Notice{
+			EventType:   row.Type,
+			Description: row.Description,
+			Message:     row.Message,
+			At:          row.CreatedAt,
+		})
+	}
+	for _, d := range digests {
+		if err = outbox.Email(ctx, repo, d); err != nil {
+			log.Printf("SendDigests: failed to queue digest for %s: %s\n", d.UserID, err)
+			return 0, internal.ErrInternalServerError
+		}
+	}
+	if _, err = repo.ClearEmailPending(ctx, ids); err != nil {
+		log.Printf("SendDigests: failed to clear pending notifications: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("SendDigests: failed to commit: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	return len(digests), nil
+}
@@ -0,0 +1,156 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain/availability"
+	"github.com/set-kaung/senior_project_1/internal/domain/listing"
+	"github.com/set-kaung/senior_project_1/internal/domain/user"
+	"github.com/set-kaung/senior_project_1/internal/ical"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+	"github.com/set-kaung/senior_project_1/internal/util"
+)
+
+// FEED_TOKEN_LENGTH is the length of calendar feed tokens. The token is the
+// only credential of the feed, so it is long enough not to be guessed.
+const FEED_TOKEN_LENGTH = 32
+
+// GetCalendarFeedToken returns the user's calendar feed token, creating one
+// on first use.
+func (prs *PostgresRequestService) GetCalendarFeedToken(ctx context.Context, userID string) (string, error) {
+	token, err := util.RandomToken(FEED_TOKEN_LENGTH)
+	if err != nil {
+		log.Printf("GetCalendarFeedToken: failed to generate token: %s\n", err)
+		return "", internal.ErrInternalServerError
+	}
+	repo := repository.New(prs.DB)
+	token, err = repo.EnsureCalendarFeedToken(ctx, repository.EnsureCalendarFeedTokenParams{UserID: userID, Token: token})
+	if err != nil {
+		log.Printf("GetCalendarFeedToken: failed to save token: %s\n", err)
+		return "", internal.ErrInternalServerError
+	}
+	return token, nil
+}
+
+// RotateCalendarFeedToken replaces the user's feed token, cutting off
+// every calendar subscribed with the old one.
+func (prs *PostgresRequestService) RotateCalendarFeedToken(ctx context.Context, userID string) (string, error) {
+	token, err := util.RandomToken(FEED_TOKEN_LENGTH)
+	if err != nil {
+		log.Printf("RotateCalendarFeedToken: failed to generate token: %s\n", err)
+		return "", internal.ErrInternalServerError
+	}
+	repo := repository.New(prs.DB)
+	token, err = repo.RotateCalendarFeedToken(ctx, repository.RotateCalendarFeedTokenParams{UserID: userID, Token: token})
+	if err != nil {
+		log.Printf("RotateCalendarFeedToken: failed to save token: %s\n", err)
+		return "", internal.ErrInternalServerError
+	}
+	return token, nil
+}
+
+// GetCalendarFeed returns the accepted requests of the user owning token.
+// returns ErrNoRecord if no user has the token.
+func (prs *PostgresRequestService) GetCalendarFeed(ctx context.Context, token string) (ical.Calendar, error) {
+	repo := repository.New(prs.DB)
+	userID, err := repo.GetCalendarFeedUser(ctx, token)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ical.Calendar{}, internal.ErrNoRecord
+		}
+		log.Printf("GetCalendarFeed: failed to get feed user: %s\n", err)
+		return ical.Calendar{}, internal.ErrInternalServerError
+	}
+	return prs.calendar(ctx, repo, userID, pgtype.Int4{})
+}
+
+// GetRequestCalendar returns a calendar holding only requestID.
+// returns ErrNoRecord unless userID is a party of the request and it has
+// been accepted.
+func (prs *PostgresRequestService) GetRequestCalendar(ctx context.Context, requestID int32, userID string) (ical.Calendar, error) {
+	c, err := prs.calendar(ctx, repository.New(prs.DB), userID, pgtype.Int4{Int32: requestID, Valid: true})
+	if err != nil {
+		return ical.Calendar{}, err
+	}
+	if len(c.Events) == 0 {
+		return ical.Calendar{}, internal.ErrNoRecord
+	}
+	return c, nil
+}
+
+func (prs *PostgresRequestService) calendar(ctx context.Context, repo *repository.Queries, userID string, requestID pgtype.Int4) (ical.Calendar, error) {
+	rows, err := repo.GetCalendarEntries(ctx, repository.GetCalendarEntriesParams{UserID: userID, RequestID: requestID})
+	if err != nil {
+		log.Printf("calendar: failed to get entries: %s\n", err)
+		return ical.Calendar{}, internal.ErrInternalServerError
+	}
+	c := ical.Calendar{Name: "Ontime sessions", Events: make([]ical.Event, len(rows))}
+	for i, row := range rows {
+		r := Request{
+			ID:           row.ID,
+			StatusDetail: string(row.StatusDetail),
+			UpdatedAt:    row.UpdatedAt,
+			Listing: listing.Listing{
+				Title:           row.Title,
+				Description:     row.Description,
+				SessionDuration: time.Duration(row.SessionDuration.Microseconds) * time.Microsecond,
+				ContactMethod:   row.ContactMethod.String,
+			},
+			Requester: user.User{ID: row.RequesterID, FullName: row.RequesterFullName},
+			Provider:  user.User{ID: row.ProviderID, FullName: row.ProviderFullName},
+		}
+		if row.SlotStartsAt.Valid {
+			r.Slot = &availability.Slot{Start: row.SlotStartsAt.Time, End: row.SlotEndsAt.Time}
+		}
+		c.Events[i] = calendarEvent(r, row.AcceptedAt, userID)
+	}
+	return c, nil
+}
+
+// calendarEvent is the entry of an accepted request in viewerID's calendar.
+// A request without a booked slot has no agreed time, so it is placed at
+// acceptedAt as a tentative event for the parties to move. acceptedAt does
+// not change afterwards, so the event stays put in subscribed calendars.
+func calendarEvent(r Request, acceptedAt time.Time, viewerID string) ical.Event {
+	e := ical.Event{
+		UID:    fmt.Sprintf("request-%d@ontime", r.ID),
+		Status: ical.STATUS_CONFIRMED,
+	}
+	if r.Slot != nil {
+		e.Start, e.End = r.Slot.Start, r.Slot.End
+	} else {
+		d := r.Listing.SessionDuration
+		if d <= 0 {
+			d = availability.DEFAULT_SESSION
+		}
+		e.Start, e.End = acceptedAt, acceptedAt.Add(d)
+		e.Status = ical.STATUS_TENTATIVE
+	}
+
+	other := r.Provider.FullName
+	if viewerID == r.Provider.ID {
+		other = r.Requester.FullName
+	}
+	e.Summary = fmt.Sprintf("%s with %s", r.Listing.Title, other)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Provider: %s\nRequester: %s\n", r.Provider.FullName, r.Requester.FullName)
+	if r.Listing.ContactMethod != "" {
+		fmt.Fprintf(&sb, "Contact: %s\n", r.Listing.ContactMethod)
+	}
+	if r.Slot == nil {
+		fmt.Fprintf(&sb, "No time was booked for this request, agree on one with %s.\n", other)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(r.Listing.Description)
+	e.Description = sb.String()
+	return e
+}
@@ -0,0 +1,147 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+// ExpiryPolicy sets how long a request may sit in each stage before the
+// system moves it on. A zero duration turns that step off.
+type ExpiryPolicy struct {
+	// PendingTimeout is how long a request waits for the provider to accept
+	// or decline it before it expires and the requester is refunded.
+	PendingTimeout time.Duration `json:"pending_timeout"`
+	// InProgressTimeout is how long an accepted request may go without
+	// either side confirming completion before it expires and the requester
+	// is refunded. It counts from the end of the booked session, if any.
+	InProgressTimeout time.Duration `json:"in_progress_timeout"`
+	// AutoCompleteAfter is how long a request waits for the second side to
+	// confirm completion once the first has, before it is completed and the
+	// provider paid.
+	AutoCompleteAfter time.Duration `json:"auto_complete_after"`
+}
+
+// DEFAULT_EXPIRY_POLICY applies where neither the environment nor a category
+// sets a policy.
+var DEFAULT_EXPIRY_POLICY = ExpiryPolicy{
+	PendingTimeout:    36 * time.Hour,
+	AutoCompleteAfter: 72 * time.Hour,
+}
+
+// CategoryExpiryPolicy overrides the global policy for the listings of a
+// category and its subcategories. A nil duration inherits the parent
+// category's value, then the global one.
+type CategoryExpiryPolicy struct {
+	CategoryID        int32          `json:"category_id"`
+	Slug              string         `json:"slug"`
+	Name              string         `json:"name"`
+	PendingTimeout    *time.Duration `json:"pending_timeout"`
+	InProgressTimeout *time.Duration `json:"in_progress_timeout"`
+	AutoCompleteAfter *time.Duration `json:"auto_complete_after"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// ExpiryPolicies is the global policy along with the category overrides.
+type ExpiryPolicies struct {
+	Default    ExpiryPolicy           `json:"default"`
+	Categories []CategoryExpiryPolicy `json:"categories"`
+}
+
+func (p CategoryExpiryPolicy) Validate() error {
+	for name, d := range map[string]*time.Duration{
+		"pending_timeout":     p.PendingTimeout,
+		"in_progress_timeout": p.InProgressTimeout,
+		"auto_complete_after": p.AutoCompleteAfter,
+	} {
+		if d != nil && *d < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+func toInterval(d time.Duration) pgtype.Interval {
+	return pgtype.Interval{Microseconds: d.Microseconds(), Valid: true}
+}
+
+func toNullInterval(d *time.Duration) pgtype.Interval {
+	if d == nil {
+		return pgtype.Interval{}
+	}
+	return toInterval(*d)
+}
+
+func fromNullInterval(iv pgtype.Interval) *time.Duration {
+	if !iv.Valid {
+		return nil
+	}
+	d := time.Duration(iv.Microseconds) * time.Microsecond
+	return &d
+}
+
+// GetExpiryPolicies returns the global policy and every category override.
+func (prs *PostgresRequestService) GetExpiryPolicies(ctx context.Context) (ExpiryPolicies, error) {
+	repo := repository.New(prs.DB)
+	rows, err := repo.GetCategoryExpiryPolicies(ctx)
+	if err != nil {
+		log.Printf("GetExpiryPolicies: failed to get category policies: %s\n", err)
+		return ExpiryPolicies{}, internal.ErrInternalServerError
+	}
+	policies := ExpiryPolicies{Default: prs.Expiry, Categories: make([]CategoryExpiryPolicy, len(rows))}
+	for i, row := range rows {
+		policies.Categories[i] = CategoryExpiryPolicy{
+			CategoryID:        row.CategoryID,
+			Slug:              row.Slug,
+			Name:              row.Name,
+			PendingTimeout:    fromNullInterval(row.PendingTimeout),
+			InProgressTimeout: fromNullInterval(row.InProgressTimeout),
+			AutoCompleteAfter: fromNullInterval(row.AutoCompleteAfter),
+			UpdatedAt:         row.UpdatedAt,
+		}
+	}
+	return policies, nil
+}
+
+// SetCategoryExpiryPolicy creates or replaces the override of a category.
+// returns ErrInvalidCategory if the category does not exist.
+func (prs *PostgresRequestService) SetCategoryExpiryPolicy(ctx context.Context, p CategoryExpiryPolicy) error {
+	repo := repository.New(prs.DB)
+	err := repo.UpsertCategoryExpiryPolicy(ctx, repository.UpsertCategoryExpiryPolicyParams{
+		CategoryID:        p.CategoryID,
+		PendingTimeout:    toNullInterval(p.PendingTimeout),
+		InProgressTimeout: toNullInterval(p.InProgressTimeout),
+		AutoCompleteAfter: toNullInterval(p.AutoCompleteAfter),
+	})
+	if err != nil {
+		var pgerr *pgconn.PgError
+		if errors.As(err, &pgerr) && pgerr.Code == "23503" {
+			return internal.ErrInvalidCategory
+		}
+		log.Printf("SetCategoryExpiryPolicy: failed to save policy: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
+
+// DeleteCategoryExpiryPolicy removes the override of a category so it
+// inherits again. returns ErrNoRecord if the category has none.
+func (prs *PostgresRequestService) DeleteCategoryExpiryPolicy(ctx context.Context, categoryID int32) error {
+	repo := repository.New(prs.DB)
+	n, err := repo.DeleteCategoryExpiryPolicy(ctx, categoryID)
+	if err != nil {
+		log.Printf("DeleteCategoryExpiryPolicy: failed to delete policy: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if n == 0 {
+		return internal.ErrNoRecord
+	}
+	return nil
+}
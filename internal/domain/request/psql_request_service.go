@@ -2,17 +2,21 @@ package request
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/set-kaung/senior_project_1/internal"
 	"github.com/set-kaung/senior_project_1/internal/domain"
+	"github.com/set-kaung/senior_project_1/internal/domain/availability"
+	"github.com/set-kaung/senior_project_1/internal/domain/ledger"
 	"github.com/set-kaung/senior_project_1/internal/domain/listing"
+	"github.com/set-kaung/senior_project_1/internal/domain/notification"
 	"github.com/set-kaung/senior_project_1/internal/domain/review"
 	"github.com/set-kaung/senior_project_1/internal/repository"
 	"github.com/set-kaung/senior_project_1/internal/util"
@@ -23,6 +27,8 @@ import (
 
 type PostgresRequestService struct {
 	DB *pgxpool.Pool
+	// Expiry is the global policy, overridden per category.
+	Expiry ExpiryPolicy
 }
 
 // return InsuffcientBalance error
@@ -53,19 +59,40 @@ func (prs *PostgresRequestService) CreateServiceRequest(ctx context.Context, r R
 		return -1, err
 	}
 
-	rowsAffected, err := repo.DeductTokens(ctx, repository.DeductTokensParams{
-		ListingID: r.Listing.ID,
-		UserID:    r.Requester.ID,
-	})
+	var slotStart *time.Time
+	if r.Slot != nil {
+		slotStart = &r.Slot.Start
+	}
+	slot, err := availability.Reserve(ctx, repo, rid, r.Listing.ID, slotStart)
+	if err != nil {
+		if errors.Is(err, internal.ErrSlotRequired) || errors.Is(err, internal.ErrSlotUnavailable) || errors.Is(err, internal.ErrSlotTaken) {
+			return -1, err
+		}
+		log.Println("CreateServiceRequest: failed to reserve slot: ", err)
+		return -1, internal.ErrInternalServerError
+	}
 
-	if rowsAffected != 1 {
-		return -1, internal.ErrInsufficientBalance
+	request, err := repo.GetRequestByID(ctx, rid)
+	if err != nil {
+		log.Println("CreateServiceRequest: failed to get request: ", err)
+		return -1, internal.ErrInternalServerError
 	}
 
+	_, err = ledger.Post(ctx, repo, ledger.Entry{
+		Kind:          ledger.ENTRY_REQUEST_ESCROW,
+		Memo:          fmt.Sprintf("Tokens held for \"%s\"", request.SlTitle),
+		ReferenceType: ledger.REF_SERVICE_REQUEST,
+		ReferenceID:   rid,
+		Lines:         ledger.Move(ledger.UserWallet(r.Requester.ID), ledger.Escrow, request.SrTokenReward),
+	})
 	if err != nil {
-		log.Println("CreateServiceRequest: failed to deduct tokens: ", err)
+		if errors.Is(err, internal.ErrInsufficientBalance) {
+			return -1, internal.ErrInsufficientBalance
+		}
+		log.Println("CreateServiceRequest: failed to move tokens into escrow: ", err)
 		return -1, internal.ErrInternalServerError
 	}
+
 	insertPaymentRequestParams := repository.InsertPaymentHoldingParams{
 		ServiceRequestID: rid,
 		PayerID:          r.Requester.ID,
@@ -77,12 +104,6 @@ func (prs *PostgresRequestService) CreateServiceRequest(ctx context.Context, r R
 		return -1, err
 	}
 
-	request, err := repo.GetRequestByID(ctx, rid)
-	if err != nil {
-		log.Println("CreateServiceRequest: failed to get request: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-
 	eID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
 		TargetID:    request.SrID,
 		Type:        domain.REQUEST_EVENT,
@@ -103,11 +124,18 @@ func (prs *PostgresRequestService) CreateServiceRequest(ctx context.Context, r R
 		log.Println("CreateServiceRequest: failed to insert transaction: ", err)
 		return -1, internal.ErrInternalServerError
 	}
-	_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-		Message:         fmt.Sprintf("%s has requested your service \"%s\"", request.RequesterFullName, request.SlTitle),
-		RecipientUserID: request.ProviderID,
-		ActionUserID:    pgtype.Text{String: request.RequesterID, Valid: true},
-		EventID:         eID,
+	message := fmt.Sprintf("%s has requested your service \"%s\"", request.RequesterFullName, request.SlTitle)
+	if slot != nil {
+		message += " for " + slot.Start.Format(SLOT_TIME_FORMAT)
+	}
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  request.ProviderID,
+		ActionUserID: pgtype.Text{String: request.RequesterID, Valid: true},
+		EventID:      eID,
+		EventType:    domain.REQUEST_EVENT,
+		Description:  domain.INITIATE_REQUEST,
+		Message:      message,
+		Urgent:       true,
 	})
 
 	if err != nil {
@@ -119,10 +147,6 @@ func (prs *PostgresRequestService) CreateServiceRequest(ctx context.Context, r R
 		log.Println("CreateServiceRequest: failed to commit transaction: ", err)
 		return -1, internal.ErrInternalServerError
 	}
-	err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", request.ProviderID), "new-notification", nil)
-	if err != nil {
-		log.Println("CreateServiceRequest: failed to send notification: ", err)
-	}
 	return rid, nil
 }
 
@@ -139,6 +163,11 @@ func (prs *PostgresRequestService) GetRequestByID(ctx context.Context, rid int32
 			return Request{}, internal.ErrInternalServerError
 		}
 	}
+	dbSlot, slotErr := repo.GetRequestSlot(ctx, rid)
+	if slotErr != nil && !errors.Is(slotErr, pgx.ErrNoRows) {
+		log.Println("GetRequestByID: failed to get slot: ", slotErr)
+		return Request{}, internal.ErrInternalServerError
+	}
 
 	var events []Event
 	if len(dbRequest.Events) > 0 {
@@ -194,7 +223,8 @@ func (prs *PostgresRequestService) GetRequestByID(ctx context.Context, rid int32
 		ProviderCompleted:  dbRequest.ProviderCompleted,
 		RequesterCompleted: dbRequest.RequesterCompleted,
 		Events:             events,
-		IsTicketOpen:       dbRequest.ReportID.Valid,
+		IsTicketOpen:       (dbRequest.ReportID.Valid && dbRequest.ReportStatus.String != REPORT_RESOLVED) || dbRequest.EscrowStatus.PaymentStatus == repository.PaymentStatusDisputed,
+		EscrowStatus:       string(dbRequest.EscrowStatus.PaymentStatus),
 		Review: review.Review{
 			ID:               dbReview.ID,
 			ReviewerID:       dbReview.ReviewerID,
@@ -207,6 +237,10 @@ func (prs *PostgresRequestService) GetRequestByID(ctx context.Context, rid int32
 		},
 	}
 
+	if slotErr == nil {
+		r.Slot = &availability.Slot{Start: dbSlot.StartsAt, End: dbSlot.EndsAt}
+	}
+
 	if dbRequest.ReportID.Valid {
 		r.Report = RequestReport{
 			ID:         dbRequest.ReportID.Int32,
@@ -249,41 +283,34 @@ func (prs *PostgresRequestService) GetUserActiveServiceRequests(ctx context.Cont
 			TokenReward:  dbRequest.TokenReward,
 			IsProvider:   dbRequest.ProviderID == userID,
 		}
+		if dbRequest.SlotStartsAt.Valid {
+			requests[i].Slot = &availability.Slot{Start: dbRequest.SlotStartsAt.Time, End: dbRequest.SlotEndsAt.Time}
+		}
 	}
 
 	return requests, nil
 }
 
-// return unauthorized err if providerID is not equal to the one in DB
+// return unauthorized err if providerID is not equal to the one in DB,
+// invalid transition err if the request is no longer pending
 // else internal server error
 func (prs *PostgresRequestService) AcceptServiceRequest(ctx context.Context, requestID int32, providerID string) (int32, error) {
-	repo := repository.New(prs.DB)
-
-	repoRequest, err := repo.GetRequestByID(ctx, requestID)
-	if err != nil {
-		log.Println("AcceptServiceRequest: failed to create db transaction: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-
-	if repoRequest.ProviderID != providerID || repoRequest.SrActivity != "active" {
-		return -1, internal.ErrUnauthorized
-	}
-
 	tx, err := prs.DB.Begin(ctx)
 	if err != nil {
 		log.Println("AcceptServiceRequest: failed to create db transaction: ", err)
 		return -1, internal.ErrInternalServerError
 	}
 	defer tx.Rollback(ctx)
-	acceptServiceParams := repository.UpdateServiceRequestParams{
-		ID:           requestID,
-		StatusDetail: "in_progress",
-		Activity:     "active",
+	repo := repository.New(prs.DB).WithTx(tx)
+
+	repoRequest, t, err := beginTransition(ctx, repo, requestID, providerID, ACTION_ACCEPT)
+	if err != nil {
+		log.Println("AcceptServiceRequest: failed to begin transition: ", err)
+		return -1, err
 	}
-	repo = repository.New(prs.DB).WithTx(tx)
-	id, err := repo.UpdateServiceRequest(ctx, acceptServiceParams)
+	id, err := applyTransition(ctx, repo, t, repoRequest)
 	if err != nil {
-		log.Println("AcceptServiceRequest: failed to create db transaction: ", err)
+		log.Println("AcceptServiceRequest: failed to apply transition: ", err)
 		return -1, internal.ErrInternalServerError
 	}
 
@@ -299,11 +326,14 @@ func (prs *PostgresRequestService) AcceptServiceRequest(ctx context.Context, req
 
 	}
 
-	_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-		Message:         fmt.Sprintf("%s has accepted your request for \"%s\"", repoRequest.ProviderFullName, repoRequest.SlTitle),
-		RecipientUserID: repoRequest.RequesterID,
-		ActionUserID:    pgtype.Text{String: repoRequest.ProviderID, Valid: true},
-		EventID:         eID,
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  repoRequest.RequesterID,
+		ActionUserID: pgtype.Text{String: repoRequest.ProviderID, Valid: true},
+		EventID:      eID,
+		EventType:    domain.REQUEST_EVENT,
+		Description:  domain.ACCEPT_REQUEST,
+		Message:      fmt.Sprintf("%s has accepted your request for \"%s\"", repoRequest.ProviderFullName, repoRequest.SlTitle),
+		Urgent:       true,
 	})
 
 	if err != nil {
@@ -316,77 +346,27 @@ func (prs *PostgresRequestService) AcceptServiceRequest(ctx context.Context, req
 		log.Println("AcceptServiceRequest: failed to commit transaction: ", err)
 		return -1, internal.ErrInternalServerError
 	}
-	err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", repoRequest.RequesterID), "new-notification", nil)
-	if err != nil {
-		log.Println("AcceptServiceRequest: failed to send notification: ", err)
-	}
 	return id, nil
 }
 
 func (prs *PostgresRequestService) DeclineServiceRequest(ctx context.Context, requestID int32, providerID string) (int32, error) {
-	repo := repository.New(prs.DB)
-
-	// make sure the declien came from the provider and that the request is active
-	repoRequest, err := repo.GetRequestByID(ctx, requestID)
-	if err != nil {
-		log.Println("DeclineServiceRequest: failed to get request from db: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-	if repoRequest.ProviderID != providerID || repoRequest.SrActivity != "active" {
-		return -1, internal.ErrUnauthorized
-	}
-
 	tx, err := prs.DB.Begin(ctx)
 	if err != nil {
 		log.Println("DeclineServiceRequest: failed to create db transaction: ", err)
 		return -1, internal.ErrInternalServerError
 	}
 	defer tx.Rollback(ctx)
-	repo = repository.New(prs.DB).WithTx(tx)
-	rID, err := repo.UpdateServiceRequest(ctx, repository.UpdateServiceRequestParams{
-		ID:           requestID,
-		StatusDetail: "declined",
-		Activity:     "inactive",
-	})
-	if err != nil {
-		log.Println("DeclineServiceRequest: failed to update service request: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-	paymentHolding, err := repo.GetPaymentHolding(ctx, repository.GetPaymentHoldingParams{
-		ServiceRequestID: repoRequest.SrID,
-		PayerID:          repoRequest.RequesterID,
-	})
-	if err != nil {
-		log.Println("DeclineServiceRequest: failed to get payment holding: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-
-	_, err = repo.AddTokens(ctx, repository.AddTokensParams{
-		TokenBalance: paymentHolding.AmountTokens,
-		ID:           repoRequest.RequesterID,
-	})
-	if err != nil {
-		log.Println("DeclineServiceRequest: failed to add user tokens: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-
-	err = repo.InsertTransaction(ctx, repository.InsertTransactionParams{
-		UserID:    repoRequest.RequesterID,
-		Type:      domain.ADDITION_TRANS,
-		PaymentID: paymentHolding.ID,
-	})
+	repo := repository.New(prs.DB).WithTx(tx)
 
+	// make sure the decline came from the provider and that the request is still pending
+	repoRequest, t, err := beginTransition(ctx, repo, requestID, providerID, ACTION_DECLINE)
 	if err != nil {
-		log.Println("DeclineServiceRequest: failed to add user tokens: ", err)
-		return -1, internal.ErrInternalServerError
+		log.Println("DeclineServiceRequest: failed to begin transition: ", err)
+		return -1, err
 	}
-	_, err = repo.UpdatePaymentHolding(ctx, repository.UpdatePaymentHoldingParams{
-		Status:           "refunded",
-		ServiceRequestID: rID,
-	})
-
+	rID, err := applyTransition(ctx, repo, t, repoRequest)
 	if err != nil {
-		log.Println("DeclineServiceRequest: failed to update payment holding: ", err)
+		log.Println("DeclineServiceRequest: failed to apply transition: ", err)
 		return -1, internal.ErrInternalServerError
 	}
 
@@ -401,11 +381,13 @@ func (prs *PostgresRequestService) DeclineServiceRequest(ctx context.Context, re
 		return -1, internal.ErrInternalServerError
 	}
 
-	_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-		Message:         fmt.Sprintf("%s has declined your service request.", repoRequest.ProviderFullName),
-		RecipientUserID: repoRequest.RequesterID,
-		ActionUserID:    pgtype.Text{String: repoRequest.ProviderID, Valid: true},
-		EventID:         eventID,
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  repoRequest.RequesterID,
+		ActionUserID: pgtype.Text{String: repoRequest.ProviderID, Valid: true},
+		EventID:      eventID,
+		EventType:    domain.REQUEST_EVENT,
+		Description:  domain.DECLINE_REQUEST,
+		Message:      fmt.Sprintf("%s has declined your service request.", repoRequest.ProviderFullName),
 	})
 
 	if err != nil {
@@ -418,10 +400,6 @@ func (prs *PostgresRequestService) DeclineServiceRequest(ctx context.Context, re
 		return -1, internal.ErrInternalServerError
 	}
 
-	err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", repoRequest.RequesterID), "new-notification", nil)
-	if err != nil {
-		log.Println("DeclineServiceRequest: failed to send notification: ", err)
-	}
 	return rID, nil
 
 }
@@ -429,19 +407,19 @@ func (prs *PostgresRequestService) DeclineServiceRequest(ctx context.Context, re
 func (prs *PostgresRequestService) CompleteServiceRequest(ctx context.Context, requestID int32, userID string) (int32, error) {
 	rid := int32(-1)
 	tx, err := prs.DB.Begin(ctx)
-	repo := repository.New(prs.DB).WithTx(tx)
 	if err != nil {
 		log.Println("CompleteServiceRequest: failed to create db transaction: ", err)
 		return -1, internal.ErrInternalServerError
 	}
 	defer tx.Rollback(ctx)
-	request, err := repo.GetRequestByID(ctx, requestID)
+	repo := repository.New(prs.DB).WithTx(tx)
+
+	// every confirmation is checked against the complete transition, but the
+	// request only moves once both sides have confirmed
+	request, t, err := beginTransition(ctx, repo, requestID, userID, ACTION_COMPLETE)
 	if err != nil {
-		log.Println("CompleteServiceRequest: failed to get request from db: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-	if request.ProviderID != userID && request.RequesterID != userID {
-		return -1, internal.ErrUnauthorized
+		log.Println("CompleteServiceRequest: failed to begin transition: ", err)
+		return -1, err
 	}
 
 	requestCompletion, err := repo.GetServiceRequestCompletion(ctx, requestID)
@@ -450,7 +428,7 @@ func (prs *PostgresRequestService) CompleteServiceRequest(ctx context.Context, r
 		return -1, internal.ErrInternalServerError
 	}
 	if !requestCompletion.IsActive {
-		return -1, internal.ErrUnauthorized
+		return -1, internal.ErrInvalidTransition
 	}
 	requesterComplete := requestCompletion.RequesterCompleted || (userID == request.RequesterID)
 	providerComplete := requestCompletion.ProviderCompleted || (userID == request.ProviderID)
@@ -464,57 +442,11 @@ func (prs *PostgresRequestService) CompleteServiceRequest(ctx context.Context, r
 		log.Println("CompleteServiceRequest: failed to get requestCompletion from db: ", err)
 		return -1, internal.ErrInternalServerError
 	}
-	requestCompletion, err = repo.GetServiceRequestCompletion(ctx, requestID)
-	if err != nil {
-		log.Println("CompleteServiceRequest: failed to get requestCompletion from db: ", err)
-		return -1, internal.ErrInternalServerError
-	}
-
-	if requestCompletion.RequesterCompleted && requestCompletion.ProviderCompleted {
-		paymentHolding, err := repo.GetPaymentHolding(ctx, repository.GetPaymentHoldingParams{
-			ServiceRequestID: request.SrID,
-			PayerID:          request.RequesterID,
-		})
-		if err != nil {
-			log.Println("CompleteServiceRequest: failed to get payment holding: ", err)
-			return -1, internal.ErrInternalServerError
-		}
-		_, err = repo.AddTokens(ctx, repository.AddTokensParams{
-			TokenBalance: paymentHolding.AmountTokens,
-			ID:           request.ProviderID,
-		})
-		if err != nil {
-			log.Println("CompleteServiceRequest: failed to add user tokens: ", err)
-			return -1, internal.ErrInternalServerError
-		}
-		err = repo.InsertTransaction(ctx, repository.InsertTransactionParams{
-			UserID:    request.ProviderID,
-			Type:      domain.ADDITION_TRANS,
-			PaymentID: paymentHolding.ID,
-		})
-		if err != nil {
-			log.Println("CompleteServiceRequest: failed to get add user tokens: ", err)
-			return -1, internal.ErrInternalServerError
-		}
-
-		_, err = repo.UpdatePaymentHolding(ctx, repository.UpdatePaymentHoldingParams{
-			Status:           "released",
-			ServiceRequestID: requestID,
-		})
 
+	if requesterComplete && providerComplete {
+		rid, err = applyTransition(ctx, repo, t, request)
 		if err != nil {
-			log.Println("CompleteServiceRequest: failed to update payment holding ", err)
-			return -1, internal.ErrInternalServerError
-		}
-
-		rid, err = repo.UpdateServiceRequest(ctx, repository.UpdateServiceRequestParams{
-			StatusDetail: "completed",
-			Activity:     "inactive",
-			ID:           requestID,
-		})
-
-		if err != nil {
-			log.Println("CompleteServiceRequest: failed to update payment holding ", err)
+			log.Println("CompleteServiceRequest: failed to apply transition: ", err)
 			return -1, internal.ErrInternalServerError
 		}
 	}
@@ -543,11 +475,13 @@ func (prs *PostgresRequestService) CompleteServiceRequest(ctx context.Context, r
 		recipientID = request.RequesterID
 		notificationMessage = fmt.Sprintf("%s has confirmed completion.", request.ProviderFullName)
 	}
-	_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-		Message:         notificationMessage,
-		EventID:         eventID,
-		ActionUserID:    pgtype.Text{String: actionUserID, Valid: true},
-		RecipientUserID: recipientID,
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  recipientID,
+		ActionUserID: pgtype.Text{String: actionUserID, Valid: true},
+		EventID:      eventID,
+		EventType:    domain.REQUEST_EVENT,
+		Description:  domain.CONFIRM_COMPLETION,
+		Message:      notificationMessage,
 	})
 
 	if err != nil {
@@ -560,14 +494,12 @@ func (prs *PostgresRequestService) CompleteServiceRequest(ctx context.Context, r
 		return -1, internal.ErrInternalServerError
 	}
 
-	err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", recipientID), "new-notification", nil)
-	if err != nil {
-		log.Println("CompleteServiceRequest: failed to send notification: ", err)
-	}
 	return rid, nil
 }
 
-func (prs *PostgresRequestService) CreateRequestReport(ctx context.Context, requestID int32, userID string) (string, error) {
+// CreateRequestReport opens a dispute ticket on a request. Only the requester
+// and provider may open one, and only one ticket can be open per request.
+func (prs *PostgresRequestService) CreateRequestReport(ctx context.Context, r RequestReport) (string, error) {
 	tx, err := prs.DB.Begin(ctx)
 	if err != nil {
 		log.Println("InsertRequestReport: failed to create db transaction: ", err)
@@ -576,14 +508,50 @@ func (prs *PostgresRequestService) CreateRequestReport(ctx context.Context, requ
 	defer tx.Rollback(ctx)
 
 	repo := repository.New(prs.DB).WithTx(tx)
+	_, err = repo.LockServiceRequest(ctx, r.RequestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", internal.ErrNoRecord
+		}
+		log.Println("InsertRequestReport: failed to lock request: ", err)
+		return "", internal.ErrInternalServerError
+	}
+	request, err := repo.GetRequestByID(ctx, r.RequestID)
+	if err != nil {
+		log.Println("InsertRequestReport: failed to get request: ", err)
+		return "", internal.ErrInternalServerError
+	}
+	if _, err = actorOf(r.ReporterID, request.RequesterID, request.ProviderID); err != nil {
+		return "", err
+	}
+	_, err = repo.GetOpenRequestReportID(ctx, r.RequestID)
+	if err == nil {
+		return "", fmt.Errorf("%w: a ticket is already open for this request", internal.ErrInvalidTransition)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Println("InsertRequestReport: failed to check open tickets: ", err)
+		return "", internal.ErrInternalServerError
+	}
+
+	evidence := r.EvidenceURLs
+	if evidence == nil {
+		evidence = []string{}
+	}
 	dbReport, err := repo.InsertRequestReport(ctx, repository.InsertRequestReportParams{
-		ReporterID: userID,
-		RequestID:  requestID,
+		ReporterID:   r.ReporterID,
+		RequestID:    r.RequestID,
+		Description:  r.Description,
+		EvidenceUrls: evidence,
 	})
 	if err != nil {
 		log.Println("InsertRequestReport: failed to insert request report: ", err)
 		return "", internal.ErrInternalServerError
 	}
+	// a request that was already settled has nothing to freeze
+	if _, err = repo.FreezePayment(ctx, r.RequestID); err != nil {
+		log.Println("InsertRequestReport: failed to freeze payment: ", err)
+		return "", internal.ErrInternalServerError
+	}
 	ticketID, err := util.GenerateTicket(int64(dbReport.ID), dbReport.CreatedAt)
 	if err != nil {
 		log.Println("InsertRequestReport: failed to generate ticket: ", err)
@@ -601,12 +569,40 @@ func (prs *PostgresRequestService) CreateRequestReport(ctx context.Context, requ
 		log.Printf("InsertRequestReport: should not happened: db ticket: %s, server ticket: %s", dbTicketID, ticketID)
 		return "", internal.ErrInternalServerError
 	}
+
+	recipientID, reporterName := request.ProviderID, request.RequesterFullName
+	if r.ReporterID == request.ProviderID {
+		recipientID, reporterName = request.RequesterID, request.ProviderFullName
+	}
+	eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+		TargetID:    request.SrID,
+		Type:        domain.REQUEST_EVENT,
+		Description: domain.DISPUTE_OPENED,
+	})
+	if err != nil {
+		log.Printf("CreateRequestReport: failed to insert event: %s\n", err)
+		return "", internal.ErrInternalServerError
+	}
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  recipientID,
+		ActionUserID: pgtype.Text{String: r.ReporterID, Valid: true},
+		EventID:      eventID,
+		EventType:    domain.REQUEST_EVENT,
+		Description:  domain.DISPUTE_OPENED,
+		Message:      fmt.Sprintf("%s opened a dispute on \"%s\" (ticket %s).", reporterName, request.SlTitle, ticketID),
+	})
+	if err != nil {
+		log.Printf("CreateRequestReport: failed to insert notification: %s\n", err)
+		return "", internal.ErrInternalServerError
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		log.Println("InsertRequestReport: failed to commit transaction: ", err)
 		return "", internal.ErrInternalServerError
 	}
 	return ticketID, nil
 }
+
 func (prs *PostgresRequestService) GetRequestReview(ctx context.Context, requestID int32) (review.Review, error) {
 	repo := repository.New(prs.DB)
 	dbReview, err := repo.GetReviewByRequestID(ctx, requestID)
@@ -628,106 +624,374 @@ func (prs *PostgresRequestService) GetRequestReview(ctx context.Context, request
 	return r, nil
 }
 
-func (prs *PostgresRequestService) GetRequestReport(ctx context.Context, requestID int32, reporterID string) (RequestReport, error) {
+// GetRequestReport returns the latest ticket on a request with its messages.
+// Both the requester and the provider can see it.
+func (prs *PostgresRequestService) GetRequestReport(ctx context.Context, requestID int32, userID string) (RequestReport, error) {
 	repo := repository.New(prs.DB)
-	dbReport, err := repo.GetRequestReport(ctx, repository.GetRequestReportParams{
-		RequestID:  requestID,
-		ReporterID: reporterID,
-	})
-	report := RequestReport{}
+	request, err := repo.GetRequestByID(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RequestReport{}, internal.ErrNoRecord
+		}
+		log.Printf("GetRequestReport: failed to get request: %s\n", err)
+		return RequestReport{}, internal.ErrInternalServerError
+	}
+	if _, err = actorOf(userID, request.RequesterID, request.ProviderID); err != nil {
+		return RequestReport{}, err
+	}
+	dbReport, err := repo.GetLatestRequestReport(ctx, requestID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return report, internal.ErrNoRecord
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RequestReport{}, internal.ErrNoRecord
 		}
 		log.Printf("GetRequestReport: failed to get report: %s\n", err)
-		return report, internal.ErrInternalServerError
+		return RequestReport{}, internal.ErrInternalServerError
+	}
+	dbMessages, err := repo.GetRequestReportMessages(ctx, dbReport.ID)
+	if err != nil {
+		log.Printf("GetRequestReport: failed to get report messages: %s\n", err)
+		return RequestReport{}, internal.ErrInternalServerError
+	}
+
+	report := toRequestReport(dbReport)
+	report.Messages = make([]DisputeMessage, len(dbMessages))
+	for i, m := range dbMessages {
+		report.Messages[i] = DisputeMessage{
+			ID:             m.ID,
+			AuthorID:       m.AuthorID,
+			AuthorFullName: m.AuthorFullName,
+			Body:           m.Body,
+			EvidenceURLs:   m.EvidenceUrls,
+			CreatedAt:      m.CreatedAt,
+		}
 	}
-	report.ID = dbReport.ID
-	report.RequestID = dbReport.RequestID
-	report.TicketID = dbReport.TicketID
-	report.ReporterID = dbReport.ReporterID
-	report.Status = dbReport.Status
 	return report, nil
 }
 
-func (prs *PostgresRequestService) UpdateExpiredRequests(ctx context.Context) error {
-	tx, err := prs.DB.BeginTx(ctx, pgx.TxOptions{})
+// AddDisputeMessage adds a statement to the open ticket on a request. The
+// counterparty uses it to respond and the reporter to follow up.
+func (prs *PostgresRequestService) AddDisputeMessage(ctx context.Context, requestID int32, m DisputeMessage) (int32, error) {
+	tx, err := prs.DB.Begin(ctx)
 	if err != nil {
-		log.Printf(" UpdateExpiredRequests: failed to start transaction: %s\n", err)
-		return err
+		log.Printf("AddDisputeMessage: failed to begin tx: %s\n", err)
+		return -1, internal.ErrInternalServerError
 	}
 	defer tx.Rollback(ctx)
 	repo := repository.New(prs.DB).WithTx(tx)
-	requestersUpdated, err := repo.UpdateExpiredRequest(ctx)
+
+	request, err := repo.GetRequestByID(ctx, requestID)
 	if err != nil {
-		log.Printf(" UpdateExpiredRequests: failed to update expired request: %v\n", err)
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return -1, internal.ErrNoRecord
+		}
+		log.Printf("AddDisputeMessage: failed to get request: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	if _, err = actorOf(m.AuthorID, request.RequesterID, request.ProviderID); err != nil {
+		return -1, err
+	}
+	reportID, err := repo.GetOpenRequestReportID(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return -1, internal.ErrNoRecord
+		}
+		log.Printf("AddDisputeMessage: failed to get open report: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	evidence := m.EvidenceURLs
+	if evidence == nil {
+		evidence = []string{}
+	}
+	messageID, err := repo.InsertRequestReportMessage(ctx, repository.InsertRequestReportMessageParams{
+		ReportID:     reportID,
+		AuthorID:     m.AuthorID,
+		Body:         m.Body,
+		EvidenceUrls: evidence,
+	})
+	if err != nil {
+		log.Printf("AddDisputeMessage: failed to insert message: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	if err = repo.TouchRequestReport(ctx, reportID); err != nil {
+		log.Printf("AddDisputeMessage: failed to update report: %s\n", err)
+		return -1, internal.ErrInternalServerError
 	}
 
-	for _, row := range requestersUpdated {
-		err = repo.UpdateServiceRequestCompletion(ctx, repository.UpdateServiceRequestCompletionParams{
-			RequesterCompleted: true,
-			ProviderCompleted:  true,
-			IsActive:           false,
-			RequestID:          row.RequestID,
-		})
-		if err != nil {
-			log.Printf("UpdateExpiredRequests: failed to update service completion: %s\n", err)
-			return internal.ErrInternalServerError
+	recipientID, authorName := request.ProviderID, request.RequesterFullName
+	if m.AuthorID == request.ProviderID {
+		recipientID, authorName = request.RequesterID, request.ProviderFullName
+	}
+	eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+		TargetID:    request.SrID,
+		Type:        domain.REQUEST_EVENT,
+		Description: domain.DISPUTE_RESPONDED,
+	})
+	if err != nil {
+		log.Printf("AddDisputeMessage: failed to insert event: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  recipientID,
+		ActionUserID: pgtype.Text{String: m.AuthorID, Valid: true},
+		EventID:      eventID,
+		EventType:    domain.REQUEST_EVENT,
+		Description:  domain.DISPUTE_RESPONDED,
+		Message:      fmt.Sprintf("%s added to the dispute on \"%s\".", authorName, request.SlTitle),
+	})
+	if err != nil {
+		log.Printf("AddDisputeMessage: failed to insert notification: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("AddDisputeMessage: failed to commit: %s\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	return messageID, nil
+}
+
+// ResolveDispute closes a ticket with an admin's ruling. Refund, release and
+// split outcomes settle the held payment and move the request through the
+// state machine in the same transaction; dismiss only closes the ticket.
+// returns ErrNoRecord, ErrInvalidTransition if the ticket is already resolved
+// or the request can no longer be settled, and ErrInvalidShare for a bad split.
+func (prs *PostgresRequestService) ResolveDispute(ctx context.Context, reportID int32, adminID string, res Resolution) error {
+	tx, err := prs.DB.Begin(ctx)
+	if err != nil {
+		log.Printf("ResolveDispute: failed to begin tx: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(prs.DB).WithTx(tx)
+
+	report, err := repo.GetRequestReportForUpdate(ctx, reportID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNoRecord
 		}
-		_, err = repo.AddTokens(ctx, repository.AddTokensParams{
-			TokenBalance: row.TokenReward,
-			ID:           row.RequesterID,
-		})
+		log.Printf("ResolveDispute: failed to get report: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if report.Status == REPORT_RESOLVED {
+		return fmt.Errorf("%w: ticket is already resolved", internal.ErrInvalidTransition)
+	}
+	status, err := repo.LockServiceRequest(ctx, report.RequestID)
+	if err != nil {
+		log.Printf("ResolveDispute: failed to lock request: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	// the ruling settles the escrow itself, and a dismissal hands it back
+	// to the normal request flow
+	if _, err = repo.UnfreezePayment(ctx, report.RequestID); err != nil {
+		log.Printf("ResolveDispute: failed to unfreeze payment: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	request, err := repo.GetRequestByID(ctx, report.RequestID)
+	if err != nil {
+		log.Printf("ResolveDispute: failed to get request: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+
+	var action Action
+	switch res.Outcome {
+	case RESOLUTION_REFUND:
+		action = ACTION_RESOLVE_REFUND
+	case RESOLUTION_RELEASE:
+		action = ACTION_RESOLVE_RELEASE
+	case RESOLUTION_SPLIT:
+		action = ACTION_RESOLVE_SPLIT
+	case RESOLUTION_DISMISS:
+	default:
+		return fmt.Errorf("%w: unknown outcome %q", internal.ErrInvalidTransition, res.Outcome)
+	}
+	if action != "" {
+		t, err := Transit(status, action, ACTOR_ADMIN)
 		if err != nil {
-			log.Printf(" UpdateExpiredRequests: failed to refund user tokens: %v\n", err)
 			return err
 		}
+		if res.Outcome == RESOLUTION_SPLIT {
+			payment, err := repo.GetPaymentHolding(ctx, repository.GetPaymentHoldingParams{
+				ServiceRequestID: request.SrID,
+				PayerID:          request.RequesterID,
+			})
+			if err != nil {
+				log.Printf("ResolveDispute: failed to get payment holding: %s\n", err)
+				return internal.ErrInternalServerError
+			}
+			if res.RequesterShare <= 0 || res.RequesterShare >= payment.AmountTokens {
+				return internal.ErrInvalidShare
+			}
+			if err = settleEscrow(ctx, repo, request, payment, res.RequesterShare); err != nil {
+				log.Printf("ResolveDispute: failed to split escrow: %s\n", err)
+				return internal.ErrInternalServerError
+			}
+		}
+		if _, err = applyTransition(ctx, repo, t, request); err != nil {
+			log.Printf("ResolveDispute: failed to apply transition: %s\n", err)
+			return internal.ErrInternalServerError
+		}
+	}
 
-		_, err = repo.UpdatePaymentHolding(ctx, repository.UpdatePaymentHoldingParams{
-			Status:           repository.PaymentStatusRefunded,
-			ServiceRequestID: row.RequestID,
+	err = repo.ResolveRequestReport(ctx, repository.ResolveRequestReportParams{
+		Resolution:     pgtype.Text{String: res.Outcome, Valid: true},
+		RequesterShare: pgtype.Int4{Int32: res.RequesterShare, Valid: res.Outcome == RESOLUTION_SPLIT},
+		ResolutionNote: pgtype.Text{String: res.Note, Valid: res.Note != ""},
+		ResolvedBy:     pgtype.Text{String: adminID, Valid: true},
+		ID:             report.ID,
+	})
+	if err != nil {
+		log.Printf("ResolveDispute: failed to resolve report: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+
+	eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+		TargetID:    request.SrID,
+		Type:        domain.REQUEST_EVENT,
+		Description: domain.DISPUTE_RESOLVED,
+	})
+	if err != nil {
+		log.Printf("ResolveDispute: failed to insert event: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	message := fmt.Sprintf("The dispute on \"%s\" (ticket %s) has been resolved: %s.", request.SlTitle, report.TicketID, res.Outcome)
+	for _, recipientID := range []string{request.RequesterID, request.ProviderID} {
+		err = notification.Send(ctx, repo, notification.Notification{
+			RecipientID:  recipientID,
+			ActionUserID: pgtype.Text{Valid: false},
+			EventID:      eventID,
+			EventType:    domain.REQUEST_EVENT,
+			Description:  domain.DISPUTE_RESOLVED,
+			Message:      message,
 		})
 		if err != nil {
-			log.Printf(" UpdateExpiredRequests: failed to update payment status: %v\n", err)
-			return err
+			log.Printf("ResolveDispute: failed to insert notification: %s\n", err)
+			return internal.ErrInternalServerError
 		}
-		eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
-			TargetID:    row.RequestID,
-			Type:        domain.REQUEST_EVENT,
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("ResolveDispute: failed to commit: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
+
+func toRequestReport(r repository.RequestReport) RequestReport {
+	report := RequestReport{
+		ID:           r.ID,
+		ReporterID:   r.ReporterID,
+		RequestID:    r.RequestID,
+		TicketID:     r.TicketID,
+		Status:       r.Status,
+		Description:  r.Description,
+		EvidenceURLs: r.EvidenceUrls,
+		CreatedAt:    r.CreatedAt,
+		UpdatedAt:    r.UpdatedAt,
+	}
+	if r.Resolution.Valid {
+		report.Resolution = &Resolution{
+			Outcome:        r.Resolution.String,
+			RequesterShare: r.RequesterShare.Int32,
+			Note:           r.ResolutionNote.String,
+			ResolvedBy:     r.ResolvedBy.String,
+			ResolvedAt:     r.ResolvedAt.Time,
+		}
+	}
+	return report
+}
+
+// UpdateExpiredRequests applies the expiry policy in one pass: pending
+// requests the provider never answered and accepted requests nobody
+// confirmed expire with a refund, and requests only one side confirmed are
+// completed and paid out. Disputed requests are left to the admins.
+func (prs *PostgresRequestService) UpdateExpiredRequests(ctx context.Context) error {
+	tx, err := prs.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Printf(" UpdateExpiredRequests: failed to start transaction: %s\n", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(prs.DB).WithTx(tx)
+
+	expired, err := repo.GetExpiredRequests(ctx, toInterval(prs.Expiry.PendingTimeout))
+	if err != nil {
+		log.Printf(" UpdateExpiredRequests: failed to get expired requests: %v\n", err)
+		return err
+	}
+	for _, row := range expired {
+		err = takeSystemMove(ctx, repo, systemMove{
+			Action:      ACTION_EXPIRE,
+			From:        row.StatusDetail,
 			Description: domain.REQUEST_EXPIRED,
+			Request: repository.GetRequestByIDRow{
+				SrID:        row.RequestID,
+				SlTitle:     row.ListingTitle,
+				RequesterID: row.RequesterID,
+				ProviderID:  row.ProviderID,
+			},
+			RequesterMessage: fmt.Sprintf("Your request for \"%s\" has expired. Your tokens have been refunded.", row.ListingTitle),
+			ProviderMessage:  fmt.Sprintf("Request from %s has expired for your service \"%s\".", row.RequesterFullName, row.ListingTitle),
+			UrgentRequester:  true,
 		})
 		if err != nil {
-			log.Printf(" UpdateExpiredRequests: failed to insert events: %v\n", err)
+			log.Printf(" UpdateExpiredRequests: failed to expire request %d: %v\n", row.RequestID, err)
 			return err
 		}
-		_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-			Message:         fmt.Sprintf("Your request for \"%s\" has expired. Your tokens have been refunded.", row.ListingTitle),
-			RecipientUserID: row.RequesterID,
-			ActionUserID:    pgtype.Text{Valid: false},
-			EventID:         eventID,
+	}
+
+	stalled, err := repo.GetStalledRequests(ctx, toInterval(prs.Expiry.InProgressTimeout))
+	if err != nil {
+		log.Printf(" UpdateExpiredRequests: failed to get stalled requests: %v\n", err)
+		return err
+	}
+	for _, row := range stalled {
+		err = takeSystemMove(ctx, repo, systemMove{
+			Action:      ACTION_EXPIRE,
+			From:        row.StatusDetail,
+			Description: domain.REQUEST_EXPIRED,
+			Request: repository.GetRequestByIDRow{
+				SrID:        row.RequestID,
+				SlTitle:     row.ListingTitle,
+				RequesterID: row.RequesterID,
+				ProviderID:  row.ProviderID,
+			},
+			RequesterMessage: fmt.Sprintf("Your request for \"%s\" has expired because it was never confirmed as completed. Your tokens have been refunded.", row.ListingTitle),
+			ProviderMessage:  fmt.Sprintf("Request from %s for your service \"%s\" has expired because it was never confirmed as completed.", row.RequesterFullName, row.ListingTitle),
+			UrgentRequester:  true,
 		})
 		if err != nil {
-			log.Printf(" UpdateExpiredRequests: failed to insert notifications: %v\n", err)
+			log.Printf(" UpdateExpiredRequests: failed to expire request %d: %v\n", row.RequestID, err)
 			return err
 		}
-		_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-			Message:         fmt.Sprintf("Request from %s has expired for your service \"%s\".", row.RequesterFullName, row.ListingTitle),
-			RecipientUserID: row.ProviderID,
-			ActionUserID:    pgtype.Text{Valid: false},
-			EventID:         eventID,
-		})
-		if err != nil {
-			log.Printf(" UpdateExpiredRequests: failed to insert notifications: %v\n", err)
-			return err
+	}
+
+	halfConfirmed, err := repo.GetHalfConfirmedRequests(ctx, toInterval(prs.Expiry.AutoCompleteAfter))
+	if err != nil {
+		log.Printf(" UpdateExpiredRequests: failed to get half-confirmed requests: %v\n", err)
+		return err
+	}
+	for _, row := range halfConfirmed {
+		move := systemMove{
+			Action:      ACTION_AUTO_COMPLETE,
+			From:        row.StatusDetail,
+			Description: domain.AUTO_COMPLETED,
+			Request: repository.GetRequestByIDRow{
+				SrID:        row.RequestID,
+				SlTitle:     row.ListingTitle,
+				RequesterID: row.RequesterID,
+				ProviderID:  row.ProviderID,
+			},
+			ProviderMessage: fmt.Sprintf("Request for \"%s\" was completed automatically. The tokens have been paid to you.", row.ListingTitle),
 		}
-		err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", row.RequesterID), "new-notification", nil)
-		if err != nil {
-			log.Printf("CancelServiceRequest: failed to push notification: %s\n", err)
+		if row.RequesterCompleted {
+			move.RequesterMessage = fmt.Sprintf("Your request for \"%s\" was completed automatically since the provider did not confirm it.", row.ListingTitle)
+		} else {
+			move.RequesterMessage = fmt.Sprintf("Your request for \"%s\" was completed automatically since you did not confirm it in time. The tokens have been paid to the provider.", row.ListingTitle)
+			move.UrgentRequester = true
 		}
-		err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", row.ProviderID), "new-notification", nil)
-		if err != nil {
-			log.Printf("CancelServiceRequest: failed to push notification: %s\n", err)
+		if err = takeSystemMove(ctx, repo, move); err != nil {
+			log.Printf(" UpdateExpiredRequests: failed to complete request %d: %v\n", row.RequestID, err)
+			return err
 		}
 	}
 
@@ -738,71 +1002,185 @@ func (prs *PostgresRequestService) UpdateExpiredRequests(ctx context.Context) er
 	return nil
 }
 
-func (prs *PostgresRequestService) CancelServiceRequest(ctx context.Context, requestID int32, userID string) error {
-	tx, err := prs.DB.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		log.Printf("CancelServiceRequest: failed to begin db transaction: %s\n", err)
-		return internal.ErrInternalServerError
-	}
-	defer tx.Rollback(ctx)
-	repo := repository.New(tx)
-	repoRequest, err := repo.GetRequestByID(ctx, requestID)
+// systemMove is a transition the system takes on its own, with what each
+// side of the request is told about it.
+type systemMove struct {
+	Action           Action
+	From             repository.ServiceRequestStatus
+	Description      string
+	Request          repository.GetRequestByIDRow
+	RequesterMessage string
+	ProviderMessage  string
+	UrgentRequester  bool
+}
+
+// takeSystemMove applies m, records its event and notifies both sides. A
+// request that has moved on since it was selected is skipped.
+func takeSystemMove(ctx context.Context, repo *repository.Queries, m systemMove) error {
+	t, err := Transit(m.From, m.Action, ACTOR_SYSTEM)
 	if err != nil {
-		log.Printf("CancelServiceRequest: failed to get request by ID:%s\n", err)
-		return internal.ErrInternalServerError
+		log.Printf("takeSystemMove: skipping request %d: %v\n", m.Request.SrID, err)
+		return nil
 	}
-	if repoRequest.RequesterID != userID || repoRequest.SrActivity != repository.ServiceActivityActive {
-		return internal.ErrUnauthorized
+	if _, err = applyTransition(ctx, repo, t, m.Request); err != nil {
+		return fmt.Errorf("apply transition: %w", err)
 	}
-	_, err = repo.UpdateServiceRequest(ctx, repository.UpdateServiceRequestParams{
-		StatusDetail: repository.ServiceRequestStatusCancelled,
-		Activity:     repository.ServiceActivityInactive,
-		ID:           requestID,
+	eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+		TargetID:    m.Request.SrID,
+		Type:        domain.REQUEST_EVENT,
+		Description: m.Description,
 	})
+	if err != nil {
+		return fmt.Errorf("insert event: %w", err)
+	}
+	for _, n := range []notification.Notification{
+		{RecipientID: m.Request.RequesterID, Message: m.RequesterMessage, Urgent: m.UrgentRequester},
+		{RecipientID: m.Request.ProviderID, Message: m.ProviderMessage},
+	} {
+		n.ActionUserID = pgtype.Text{Valid: false}
+		n.EventID = eventID
+		n.EventType = domain.REQUEST_EVENT
+		n.Description = m.Description
+		if err = notification.Send(ctx, repo, n); err != nil {
+			return fmt.Errorf("send notification: %w", err)
+		}
+	}
+	return nil
+}
 
+// SendSlotReminders notifies both sides of accepted requests whose session
+// starts within lead. Each session is reminded of once.
+func (prs *PostgresRequestService) SendSlotReminders(ctx context.Context, lead time.Duration) (int, error) {
+	tx, err := prs.DB.Begin(ctx)
 	if err != nil {
-		log.Printf("CancelServiceRequest: failed to update service request: %s\n", err)
-		return internal.ErrInternalServerError
+		log.Printf("SendSlotReminders: failed to start transaction: %s\n", err)
+		return 0, err
 	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(prs.DB).WithTx(tx)
+	due, err := repo.GetDueSlotReminders(ctx, time.Now().Add(lead))
+	if err != nil {
+		log.Printf("SendSlotReminders: failed to get due reminders: %s\n", err)
+		return 0, err
+	}
+	for _, row := range due {
+		eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+			TargetID:    row.RequestID,
+			Type:        domain.REQUEST_EVENT,
+			Description: domain.SESSION_REMINDER,
+		})
+		if err != nil {
+			log.Printf("SendSlotReminders: failed to insert event: %s\n", err)
+			return 0, err
+		}
+		for _, recipient := range []string{row.RequesterID, row.ProviderID} {
+			err = notification.Send(ctx, repo, notification.Notification{
+				RecipientID:  recipient,
+				ActionUserID: pgtype.Text{Valid: false},
+				EventID:      eventID,
+				EventType:    domain.REQUEST_EVENT,
+				Description:  domain.SESSION_REMINDER,
+				Message:      fmt.Sprintf("Reminder: your session for \"%s\" starts %s", row.ListingTitle, row.StartsAt.UTC().Format(SLOT_TIME_FORMAT)),
+			})
+			if err != nil {
+				log.Printf("SendSlotReminders: failed to insert notification: %s\n", err)
+				return 0, err
+			}
+		}
+		if err = repo.MarkSlotReminded(ctx, row.RequestID); err != nil {
+			log.Printf("SendSlotReminders: failed to mark reminded: %s\n", err)
+			return 0, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("SendSlotReminders: failed to commit: %s\n", err)
+		return 0, err
+	}
+	return len(due), nil
+}
 
-	err = repo.UpdateServiceRequestCompletion(ctx, repository.UpdateServiceRequestCompletionParams{
-		RequesterCompleted: true,
-		ProviderCompleted:  true,
-		IsActive:           false,
-		RequestID:          requestID,
-	})
+// SendPendingReminders reminds providers of pending requests that expire
+// within one of the leads. Each request is reminded once per lead, and a
+// request that is already past several leads is only reminded at the
+// nearest one. returns how many reminders were sent.
+func (prs *PostgresRequestService) SendPendingReminders(ctx context.Context, leads []time.Duration) (int, error) {
+	tx, err := prs.DB.Begin(ctx)
 	if err != nil {
-		log.Printf("CancelServiceRequest: failed to update service completion: %s\n", err)
-		return internal.ErrInternalServerError
+		log.Printf("SendPendingReminders: failed to start transaction: %s\n", err)
+		return 0, err
 	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(prs.DB).WithTx(tx)
 
-	_, err = repo.AddTokens(ctx, repository.AddTokensParams{
-		TokenBalance: repoRequest.SrTokenReward,
-		ID:           repoRequest.RequesterID,
-	})
+	leads = slices.Clone(leads)
+	slices.Sort(leads)
+	sent := 0
+	for _, lead := range leads {
+		due, err := repo.GetDuePendingReminders(ctx, repository.GetDuePendingRemindersParams{
+			PendingTimeout: toInterval(prs.Expiry.PendingTimeout),
+			LeadMinutes:    int32(lead.Minutes()),
+		})
+		if err != nil {
+			log.Printf("SendPendingReminders: failed to get due reminders: %s\n", err)
+			return 0, err
+		}
+		for _, row := range due {
+			eventID, err := repo.InsertEvent(ctx, repository.InsertEventParams{
+				TargetID:    row.RequestID,
+				Type:        domain.REQUEST_EVENT,
+				Description: domain.PENDING_REMINDER,
+			})
+			if err != nil {
+				log.Printf("SendPendingReminders: failed to insert event: %s\n", err)
+				return 0, err
+			}
+			err = notification.Send(ctx, repo, notification.Notification{
+				RecipientID:  row.ProviderID,
+				ActionUserID: pgtype.Text{Valid: false},
+				EventID:      eventID,
+				EventType:    domain.REQUEST_EVENT,
+				Description:  domain.PENDING_REMINDER,
+				Message: fmt.Sprintf("%s's request for \"%s\" is waiting for your reply and expires %s. Accept or decline it before then.",
+					row.RequesterFullName, row.ListingTitle, row.ExpiresAt.UTC().Format(SLOT_TIME_FORMAT)),
+			})
+			if err != nil {
+				log.Printf("SendPendingReminders: failed to insert notification: %s\n", err)
+				return 0, err
+			}
+			err = repo.InsertPendingReminder(ctx, repository.InsertPendingReminderParams{
+				RequestID:   row.RequestID,
+				LeadMinutes: int32(lead.Minutes()),
+			})
+			if err != nil {
+				log.Printf("SendPendingReminders: failed to record reminder: %s\n", err)
+				return 0, err
+			}
+			sent++
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("SendPendingReminders: failed to commit: %s\n", err)
+		return 0, err
+	}
+	return sent, nil
+}
 
+func (prs *PostgresRequestService) CancelServiceRequest(ctx context.Context, requestID int32, userID string) error {
+	tx, err := prs.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		log.Printf("CancelServiceRequest: failed to add user tokens: %s\n", err)
+		log.Printf("CancelServiceRequest: failed to begin db transaction: %s\n", err)
 		return internal.ErrInternalServerError
 	}
-
-	paymentID, err := repo.UpdatePaymentHolding(ctx, repository.UpdatePaymentHoldingParams{
-		Status:           "refunded",
-		ServiceRequestID: repoRequest.SrID,
-	})
+	defer tx.Rollback(ctx)
+	repo := repository.New(tx)
+	repoRequest, t, err := beginTransition(ctx, repo, requestID, userID, ACTION_CANCEL)
 	if err != nil {
-		log.Printf("CancelServiceRequest: failed to update payment holding: %s\n", err)
-		return internal.ErrInternalServerError
+		log.Printf("CancelServiceRequest: failed to begin transition: %s\n", err)
+		return err
 	}
-
-	err = repo.InsertTransaction(ctx, repository.InsertTransactionParams{
-		UserID:    repoRequest.RequesterID,
-		Type:      "addition",
-		PaymentID: paymentID,
-	})
-
+	_, err = applyTransition(ctx, repo, t, repoRequest)
 	if err != nil {
-		log.Printf("CancelServiceRequest: failed to insert transaction: %s\n", err)
+		log.Printf("CancelServiceRequest: failed to apply transition: %s\n", err)
 		return internal.ErrInternalServerError
 	}
 
@@ -815,11 +1193,13 @@ func (prs *PostgresRequestService) CancelServiceRequest(ctx context.Context, req
 		log.Printf("CancelServiceRequest: failed to insert event: %s\n", err)
 		return internal.ErrInternalServerError
 	}
-	_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-		Message:         fmt.Sprintf("%s cancelled request for your service \"%s\".", repoRequest.RequesterFullName, repoRequest.SlTitle),
-		RecipientUserID: repoRequest.ProviderID,
-		ActionUserID:    pgtype.Text{String: repoRequest.RequesterID, Valid: true},
-		EventID:         eventID,
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  repoRequest.ProviderID,
+		ActionUserID: pgtype.Text{String: repoRequest.RequesterID, Valid: true},
+		EventID:      eventID,
+		EventType:    domain.REQUEST_EVENT,
+		Description:  domain.CANCELLED_REQUEST,
+		Message:      fmt.Sprintf("%s cancelled request for your service \"%s\".", repoRequest.RequesterFullName, repoRequest.SlTitle),
 	})
 	if err != nil {
 		log.Printf("CancelServiceRequest: failed to insert notification: %s\n", err)
@@ -829,10 +1209,6 @@ func (prs *PostgresRequestService) CancelServiceRequest(ctx context.Context, req
 		log.Printf("CancelServiceRequest: failed to commit transaction: %s\n", err)
 		return internal.ErrInternalServerError
 	}
-	err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", repoRequest.RequesterID), "new-notification", nil)
-	if err != nil {
-		log.Printf("CancelServiceRequest: failed to push notification: %s\n", err)
-	}
 	return nil
 }
 
@@ -847,15 +1223,199 @@ func (prs *PostgresRequestService) GetAllUserRequestReports(ctx context.Context,
 	for i, dbr := range dbReports {
 		log.Println("created at: ", dbr.CreatedAt)
 		tickets[i] = RequestReport{
-			ID:         dbr.ID,
-			ReporterID: dbr.ReporterID,
-			RequestID:  dbr.RequestID,
-			TicketID:   dbr.TicketID,
-			Status:     dbr.Status,
-			CreatedAt:  dbr.CreatedAt,
-			UpdatedAt:  dbr.UpdatedAt,
+			ID:           dbr.ID,
+			ReporterID:   dbr.ReporterID,
+			RequestID:    dbr.RequestID,
+			TicketID:     dbr.TicketID,
+			Status:       dbr.Status,
+			Description:  dbr.Description,
+			EvidenceURLs: dbr.EvidenceUrls,
+			CreatedAt:    dbr.CreatedAt,
+			UpdatedAt:    dbr.UpdatedAt,
 		}
 	}
 
 	return tickets, nil
 }
+
+// GetRequestReportsByStatus lists every ticket in the given status, oldest
+// first, for the admin queue.
+func (prs *PostgresRequestService) GetRequestReportsByStatus(ctx context.Context, status string) ([]RequestReport, error) {
+	repo := repository.New(prs.DB)
+	dbReports, err := repo.GetRequestReportsByStatus(ctx, status)
+	if err != nil {
+		log.Printf("GetRequestReportsByStatus: failed to get tickets: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	tickets := make([]RequestReport, len(dbReports))
+	for i, dbr := range dbReports {
+		tickets[i] = toRequestReport(repository.RequestReport{
+			ID:             dbr.ID,
+			ReporterID:     dbr.ReporterID,
+			RequestID:      dbr.RequestID,
+			TicketID:       dbr.TicketID,
+			CreatedAt:      dbr.CreatedAt,
+			Status:         dbr.Status,
+			UpdatedAt:      dbr.UpdatedAt,
+			Description:    dbr.Description,
+			EvidenceUrls:   dbr.EvidenceUrls,
+			Resolution:     dbr.Resolution,
+			RequesterShare: dbr.RequesterShare,
+			ResolutionNote: dbr.ResolutionNote,
+			ResolvedBy:     dbr.ResolvedBy,
+			ResolvedAt:     dbr.ResolvedAt,
+		})
+		tickets[i].ListingTitle = dbr.Title
+	}
+	return tickets, nil
+}
+
+// beginTransition locks the request row, works out the role userID plays in
+// it and checks the action against the state machine. Errors are either
+// ErrNoRecord, ErrUnauthorized, ErrInvalidTransition or ErrInternalServerError.
+func beginTransition(ctx context.Context, repo *repository.Queries, requestID int32, userID string, action Action) (repository.GetRequestByIDRow, Transition, error) {
+	status, err := repo.LockServiceRequest(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.GetRequestByIDRow{}, Transition{}, internal.ErrNoRecord
+		}
+		log.Printf("beginTransition: failed to lock request: %s\n", err)
+		return repository.GetRequestByIDRow{}, Transition{}, internal.ErrInternalServerError
+	}
+	request, err := repo.GetRequestByID(ctx, requestID)
+	if err != nil {
+		log.Printf("beginTransition: failed to get request: %s\n", err)
+		return repository.GetRequestByIDRow{}, Transition{}, internal.ErrInternalServerError
+	}
+	actor, err := actorOf(userID, request.RequesterID, request.ProviderID)
+	if err != nil {
+		return repository.GetRequestByIDRow{}, Transition{}, err
+	}
+	t, err := Transit(status, action, actor)
+	if err != nil {
+		return repository.GetRequestByIDRow{}, Transition{}, err
+	}
+	// held tokens may not move while a dispute is open, only ResolveDispute
+	// can settle them
+	if t.Has(EFFECT_REFUND_ESCROW) || t.Has(EFFECT_RELEASE_ESCROW) {
+		if request.EscrowStatus.Valid && request.EscrowStatus.PaymentStatus == repository.PaymentStatusDisputed {
+			return repository.GetRequestByIDRow{}, Transition{}, internal.ErrEscrowFrozen
+		}
+	}
+	return request, t, nil
+}
+
+// applyTransition moves the request to t.To and runs the transition's side
+// effects. Only SrID, SlTitle, RequesterID and ProviderID of request are used.
+func applyTransition(ctx context.Context, repo *repository.Queries, t Transition, request repository.GetRequestByIDRow) (int32, error) {
+	id, err := repo.UpdateServiceRequest(ctx, repository.UpdateServiceRequestParams{
+		StatusDetail: t.To,
+		Activity:     Activity(t.To),
+		ID:           request.SrID,
+	})
+	if err != nil {
+		return -1, fmt.Errorf("update service request: %w", err)
+	}
+
+	if t.Has(EFFECT_CLOSE_COMPLETION) {
+		err = repo.UpdateServiceRequestCompletion(ctx, repository.UpdateServiceRequestCompletionParams{
+			RequesterCompleted: true,
+			ProviderCompleted:  true,
+			IsActive:           false,
+			RequestID:          request.SrID,
+		})
+		if err != nil {
+			return -1, fmt.Errorf("close completion: %w", err)
+		}
+	}
+
+	if t.Has(EFFECT_RELEASE_SLOT) {
+		if err = repo.ReleaseRequestSlot(ctx, request.SrID); err != nil {
+			return -1, fmt.Errorf("release slot: %w", err)
+		}
+	}
+
+	if t.Has(EFFECT_REFUND_ESCROW) || t.Has(EFFECT_RELEASE_ESCROW) {
+		payment, err := repo.GetPaymentHolding(ctx, repository.GetPaymentHoldingParams{
+			ServiceRequestID: request.SrID,
+			PayerID:          request.RequesterID,
+		})
+		if err != nil {
+			return -1, fmt.Errorf("get payment holding: %w", err)
+		}
+		requesterShare := payment.AmountTokens
+		if t.Has(EFFECT_RELEASE_ESCROW) {
+			requesterShare = 0
+		}
+		if err = settleEscrow(ctx, repo, request, payment, requesterShare); err != nil {
+			return -1, err
+		}
+	}
+	return id, nil
+}
+
+// settleEscrow pays a held payment out: requesterShare goes back to the
+// requester and the rest to the provider, as a single journal entry.
+func settleEscrow(ctx context.Context, repo *repository.Queries, request repository.GetRequestByIDRow, payment repository.Payment, requesterShare int32) error {
+	if payment.Status != repository.PaymentStatusHolding {
+		return fmt.Errorf("payment %d is %s, not holding", payment.ID, payment.Status)
+	}
+	providerShare := payment.AmountTokens - requesterShare
+	entry := ledger.Entry{
+		ReferenceType: ledger.REF_SERVICE_REQUEST,
+		ReferenceID:   request.SrID,
+		Lines:         []ledger.Line{{Account: ledger.Escrow, Amount: -payment.AmountTokens}},
+	}
+	var status repository.PaymentStatus
+	switch {
+	case providerShare == 0:
+		status = repository.PaymentStatusRefunded
+		entry.Kind = ledger.ENTRY_REQUEST_REFUND
+		entry.Memo = fmt.Sprintf("Refund for \"%s\"", request.SlTitle)
+	case requesterShare == 0:
+		status = repository.PaymentStatusReleased
+		entry.Kind = ledger.ENTRY_REQUEST_RELEASE
+		entry.Memo = fmt.Sprintf("Payment released for \"%s\"", request.SlTitle)
+	default:
+		status = repository.PaymentStatusSplit
+		entry.Kind = ledger.ENTRY_DISPUTE_SPLIT
+		entry.Memo = fmt.Sprintf("Dispute settlement for \"%s\"", request.SlTitle)
+	}
+
+	shares := []struct {
+		userID string
+		amount int32
+	}{
+		{request.RequesterID, requesterShare},
+		{request.ProviderID, providerShare},
+	}
+	for _, share := range shares {
+		if share.amount > 0 {
+			entry.Lines = append(entry.Lines, ledger.Line{Account: ledger.UserWallet(share.userID), Amount: share.amount})
+		}
+	}
+	if _, err := ledger.Post(ctx, repo, entry); err != nil {
+		return fmt.Errorf("move escrow: %w", err)
+	}
+	_, err := repo.UpdatePaymentHolding(ctx, repository.UpdatePaymentHoldingParams{
+		Status:           status,
+		ServiceRequestID: request.SrID,
+	})
+	if err != nil {
+		return fmt.Errorf("update payment holding: %w", err)
+	}
+	for _, share := range shares {
+		if share.amount == 0 {
+			continue
+		}
+		err = repo.InsertTransactionAmount(ctx, repository.InsertTransactionAmountParams{
+			UserID: share.userID,
+			Type:   domain.ADDITION_TRANS,
+			Amount: share.amount,
+		})
+		if err != nil {
+			return fmt.Errorf("insert transaction: %w", err)
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/set-kaung/senior_project_1/internal/domain/availability"
 	"github.com/set-kaung/senior_project_1/internal/domain/listing"
 	"github.com/set-kaung/senior_project_1/internal/domain/review"
 	"github.com/set-kaung/senior_project_1/internal/domain/user"
@@ -35,9 +36,16 @@ type Request struct {
 	Review             review.Review   `json:"review"`
 	Events             []Event         `json:"events"`
 	IsTicketOpen       bool            `json:"is_ticket_open"`
+	EscrowStatus       string          `json:"escrow_status"`
 	Report             RequestReport   `json:"request_report,omitzero"`
+	// Slot is the booked session time, for listings whose provider
+	// publishes availability.
+	Slot *availability.Slot `json:"slot,omitempty"`
 }
 
+// SLOT_TIME_FORMAT is how slot times read in notifications.
+const SLOT_TIME_FORMAT = "Mon 2 Jan 15:04 MST"
+
 func CreateClientServiceRequest(listingID int32, requesterID string) Request {
 	return Request{Listing: listing.Listing{ID: listingID}, Requester: user.User{ID: requesterID}}
 }
@@ -82,12 +90,52 @@ func (e Events) Value() (driver.Value, error) {
 	return json.Marshal(e)
 }
 
+// Request report (dispute ticket) statuses.
+const (
+	REPORT_ONGOING  = "ongoing"
+	REPORT_RESOLVED = "resolved"
+)
+
+// Dispute outcomes an admin can resolve a ticket with.
+const (
+	RESOLUTION_REFUND  = "refund"
+	RESOLUTION_RELEASE = "release"
+	RESOLUTION_SPLIT   = "split"
+	RESOLUTION_DISMISS = "dismiss"
+)
+
 type RequestReport struct {
-	ID         int32     `json:"id"`
-	ReporterID string    `json:"reporter_id"`
-	RequestID  int32     `json:"request_id"`
-	TicketID   string    `json:"ticket_id"`
-	Status     string    `json:"status"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID           int32            `json:"id"`
+	ReporterID   string           `json:"reporter_id"`
+	RequestID    int32            `json:"request_id"`
+	ListingTitle string           `json:"listing_title,omitempty"`
+	TicketID     string           `json:"ticket_id"`
+	Status       string           `json:"status"`
+	Description  string           `json:"description"`
+	EvidenceURLs []string         `json:"evidence_urls"`
+	Messages     []DisputeMessage `json:"messages,omitempty"`
+	Resolution   *Resolution      `json:"resolution,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// DisputeMessage is a statement added to an open ticket by either party.
+type DisputeMessage struct {
+	ID             int32     `json:"id"`
+	AuthorID       string    `json:"author_id"`
+	AuthorFullName string    `json:"author_full_name"`
+	Body           string    `json:"body"`
+	EvidenceURLs   []string  `json:"evidence_urls"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Resolution is an admin's ruling on a ticket. RequesterShare is only used
+// for RESOLUTION_SPLIT and is the part of the held tokens that goes back to
+// the requester; the provider receives the rest.
+type Resolution struct {
+	Outcome        string    `json:"outcome"`
+	RequesterShare int32     `json:"requester_share"`
+	Note           string    `json:"note"`
+	ResolvedBy     string    `json:"resolved_by,omitempty"`
+	ResolvedAt     time.Time `json:"resolved_at,omitzero"`
 }
@@ -1,18 +1,28 @@
 package request
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain/availability"
 	"github.com/set-kaung/senior_project_1/internal/helpers"
+	"github.com/set-kaung/senior_project_1/internal/ical"
 )
 
 type RequestHandler struct {
 	RequestService RequestService
+	// PublicURL is the externally reachable base URL of the API, used to
+	// build calendar feed links.
+	PublicURL string
 }
 
 func (rh *RequestHandler) HandleCreateRequest(w http.ResponseWriter, r *http.Request) {
@@ -25,6 +35,18 @@ func (rh *RequestHandler) HandleCreateRequest(w http.ResponseWriter, r *http.Req
 		return
 	}
 	serviceRequest := CreateClientServiceRequest(int32(listingID), userID)
+	// the body is optional, only listings with availability need a slot
+	body := struct {
+		SlotStart *time.Time `json:"slot_start"`
+	}{}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		log.Println("request_handler -> HandleCreateRequest: err: ", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if body.SlotStart != nil {
+		serviceRequest.Slot = &availability.Slot{Start: *body.SlotStart}
+	}
 	requestID, err := rh.RequestService.CreateServiceRequest(r.Context(), serviceRequest)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -36,6 +58,14 @@ func (rh *RequestHandler) HandleCreateRequest(w http.ResponseWriter, r *http.Req
 			helpers.WriteError(w, http.StatusBadRequest, "insufficient balance", nil)
 			return
 		}
+		if errors.Is(err, internal.ErrSlotRequired) || errors.Is(err, internal.ErrSlotUnavailable) {
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if errors.Is(err, internal.ErrSlotTaken) {
+			helpers.WriteError(w, http.StatusConflict, err.Error(), nil)
+			return
+		}
 		log.Println("request_handler -> HandleCreateRequest: err: ", err)
 		helpers.WriteServerError(w, nil)
 		return
@@ -93,7 +123,7 @@ func (rh *RequestHandler) HandleAcceptServiceRequest(w http.ResponseWriter, r *h
 	rid, err := rh.RequestService.AcceptServiceRequest(r.Context(), int32(listingID), userID)
 	if err != nil {
 		log.Println("request_handler -> HandleAcceptServiceRequest: err: ", err)
-		helpers.WriteServerError(w, nil)
+		writeTransitionError(w, err)
 		return
 	}
 	helpers.WriteData(w, http.StatusOK, map[string]int32{"request_id": rid}, nil)
@@ -110,8 +140,8 @@ func (rh *RequestHandler) HandleDeclineServiceRequest(w http.ResponseWriter, r *
 	}
 	rid, err := rh.RequestService.DeclineServiceRequest(r.Context(), int32(listingID), userID)
 	if err != nil {
-		log.Println("request_handler -> HandleAcceptServiceRequest: err: ", err)
-		helpers.WriteServerError(w, nil)
+		log.Println("request_handler -> HandleDeclineServiceRequest: err: ", err)
+		writeTransitionError(w, err)
 		return
 	}
 	helpers.WriteData(w, http.StatusOK, map[string]int32{"request_id": rid}, nil)
@@ -128,12 +158,7 @@ func (rh *RequestHandler) HandleCompleteServiceRequest(w http.ResponseWriter, r
 	}
 	rid, err := rh.RequestService.CompleteServiceRequest(r.Context(), int32(requestID), userID)
 	if err != nil {
-		if errors.Is(err, internal.ErrUnauthorized) {
-			helpers.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
-
-		} else {
-			helpers.WriteServerError(w, nil)
-		}
+		writeTransitionError(w, err)
 		return
 	}
 
@@ -166,14 +191,54 @@ func (rh *RequestHandler) HandleCreateRequestReport(w http.ResponseWriter, r *ht
 		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
 		return
 	}
-	ticketID, err := rh.RequestService.CreateRequestReport(r.Context(), int32(requestID), userID)
+	report := RequestReport{}
+	if err = json.NewDecoder(r.Body).Decode(&report); err != nil {
+		log.Printf("HandleCreateRequestReport: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if strings.TrimSpace(report.Description) == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "description is required", nil)
+		return
+	}
+	report.RequestID = int32(requestID)
+	report.ReporterID = userID
+	ticketID, err := rh.RequestService.CreateRequestReport(r.Context(), report)
 	if err != nil {
-		helpers.WriteServerError(w, nil)
+		writeTransitionError(w, err)
 		return
 	}
 	helpers.WriteData(w, http.StatusCreated, map[string]string{"ticket_id": ticketID}, nil)
 }
 
+func (rh *RequestHandler) HandleAddDisputeMessage(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	requestPathValue := r.PathValue("id")
+	requestID, err := strconv.ParseInt(requestPathValue, 10, 32)
+	if err != nil {
+		log.Printf("HandleAddDisputeMessage: %s \n", err)
+		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
+		return
+	}
+	message := DisputeMessage{}
+	if err = json.NewDecoder(r.Body).Decode(&message); err != nil {
+		log.Printf("HandleAddDisputeMessage: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if strings.TrimSpace(message.Body) == "" {
+		helpers.WriteError(w, http.StatusBadRequest, "body is required", nil)
+		return
+	}
+	message.AuthorID = userID
+	messageID, err := rh.RequestService.AddDisputeMessage(r.Context(), int32(requestID), message)
+	if err != nil {
+		writeTransitionError(w, err)
+		return
+	}
+	helpers.WriteData(w, http.StatusCreated, map[string]int32{"message_id": messageID}, nil)
+}
+
 func (rh *RequestHandler) HandleGetReviewByRequestID(w http.ResponseWriter, r *http.Request) {
 	requestPathValue := r.PathValue("id")
 	requestID, err := strconv.ParseInt(requestPathValue, 10, 32)
@@ -201,11 +266,7 @@ func (rh *RequestHandler) HandleGetRequestReport(w http.ResponseWriter, r *http.
 	}
 	report, err := rh.RequestService.GetRequestReport(r.Context(), int32(requestID), userID)
 	if err != nil {
-		if errors.Is(err, internal.ErrNoRecord) {
-			helpers.WriteError(w, http.StatusNotFound, "no such recor", nil)
-			return
-		}
-		helpers.WriteServerError(w, nil)
+		writeTransitionError(w, err)
 		return
 	}
 	helpers.WriteData(w, http.StatusOK, report, nil)
@@ -222,11 +283,7 @@ func (rh *RequestHandler) HandleCancelRequest(w http.ResponseWriter, r *http.Req
 	}
 	err = rh.RequestService.CancelServiceRequest(r.Context(), int32(requestID), userID)
 	if err != nil {
-		if errors.Is(err, internal.ErrUnauthorized) {
-			helpers.WriteError(w, http.StatusUnauthorized, "not authorized", nil)
-		} else {
-			helpers.WriteServerError(w, nil)
-		}
+		writeTransitionError(w, err)
 		return
 	}
 	helpers.WriteSuccess(w, http.StatusOK, "request cancelled", nil)
@@ -241,3 +298,196 @@ func (rh *RequestHandler) HandleGetAllUserRequestReports(w http.ResponseWriter,
 	}
 	helpers.WriteData(w, http.StatusOK, tickets, nil)
 }
+
+func (rh *RequestHandler) HandleGetTickets(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = REPORT_ONGOING
+	}
+	if status != REPORT_ONGOING && status != REPORT_RESOLVED {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid status", nil)
+		return
+	}
+	tickets, err := rh.RequestService.GetRequestReportsByStatus(r.Context(), status)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, tickets, nil)
+}
+
+func (rh *RequestHandler) HandleResolveDispute(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	pathID := r.PathValue("id")
+	reportID, err := strconv.ParseInt(pathID, 10, 32)
+	if err != nil {
+		log.Printf("HandleResolveDispute: %s \n", err)
+		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
+		return
+	}
+	resolution := Resolution{}
+	if err = json.NewDecoder(r.Body).Decode(&resolution); err != nil {
+		log.Printf("HandleResolveDispute: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	err = rh.RequestService.ResolveDispute(r.Context(), int32(reportID), adminID, resolution)
+	if err != nil {
+		writeTransitionError(w, err)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "ticket resolved", nil)
+}
+
+// writeTransitionError maps errors from the request state machine to responses.
+func writeTransitionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrInvalidTransition), errors.Is(err, internal.ErrEscrowFrozen):
+		helpers.WriteError(w, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, internal.ErrUnauthorized):
+		helpers.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+	case errors.Is(err, internal.ErrInvalidShare):
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, internal.ErrNoRecord):
+		helpers.WriteError(w, http.StatusNotFound, "no such record", nil)
+	default:
+		helpers.WriteServerError(w, nil)
+	}
+}
+
+// HandleGetCalendarFeedLink returns the user's calendar subscription URL.
+func (rh *RequestHandler) HandleGetCalendarFeedLink(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	token, err := rh.RequestService.GetCalendarFeedToken(r.Context(), userID)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, rh.calendarFeedLink(token), nil)
+}
+
+// HandleRotateCalendarFeed replaces the user's calendar subscription URL.
+func (rh *RequestHandler) HandleRotateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	token, err := rh.RequestService.RotateCalendarFeedToken(r.Context(), userID)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, rh.calendarFeedLink(token), nil)
+}
+
+func (rh *RequestHandler) calendarFeedLink(token string) map[string]string {
+	return map[string]string{
+		"token": token,
+		"url":   strings.TrimSuffix(rh.PublicURL, "/") + "/calendar/" + token + ".ics",
+	}
+}
+
+// HandleGetCalendarFeed serves the iCalendar feed of the user owning the
+// token in the path. It is not behind auth since calendar apps cannot sign
+// in; the token is the credential.
+func (rh *RequestHandler) HandleGetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+	c, err := rh.RequestService.GetCalendarFeed(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) {
+			http.NotFound(w, r)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	writeCalendar(w, c, "")
+}
+
+func (rh *RequestHandler) HandleGetRequestICS(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	requestID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid id", nil)
+		return
+	}
+	c, err := rh.RequestService.GetRequestCalendar(r.Context(), int32(requestID), userID)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) {
+			helpers.WriteError(w, http.StatusNotFound, "no accepted request with this id", nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	writeCalendar(w, c, fmt.Sprintf("request-%d.ics", requestID))
+}
+
+// writeCalendar serves c, as a download named filename if one is given.
+func writeCalendar(w http.ResponseWriter, c ical.Calendar, filename string) {
+	w.Header().Set("Content-Type", ical.CONTENT_TYPE)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	if err := c.Encode(w, time.Now()); err != nil {
+		log.Printf("writeCalendar: failed to write calendar: %s\n", err)
+	}
+}
+
+// HandleGetExpiryPolicies returns the global expiry policy and the category
+// overrides.
+func (rh *RequestHandler) HandleGetExpiryPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := rh.RequestService.GetExpiryPolicies(r.Context())
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, policies, nil)
+}
+
+func (rh *RequestHandler) HandleSetCategoryExpiryPolicy(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		log.Printf("HandleSetCategoryExpiryPolicy: %s \n", err)
+		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
+		return
+	}
+	p := CategoryExpiryPolicy{}
+	if err = json.NewDecoder(r.Body).Decode(&p); err != nil {
+		log.Printf("HandleSetCategoryExpiryPolicy: %s \n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if err = p.Validate(); err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	p.CategoryID = int32(categoryID)
+	err = rh.RequestService.SetCategoryExpiryPolicy(r.Context(), p)
+	if errors.Is(err, internal.ErrInvalidCategory) {
+		helpers.WriteError(w, http.StatusNotFound, "no such category", nil)
+		return
+	}
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "expiry policy updated", nil)
+}
+
+func (rh *RequestHandler) HandleDeleteCategoryExpiryPolicy(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		log.Printf("HandleDeleteCategoryExpiryPolicy: %s \n", err)
+		helpers.WriteError(w, http.StatusUnprocessableEntity, "unprocessable entity", nil)
+		return
+	}
+	err = rh.RequestService.DeleteCategoryExpiryPolicy(r.Context(), int32(categoryID))
+	if errors.Is(err, internal.ErrNoRecord) {
+		helpers.WriteError(w, http.StatusNotFound, "no such record", nil)
+		return
+	}
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "expiry policy removed", nil)
+}
@@ -2,8 +2,10 @@ package request
 
 import (
 	"context"
+	"time"
 
 	"github.com/set-kaung/senior_project_1/internal/domain/review"
+	"github.com/set-kaung/senior_project_1/internal/ical"
 )
 
 type RequestService interface {
@@ -14,10 +16,24 @@ type RequestService interface {
 	DeclineServiceRequest(context.Context, int32, string) (int32, error)
 	CancelServiceRequest(ctx context.Context, requestID int32, userID string) error
 	CompleteServiceRequest(context.Context, int32, string) (int32, error)
-	CreateRequestReport(ctx context.Context, requestID int32, userID string) (string, error)
-	GetRequestReport(ctx context.Context, requestID int32, reporterID string) (RequestReport, error)
+	CreateRequestReport(ctx context.Context, r RequestReport) (string, error)
+	GetRequestReport(ctx context.Context, requestID int32, userID string) (RequestReport, error)
+	AddDisputeMessage(ctx context.Context, requestID int32, m DisputeMessage) (int32, error)
+	ResolveDispute(ctx context.Context, reportID int32, adminID string, res Resolution) error
 	GetRequestReview(ctx context.Context, requestID int32) (review.Review, error)
 	UpdateExpiredRequests(ctx context.Context) error
+	SendSlotReminders(ctx context.Context, lead time.Duration) (int, error)
+	SendPendingReminders(ctx context.Context, leads []time.Duration) (int, error)
+
+	GetExpiryPolicies(ctx context.Context) (ExpiryPolicies, error)
+	SetCategoryExpiryPolicy(ctx context.Context, p CategoryExpiryPolicy) error
+	DeleteCategoryExpiryPolicy(ctx context.Context, categoryID int32) error
+
+	GetCalendarFeedToken(ctx context.Context, userID string) (string, error)
+	RotateCalendarFeedToken(ctx context.Context, userID string) (string, error)
+	GetCalendarFeed(ctx context.Context, token string) (ical.Calendar, error)
+	GetRequestCalendar(ctx context.Context, requestID int32, userID string) (ical.Calendar, error)
 
 	GetAllUserRequestReports(ctx context.Context, userID string) ([]RequestReport, error)
+	GetRequestReportsByStatus(ctx context.Context, status string) ([]RequestReport, error)
 }
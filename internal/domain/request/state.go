@@ -0,0 +1,189 @@
+package request
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+// Actor is the party that triggers a transition.
+type Actor string
+
+const (
+	ACTOR_REQUESTER Actor = "requester"
+	ACTOR_PROVIDER  Actor = "provider"
+	ACTOR_SYSTEM    Actor = "system"
+	ACTOR_ADMIN     Actor = "admin"
+)
+
+type Action string
+
+const (
+	ACTION_ACCEPT   Action = "accept"
+	ACTION_DECLINE  Action = "decline"
+	ACTION_CANCEL   Action = "cancel"
+	ACTION_COMPLETE Action = "complete"
+	ACTION_EXPIRE   Action = "expire"
+	// ACTION_AUTO_COMPLETE finishes a request one side confirmed and the
+	// other never answered.
+	ACTION_AUTO_COMPLETE Action = "auto_complete"
+
+	ACTION_RESOLVE_REFUND  Action = "resolve_refund"
+	ACTION_RESOLVE_RELEASE Action = "resolve_release"
+	ACTION_RESOLVE_SPLIT   Action = "resolve_split"
+)
+
+// Effect is a side effect applied in the same db transaction as the
+// status change.
+type Effect int
+
+const (
+	// EFFECT_REFUND_ESCROW returns the held tokens to the requester.
+	EFFECT_REFUND_ESCROW Effect = iota + 1
+	// EFFECT_RELEASE_ESCROW pays the held tokens out to the provider.
+	EFFECT_RELEASE_ESCROW
+	// EFFECT_CLOSE_COMPLETION marks both sides as done so the request
+	// no longer waits for confirmation.
+	EFFECT_CLOSE_COMPLETION
+	// EFFECT_RELEASE_SLOT frees the booked time slot for other requests.
+	EFFECT_RELEASE_SLOT
+)
+
+// Transition is one legal move of a service request from one status to another.
+type Transition struct {
+	Action  Action
+	From    []repository.ServiceRequestStatus
+	To      repository.ServiceRequestStatus
+	By      []Actor
+	Effects []Effect
+}
+
+// transitions is the request lifecycle. Any move not listed here is rejected.
+//
+//	pending -> in_progress (accept)  -> completed (complete | auto_complete)
+//	pending -> declined | cancelled | expired
+//	in_progress -> expired
+//
+// Expiry and auto-completion are taken by the system once the timeouts of
+// the request's ExpiryPolicy run out.
+//
+// Disputes are settled by an admin: a refund ends the request as refunded,
+// a release or split ends it as completed.
+var transitions = map[Action]Transition{
+	ACTION_ACCEPT: {
+		Action: ACTION_ACCEPT,
+		From:   []repository.ServiceRequestStatus{repository.ServiceRequestStatusPending},
+		To:     repository.ServiceRequestStatusInProgress,
+		By:     []Actor{ACTOR_PROVIDER},
+	},
+	ACTION_DECLINE: {
+		Action:  ACTION_DECLINE,
+		From:    []repository.ServiceRequestStatus{repository.ServiceRequestStatusPending},
+		To:      repository.ServiceRequestStatusDeclined,
+		By:      []Actor{ACTOR_PROVIDER},
+		Effects: []Effect{EFFECT_REFUND_ESCROW, EFFECT_CLOSE_COMPLETION, EFFECT_RELEASE_SLOT},
+	},
+	ACTION_CANCEL: {
+		Action:  ACTION_CANCEL,
+		From:    []repository.ServiceRequestStatus{repository.ServiceRequestStatusPending},
+		To:      repository.ServiceRequestStatusCancelled,
+		By:      []Actor{ACTOR_REQUESTER},
+		Effects: []Effect{EFFECT_REFUND_ESCROW, EFFECT_CLOSE_COMPLETION, EFFECT_RELEASE_SLOT},
+	},
+	ACTION_COMPLETE: {
+		Action:  ACTION_COMPLETE,
+		From:    []repository.ServiceRequestStatus{repository.ServiceRequestStatusInProgress},
+		To:      repository.ServiceRequestStatusCompleted,
+		By:      []Actor{ACTOR_REQUESTER, ACTOR_PROVIDER},
+		Effects: []Effect{EFFECT_RELEASE_ESCROW},
+	},
+	ACTION_EXPIRE: {
+		Action: ACTION_EXPIRE,
+		From: []repository.ServiceRequestStatus{
+			repository.ServiceRequestStatusPending,
+			repository.ServiceRequestStatusInProgress,
+		},
+		To:      repository.ServiceRequestStatusExpired,
+		By:      []Actor{ACTOR_SYSTEM},
+		Effects: []Effect{EFFECT_REFUND_ESCROW, EFFECT_CLOSE_COMPLETION, EFFECT_RELEASE_SLOT},
+	},
+	ACTION_AUTO_COMPLETE: {
+		Action:  ACTION_AUTO_COMPLETE,
+		From:    []repository.ServiceRequestStatus{repository.ServiceRequestStatusInProgress},
+		To:      repository.ServiceRequestStatusCompleted,
+		By:      []Actor{ACTOR_SYSTEM},
+		Effects: []Effect{EFFECT_RELEASE_ESCROW, EFFECT_CLOSE_COMPLETION},
+	},
+	ACTION_RESOLVE_REFUND: {
+		Action: ACTION_RESOLVE_REFUND,
+		From: []repository.ServiceRequestStatus{
+			repository.ServiceRequestStatusPending,
+			repository.ServiceRequestStatusInProgress,
+		},
+		To:      repository.ServiceRequestStatusRefunded,
+		By:      []Actor{ACTOR_ADMIN},
+		Effects: []Effect{EFFECT_REFUND_ESCROW, EFFECT_CLOSE_COMPLETION, EFFECT_RELEASE_SLOT},
+	},
+	ACTION_RESOLVE_RELEASE: {
+		Action:  ACTION_RESOLVE_RELEASE,
+		From:    []repository.ServiceRequestStatus{repository.ServiceRequestStatusInProgress},
+		To:      repository.ServiceRequestStatusCompleted,
+		By:      []Actor{ACTOR_ADMIN},
+		Effects: []Effect{EFFECT_RELEASE_ESCROW, EFFECT_CLOSE_COMPLETION},
+	},
+	// the split itself is settled by ResolveDispute since the shares are
+	// part of the ruling, not of the transition
+	ACTION_RESOLVE_SPLIT: {
+		Action:  ACTION_RESOLVE_SPLIT,
+		From:    []repository.ServiceRequestStatus{repository.ServiceRequestStatusInProgress},
+		To:      repository.ServiceRequestStatusCompleted,
+		By:      []Actor{ACTOR_ADMIN},
+		Effects: []Effect{EFFECT_CLOSE_COMPLETION},
+	},
+}
+
+// Transit looks up the transition for action and checks that actor may take
+// it from the given status.
+// returns ErrUnauthorized if the actor may not take the action at all, and
+// ErrInvalidTransition if the request is not in a status the action starts from.
+func Transit(from repository.ServiceRequestStatus, action Action, actor Actor) (Transition, error) {
+	t, ok := transitions[action]
+	if !ok {
+		return Transition{}, fmt.Errorf("%w: unknown action %q", internal.ErrInvalidTransition, action)
+	}
+	if !slices.Contains(t.By, actor) {
+		return Transition{}, internal.ErrUnauthorized
+	}
+	if !slices.Contains(t.From, from) {
+		return Transition{}, fmt.Errorf("%w: cannot %s a request that is %s", internal.ErrInvalidTransition, action, from)
+	}
+	return t, nil
+}
+
+func (t Transition) Has(e Effect) bool {
+	return slices.Contains(t.Effects, e)
+}
+
+// Activity is the activity a request has once it reaches status s.
+func Activity(s repository.ServiceRequestStatus) repository.ServiceActivity {
+	switch s {
+	case repository.ServiceRequestStatusPending,
+		repository.ServiceRequestStatusAccepted,
+		repository.ServiceRequestStatusInProgress:
+		return repository.ServiceActivityActive
+	}
+	return repository.ServiceActivityInactive
+}
+
+// actorOf returns the role userID plays in a request.
+func actorOf(userID, requesterID, providerID string) (Actor, error) {
+	switch userID {
+	case providerID:
+		return ACTOR_PROVIDER, nil
+	case requesterID:
+		return ACTOR_REQUESTER, nil
+	}
+	return "", internal.ErrUnauthorized
+}
@@ -0,0 +1,95 @@
+package request
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+func TestTransit(t *testing.T) {
+	const (
+		pending    = repository.ServiceRequestStatusPending
+		accepted   = repository.ServiceRequestStatusAccepted
+		declined   = repository.ServiceRequestStatusDeclined
+		inProgress = repository.ServiceRequestStatusInProgress
+		completed  = repository.ServiceRequestStatusCompleted
+		cancelled  = repository.ServiceRequestStatusCancelled
+		expired    = repository.ServiceRequestStatusExpired
+		refunded   = repository.ServiceRequestStatusRefunded
+	)
+	statuses := []repository.ServiceRequestStatus{pending, accepted, declined, inProgress, completed, cancelled, expired, refunded}
+	actors := []Actor{ACTOR_REQUESTER, ACTOR_PROVIDER, ACTOR_SYSTEM, ACTOR_ADMIN}
+	refund := []Effect{EFFECT_REFUND_ESCROW, EFFECT_CLOSE_COMPLETION, EFFECT_RELEASE_SLOT}
+
+	// the lifecycle as the product defines it, written out independently of
+	// the transitions table
+	legal := map[Action]struct {
+		from    []repository.ServiceRequestStatus
+		by      []Actor
+		to      repository.ServiceRequestStatus
+		effects []Effect
+	}{
+		ACTION_ACCEPT:          {[]repository.ServiceRequestStatus{pending}, []Actor{ACTOR_PROVIDER}, inProgress, nil},
+		ACTION_DECLINE:         {[]repository.ServiceRequestStatus{pending}, []Actor{ACTOR_PROVIDER}, declined, refund},
+		ACTION_CANCEL:          {[]repository.ServiceRequestStatus{pending}, []Actor{ACTOR_REQUESTER}, cancelled, refund},
+		ACTION_COMPLETE:        {[]repository.ServiceRequestStatus{inProgress}, []Actor{ACTOR_REQUESTER, ACTOR_PROVIDER}, completed, []Effect{EFFECT_RELEASE_ESCROW}},
+		ACTION_EXPIRE:          {[]repository.ServiceRequestStatus{pending, inProgress}, []Actor{ACTOR_SYSTEM}, expired, refund},
+		ACTION_AUTO_COMPLETE:   {[]repository.ServiceRequestStatus{inProgress}, []Actor{ACTOR_SYSTEM}, completed, []Effect{EFFECT_RELEASE_ESCROW, EFFECT_CLOSE_COMPLETION}},
+		ACTION_RESOLVE_REFUND:  {[]repository.ServiceRequestStatus{pending, inProgress}, []Actor{ACTOR_ADMIN}, refunded, refund},
+		ACTION_RESOLVE_RELEASE: {[]repository.ServiceRequestStatus{inProgress}, []Actor{ACTOR_ADMIN}, completed, []Effect{EFFECT_RELEASE_ESCROW, EFFECT_CLOSE_COMPLETION}},
+		ACTION_RESOLVE_SPLIT:   {[]repository.ServiceRequestStatus{inProgress}, []Actor{ACTOR_ADMIN}, completed, []Effect{EFFECT_CLOSE_COMPLETION}},
+	}
+	if len(legal) != len(transitions) {
+		t.Fatalf("transitions has %d actions, the lifecycle %d", len(transitions), len(legal))
+	}
+
+	for action, want := range legal {
+		for _, actor := range actors {
+			for _, from := range statuses {
+				got, err := Transit(from, action, actor)
+				switch {
+				case !slices.Contains(want.by, actor):
+					if !errors.Is(err, internal.ErrUnauthorized) {
+						t.Errorf("Transit(%s, %s, %s) error = %v, want ErrUnauthorized", from, action, actor, err)
+					}
+				case !slices.Contains(want.from, from):
+					if !errors.Is(err, internal.ErrInvalidTransition) {
+						t.Errorf("Transit(%s, %s, %s) error = %v, want ErrInvalidTransition", from, action, actor, err)
+					}
+				case err != nil:
+					t.Errorf("Transit(%s, %s, %s) error = %v, want %s", from, action, actor, err, want.to)
+				case got.To != want.to || !slices.Equal(got.Effects, want.effects):
+					t.Errorf("Transit(%s, %s, %s) = %s with effects %v, want %s with %v", from, action, actor, got.To, got.Effects, want.to, want.effects)
+				}
+			}
+		}
+	}
+
+	for _, actor := range actors {
+		if _, err := Transit(pending, "reopen", actor); !errors.Is(err, internal.ErrInvalidTransition) {
+			t.Errorf("Transit(pending, reopen, %s) error = %v, want ErrInvalidTransition", actor, err)
+		}
+	}
+}
+
+func TestActorOf(t *testing.T) {
+	tests := []struct {
+		userID  string
+		want    Actor
+		wantErr error
+	}{
+		{"provider", ACTOR_PROVIDER, nil},
+		{"requester", ACTOR_REQUESTER, nil},
+		{"stranger", "", internal.ErrUnauthorized},
+		{"", "", internal.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		got, err := actorOf(tt.userID, "requester", "provider")
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("actorOf(%q) = %q, %v, want %q, %v", tt.userID, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
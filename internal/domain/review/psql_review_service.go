@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/set-kaung/senior_project_1/internal"
 	"github.com/set-kaung/senior_project_1/internal/domain"
+	"github.com/set-kaung/senior_project_1/internal/domain/notification"
 	"github.com/set-kaung/senior_project_1/internal/repository"
 )
 
@@ -68,11 +69,13 @@ func (prs *PostgresReviewService) InsertReview(ctx context.Context, r Review) (i
 		log.Printf("InsertReview: failed to get user full_nam:%s\n", err)
 		return -1, internal.ErrInternalServerError
 	}
-	_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-		Message:         fmt.Sprintf("%s left a review on you.", fullName),
-		RecipientUserID: insertedData.RevieweeID,
-		ActionUserID:    pgtype.Text{String: r.ReviewerID, Valid: true},
-		EventID:         eventID,
+	err = notification.Send(ctx, repo, notification.Notification{
+		RecipientID:  insertedData.RevieweeID,
+		ActionUserID: pgtype.Text{String: r.ReviewerID, Valid: true},
+		EventID:      eventID,
+		EventType:    domain.REVIEW_EVENT,
+		Description:  domain.REVIEWED_REQUEST,
+		Message:      fmt.Sprintf("%s left a review on you.", fullName),
 	})
 
 	if err != nil {
@@ -84,10 +87,6 @@ func (prs *PostgresReviewService) InsertReview(ctx context.Context, r Review) (i
 		log.Printf("InsertRequestReview: failed to commit transaction: %s\n", err)
 		return -1, internal.ErrInternalServerError
 	}
-	err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", insertedData.RevieweeID), "new-notifications", nil)
-	if err != nil {
-		log.Printf("failed to trigger pusher notification: %s\n", err)
-	}
 	return insertedData.ID, nil
 }
 
@@ -2,12 +2,15 @@ package reward
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"math/rand"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain/ledger"
 	"github.com/set-kaung/senior_project_1/internal/repository"
 )
 
@@ -134,11 +137,22 @@ func (prs *PostgresRewardService) InsertRedeemedReward(ctx context.Context, rewa
 		return "", internal.ErrInsufficientBalance
 	}
 
-	err = repo.DeductRewardTokensFromUser(ctx, repository.DeductRewardTokensFromUserParams{
-		UserID:   userID,
-		RewardID: rewardID,
+	reward, err := repo.GetRewardByID(ctx, rewardID)
+	if err != nil {
+		log.Printf("InsertRedeemedReward: failed to get reward: %v\n", err)
+		return "", internal.ErrInternalServerError
+	}
+	_, err = ledger.Post(ctx, repo, ledger.Entry{
+		Kind:          ledger.ENTRY_REWARD_REDEMPTION,
+		Memo:          fmt.Sprintf("Redeemed \"%s\"", reward.Title),
+		ReferenceType: ledger.REF_REWARD,
+		ReferenceID:   rewardID,
+		Lines:         ledger.Move(ledger.UserWallet(userID), ledger.RewardSink, reward.Cost),
 	})
 	if err != nil {
+		if errors.Is(err, internal.ErrInsufficientBalance) {
+			return "", internal.ErrInsufficientBalance
+		}
 		log.Printf("InsertRedeemedReward: failed to deduct reward cost from user: %v\n", err)
 		return "", internal.ErrInternalServerError
 	}
@@ -0,0 +1,68 @@
+package user
+
+import (
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
+)
+
+const (
+	// AD_WINDOW_ROLLING counts the ads watched in the last 24 hours.
+	AD_WINDOW_ROLLING = "rolling"
+	// AD_WINDOW_CALENDAR counts the ads watched since midnight in the
+	// policy's time zone.
+	AD_WINDOW_CALENDAR = "calendar"
+)
+
+// AdPolicy caps how many ads a user is rewarded for per day and how many
+// tokens each ad is worth.
+type AdPolicy struct {
+	DailyLimit int32
+	Window     string
+	// Location is the time zone calendar days start in.
+	Location *time.Location
+	// Reward is paid for ads without an ad unit.
+	Reward int32
+	// Rewards is what each known ad unit pays. Any other ad unit is rejected.
+	Rewards map[string]int32
+}
+
+var DEFAULT_AD_POLICY = AdPolicy{
+	DailyLimit: 10,
+	Window:     AD_WINDOW_ROLLING,
+	Location:   time.UTC,
+	Reward:     1,
+}
+
+// WindowStart returns when the day counted against the limit started.
+func (p AdPolicy) WindowStart(now time.Time) time.Time {
+	if p.Window == AD_WINDOW_CALENDAR {
+		loc := p.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		y, m, d := now.In(loc).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	return now.Add(-24 * time.Hour)
+}
+
+// RewardFor returns the tokens paid for watching an ad from adUnit.
+// returns ErrUnknownAdUnit if adUnit is set and has no reward.
+func (p AdPolicy) RewardFor(adUnit string) (int32, error) {
+	if adUnit == "" {
+		return p.Reward, nil
+	}
+	tokens, ok := p.Rewards[adUnit]
+	if !ok {
+		return 0, internal.ErrUnknownAdUnit
+	}
+	return tokens, nil
+}
+
+type AdsStatus struct {
+	Count     int64 `json:"count"`
+	Limit     int32 `json:"limit"`
+	Remaining int64 `json:"remaining"`
+	IsAtLimit bool  `json:"is_at_limit"`
+}
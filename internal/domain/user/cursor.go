@@ -0,0 +1,45 @@
+package user
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
+)
+
+// notificationCursor marks the last notification of a page by the time of
+// its event and its id, which break ties between events created together.
+type notificationCursor struct {
+	At time.Time
+	ID int32
+}
+
+func (c notificationCursor) encode() string {
+	raw := fmt.Sprintf("%d:%d", c.At.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeNotificationCursor parses a cursor handed out by GetNotifications.
+// returns ErrInvalidCursor if it is malformed.
+func decodeNotificationCursor(cursor string) (notificationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return notificationCursor{}, internal.ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return notificationCursor{}, internal.ErrInvalidCursor
+	}
+	micros, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return notificationCursor{}, internal.ErrInvalidCursor
+	}
+	notiID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		return notificationCursor{}, internal.ErrInvalidCursor
+	}
+	return notificationCursor{At: time.UnixMicro(micros), ID: int32(notiID)}, nil
+}
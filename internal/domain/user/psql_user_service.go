@@ -16,11 +16,19 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/set-kaung/senior_project_1/internal"
 	"github.com/set-kaung/senior_project_1/internal/domain"
+	"github.com/set-kaung/senior_project_1/internal/domain/ledger"
+	"github.com/set-kaung/senior_project_1/internal/domain/notification"
+	"github.com/set-kaung/senior_project_1/internal/geo"
 	"github.com/set-kaung/senior_project_1/internal/repository"
 )
 
 type PostgresUserService struct {
 	DB *pgxpool.Pool
+	// Geocoder locates users from their address when they sign up or
+	// change it. Users are left without a location when it is nil.
+	Geocoder geo.Geocoder
+	// Ads limits and prices ad rewards.
+	Ads AdPolicy
 }
 
 func (pus *PostgresUserService) GetUserByID(ctx context.Context, id string) (User, error) {
@@ -45,6 +53,7 @@ func (pus *PostgresUserService) GetUserByID(ctx context.Context, id string) (Use
 	rating := float32(repoUser.TotalRatings.Int32) / max(1.0, float32(repoUser.RatingCount.Int32))
 	user.Rating = float32(math.Round(float64(rating)*100) / 100)
 	user.AboutMe = repoUser.AboutMe.String
+	user.SuspendedUntil = repoUser.SuspendedUntil.Time
 	return user, err
 }
 
@@ -92,6 +101,7 @@ func (pus *PostgresUserService) InsertUser(ctx context.Context, user User) error
 		log.Printf("UserService -> InsertUser: error commiting transaction: %s\n", err)
 		return internal.ErrInternalServerError
 	}
+	pus.geocodeUser(ctx, user)
 	return nil
 }
 
@@ -125,9 +135,63 @@ func (pus *PostgresUserService) UpdateUser(ctx context.Context, user User) error
 		log.Printf("UserService -> InsertUser: error commiting transaction: %s\n", err)
 		return internal.ErrInternalServerError
 	}
+	pus.geocodeUser(ctx, user)
 	return nil
 }
 
+// geocodeUser stores the coordinates of the user's address. It is best
+// effort: a failed lookup only means the user has no location yet.
+func (pus *PostgresUserService) geocodeUser(ctx context.Context, user User) {
+	if pus.Geocoder == nil {
+		return
+	}
+	locateUser(ctx, pus.Geocoder, repository.New(pus.DB), user)
+}
+
+func locateUser(ctx context.Context, geocoder geo.Geocoder, repo *repository.Queries, user User) {
+	point, err := geocoder.Geocode(ctx, geo.Address{
+		Line1:         user.AddressLine1,
+		City:          user.City,
+		StateProvince: user.StateProvince,
+		ZipPostalCode: user.ZipPostalCode,
+		Country:       user.Country,
+	})
+	if err != nil {
+		log.Printf("UserService -> geocodeUser: failed to geocode user %s: %s\n", user.ID, err)
+		return
+	}
+	err = repo.UpsertUserLocation(ctx, repository.UpsertUserLocationParams{
+		UserID:    user.ID,
+		Latitude:  point.Latitude,
+		Longitude: point.Longitude,
+		Source:    LOCATION_GEOCODED,
+	})
+	if err != nil {
+		log.Printf("UserService -> geocodeUser: failed to save location: %s\n", err)
+	}
+}
+
+// EnsureDeletable returns ErrEscrowFrozen while the user is party to an open
+// dispute, whose held tokens would otherwise be refunded or dropped with
+// their requests.
+func (pus *PostgresUserService) EnsureDeletable(ctx context.Context, id string) error {
+	return ensureDeletable(ctx, repository.New(pus.DB), id)
+}
+
+func ensureDeletable(ctx context.Context, repo *repository.Queries, id string) error {
+	disputes, err := repo.CountUserDisputes(ctx, id)
+	if err != nil {
+		log.Printf("UserService -> ensureDeletable: failed to count disputes: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if disputes > 0 {
+		return internal.ErrEscrowFrozen
+	}
+	return nil
+}
+
+// DeleteUser refunds the requests the user is providing and deletes them.
+// returns ErrEscrowFrozen while the user is party to an open dispute.
 func (pus *PostgresUserService) DeleteUser(ctx context.Context, id string) error {
 	tx, err := pus.DB.Begin(ctx)
 	if err != nil {
@@ -141,6 +205,9 @@ func (pus *PostgresUserService) DeleteUser(ctx context.Context, id string) error
 	}()
 
 	repo := repository.New(pus.DB).WithTx(tx)
+	if err = ensureDeletable(ctx, repo, id); err != nil {
+		return err
+	}
 	requestIDs, err := repo.GetProvidingeRequests(ctx, id)
 	if err != nil {
 		log.Printf("DeleteUser: failed to get requestIDs: %s\n", err)
@@ -153,12 +220,15 @@ func (pus *PostgresUserService) DeleteUser(ctx context.Context, id string) error
 			log.Printf("DeleteUser: failed to get request payment: %s\n", err)
 			return internal.ErrInternalServerError
 		}
-		_, err = repo.AddTokens(ctx, repository.AddTokensParams{
-			ID:           payment.PayerID,
-			TokenBalance: payment.AmountTokens,
+		_, err = ledger.Post(ctx, repo, ledger.Entry{
+			Kind:          ledger.ENTRY_REQUEST_REFUND,
+			Memo:          fmt.Sprintf("Refund for \"%s\"", request.Title),
+			ReferenceType: ledger.REF_SERVICE_REQUEST,
+			ReferenceID:   request.ID,
+			Lines:         ledger.Move(ledger.Escrow, ledger.UserWallet(payment.PayerID), payment.AmountTokens),
 		})
 		if err != nil {
-			log.Printf("DeleteUser: failed to add tokens: %s\n", err)
+			log.Printf("DeleteUser: failed to refund tokens: %s\n", err)
 			return internal.ErrInternalServerError
 		}
 		_, err = repo.UpdatePaymentHolding(ctx, repository.UpdatePaymentHoldingParams{
@@ -187,20 +257,18 @@ func (pus *PostgresUserService) DeleteUser(ctx context.Context, id string) error
 			log.Printf("DeleteUser: failed to  insert event: %s\n", err)
 			return internal.ErrInternalServerError
 		}
-		_, err = repo.InsertNotification(ctx, repository.InsertNotificationParams{
-			Message:         fmt.Sprintf("Your token for %s has been refunded.", request.Title),
-			RecipientUserID: payment.PayerID,
-			ActionUserID:    pgtype.Text{Valid: false},
-			EventID:         eventID,
+		err = notification.Send(ctx, repo, notification.Notification{
+			RecipientID:  payment.PayerID,
+			ActionUserID: pgtype.Text{Valid: false},
+			EventID:      eventID,
+			EventType:    domain.REQUEST_EVENT,
+			Description:  domain.USER_DO_NOT_EXIST,
+			Message:      fmt.Sprintf("Your token for %s has been refunded.", request.Title),
 		})
 		if err != nil {
 			log.Printf("DeleteUser: failed to insert notification: %s\n", err)
 			return internal.ErrInternalServerError
 		}
-		err = internal.PusherClient.Trigger(fmt.Sprintf("user-%s", payment.PayerID), "new-notification", nil)
-		if err != nil {
-			log.Printf("DeleteUser: failed to trigger pusher: %s\n", err)
-		}
 	}
 
 	_, err = repo.DeleteUser(ctx, id)
@@ -216,11 +284,19 @@ func (pus *PostgresUserService) DeleteUser(ctx context.Context, id string) error
 	return nil
 }
 
-func (pus *PostgresUserService) InsertAdsHistory(ctx context.Context, userID string) error {
+// InsertAdsHistory records an ad watched from adUnit and credits its reward,
+// returning the tokens credited. returns ErrAdLimitReached once the user has
+// been rewarded for the daily limit of ads, and ErrUnknownAdUnit for an ad
+// unit without a reward.
+func (pus *PostgresUserService) InsertAdsHistory(ctx context.Context, userID string, adUnit string) (int32, error) {
+	tokens, err := pus.Ads.RewardFor(adUnit)
+	if err != nil {
+		return 0, err
+	}
 	tx, err := pus.DB.Begin(ctx)
 	if err != nil {
 		log.Printf("failed to begin tx: %s\n", err)
-		return internal.ErrInternalServerError
+		return 0, internal.ErrInternalServerError
 	}
 	defer func() {
 		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
@@ -228,62 +304,181 @@ func (pus *PostgresUserService) InsertAdsHistory(ctx context.Context, userID str
 		}
 	}()
 
-	repo := repository.New(pus.DB).WithTx(tx)
-	_, err = repo.InsertAdsHistory(ctx, userID)
+	if err = insertAdsHistory(ctx, repository.New(pus.DB).WithTx(tx), pus.Ads, time.Now(), userID, adUnit, tokens); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit ads history: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+
+	return tokens, nil
+}
+
+func insertAdsHistory(ctx context.Context, repo *repository.Queries, policy AdPolicy, now time.Time, userID string, adUnit string, tokens int32) error {
+	// the user's row lock serialises concurrent claims, so two requests
+	// cannot both see the last ad left under the limit
+	if _, err := repo.LockUserBalance(ctx, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNoRecord
+		}
+		log.Printf("failed to lock user for ads history: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	count, err := repo.GetAdsWatched(ctx, repository.GetAdsWatchedParams{
+		UserID: userID,
+		Since:  policy.WindowStart(now),
+	})
 	if err != nil {
-		log.Printf("failed to insert ads history: %s\n", err)
+		log.Printf("failed to count ads watched: %s\n", err)
 		return internal.ErrInternalServerError
 	}
-	_, err = repo.AddTokens(ctx, repository.AddTokensParams{
-		TokenBalance: 1,
-		ID:           userID,
+	if count >= int64(policy.DailyLimit) {
+		return internal.ErrAdLimitReached
+	}
+	adID, err := repo.InsertAdsHistory(ctx, repository.InsertAdsHistoryParams{
+		UserID: userID,
+		AdUnit: pgtype.Text{String: adUnit, Valid: adUnit != ""},
+		Tokens: tokens,
 	})
 	if err != nil {
-		log.Printf("failed to add token balance for ad watching: %s\n", err)
+		log.Printf("failed to insert ads history: %s\n", err)
 		return internal.ErrInternalServerError
 	}
-	if err = tx.Commit(ctx); err != nil {
-		log.Printf("failed to commit ads history: %s\n", err)
+	_, err = ledger.Post(ctx, repo, ledger.Entry{
+		Kind:          ledger.ENTRY_AD_REWARD,
+		Memo:          "Advertisement watched",
+		ReferenceType: ledger.REF_AD_WATCH,
+		ReferenceID:   adID,
+		Lines:         ledger.Move(ledger.SystemMint, ledger.UserWallet(userID), tokens),
+	})
+	if err != nil {
+		log.Printf("failed to add token balance for ad watching: %s\n", err)
 		return internal.ErrInternalServerError
 	}
-
 	return nil
 }
 
-func (pus *PostgresUserService) GetAdsHistory(ctx context.Context, userID string) (int64, error) {
+// GetAdsStatus returns how many ads the user has watched in the current
+// window and how many more they will be rewarded for.
+func (pus *PostgresUserService) GetAdsStatus(ctx context.Context, userID string) (AdsStatus, error) {
 	repo := repository.New(pus.DB)
-	count, err := repo.GetAdsWatched(ctx, userID)
+	count, err := repo.GetAdsWatched(ctx, repository.GetAdsWatchedParams{
+		UserID: userID,
+		Since:  pus.Ads.WindowStart(time.Now()),
+	})
 	if err != nil {
 		log.Printf("failed to get ads history: %s\n", err)
-		return -1, internal.ErrInternalServerError
-	}
-	return count, nil
+		return AdsStatus{}, internal.ErrInternalServerError
+	}
+	limit := int64(pus.Ads.DailyLimit)
+	return AdsStatus{
+		Count:     count,
+		Limit:     pus.Ads.DailyLimit,
+		Remaining: max(limit-count, 0),
+		IsAtLimit: count >= limit,
+	}, nil
 }
 
-func (pus *PostgresUserService) GetNotifications(ctx context.Context, userID string) ([]Notification, error) {
+// GetNotifications returns a page of the user's notifications matching q,
+// newest event first. returns ErrInvalidCursor if q.Cursor cannot be used.
+func (pus *PostgresUserService) GetNotifications(ctx context.Context, userID string, q NotificationQuery) (NotificationPage, error) {
+	if q.Limit <= 0 || q.Limit > MAX_NOTIFICATION_PAGE_SIZE {
+		q.Limit = DEFAULT_NOTIFICATION_PAGE_SIZE
+	}
+	params := repository.GetNotificationsParams{
+		RecipientUserID: userID,
+		Archived:        q.Archived,
+		UnreadOnly:      q.UnreadOnly,
+		EventType:       pgtype.Text{String: q.EventType, Valid: q.EventType != ""},
+		// one extra row tells us whether there is a next page
+		PageSize: q.Limit + 1,
+	}
+	if q.Cursor != "" {
+		c, err := decodeNotificationCursor(q.Cursor)
+		if err != nil {
+			return NotificationPage{}, err
+		}
+		params.BeforeTime = pgtype.Timestamptz{Time: c.At, Valid: true}
+		params.BeforeID = pgtype.Int4{Int32: c.ID, Valid: true}
+	}
 	repo := repository.New(pus.DB)
-	dbNotis, err := repo.GetNotifications(ctx, userID)
+	dbNotis, err := repo.GetNotifications(ctx, params)
 	if err != nil {
 		log.Printf("GetNotifications: failed to get notifications from DB: %s\n", err)
-		return nil, internal.ErrInternalServerError
+		return NotificationPage{}, internal.ErrInternalServerError
 	}
 
-	notifications := make([]Notification, 0, len(dbNotis))
+	page := NotificationPage{}
+	if len(dbNotis) > int(q.Limit) {
+		dbNotis = dbNotis[:q.Limit]
+		last := dbNotis[len(dbNotis)-1]
+		page.NextCursor = notificationCursor{At: last.CreatedAt, ID: last.ID}.encode()
+	}
+	page.Notifications = make([]Notification, 0, len(dbNotis))
 	for _, dbN := range dbNotis {
-		notifications = append(notifications, Notification{
-			ID:              dbN.ID,
-			ActionUserID:    dbN.ActionUserID.String,
-			RecipientUserID: dbN.RecipientUserID,
-			EventID:         dbN.EventID,
-			Message:         dbN.Message,
-			IsRead:          dbN.IsRead,
-			CreatedAt:       dbN.CreatedAt,
-			EventType:       dbN.Type,
-			EventTargetID:   dbN.TargetID,
+		page.Notifications = append(page.Notifications, Notification{
+			ID:               dbN.ID,
+			ActionUserID:     dbN.ActionUserID.String,
+			RecipientUserID:  dbN.RecipientUserID,
+			EventID:          dbN.EventID,
+			Message:          dbN.Message,
+			IsRead:           dbN.IsRead,
+			CreatedAt:        dbN.CreatedAt,
+			EventType:        dbN.Type,
+			EventDescription: dbN.Description,
+			EventTargetID:    dbN.TargetID,
+			ArchivedAt:       dbN.ArchivedAt.Time,
 		})
 	}
 
-	return notifications, nil
+	return page, nil
+}
+
+// GetUnreadNotificationCount counts the user's unread notifications that are
+// not archived.
+func (pus *PostgresUserService) GetUnreadNotificationCount(ctx context.Context, userID string) (int64, error) {
+	count, err := repository.New(pus.DB).GetUnreadNotificationCount(ctx, userID)
+	if err != nil {
+		log.Printf("GetUnreadNotificationCount: failed to count notifications: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	return count, nil
+}
+
+// SetNotificationArchived moves a notification of the user into or out of
+// the archive. returns ErrNoRecord if the user has no such notification.
+func (pus *PostgresUserService) SetNotificationArchived(ctx context.Context, userID string, notiID int32, archived bool) error {
+	n, err := repository.New(pus.DB).SetNotificationArchived(ctx, repository.SetNotificationArchivedParams{
+		Archived:        archived,
+		ID:              notiID,
+		RecipientUserID: userID,
+	})
+	if err != nil {
+		log.Printf("SetNotificationArchived: failed to update notification: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if n == 0 {
+		return internal.ErrNoRecord
+	}
+	return nil
+}
+
+// DeleteNotification deletes a notification of the user. returns ErrNoRecord
+// if the user has no such notification.
+func (pus *PostgresUserService) DeleteNotification(ctx context.Context, userID string, notiID int32) error {
+	n, err := repository.New(pus.DB).DeleteNotification(ctx, repository.DeleteNotificationParams{
+		ID:              notiID,
+		RecipientUserID: userID,
+	})
+	if err != nil {
+		log.Printf("DeleteNotification: failed to delete notification: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	if n == 0 {
+		return internal.ErrNoRecord
+	}
+	return nil
 }
 
 func (pus *PostgresUserService) UpdateNotificationStatus(ctx context.Context, userID string, notiID int32) error {
@@ -394,7 +589,7 @@ func (pus *PostgresUserService) GetAllHistory(ctx context.Context, userID string
 			Description:     "Advertisement Watched",
 			IsIncoming:      true,
 			TargetID:        a.ID,
-			Amount:          1,
+			Amount:          a.Tokens,
 			Status:          "completed",
 			Timestamp:       a.DateTime,
 		})
@@ -436,10 +631,7 @@ func (pus *PostgresUserService) UpdateOneTimePaid(ctx context.Context, userID st
 	}
 	bonus := int32(amount64)
 
-	newBalance, err := repo.MarkSignupPaidAndAward(ctx, repository.MarkSignupPaidAndAwardParams{
-		ID:           userID,
-		TokenBalance: bonus,
-	})
+	_, err = repo.MarkSignupPaid(ctx, userID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return -1, nil
@@ -447,6 +639,21 @@ func (pus *PostgresUserService) UpdateOneTimePaid(ctx context.Context, userID st
 		log.Printf("UpdateOneTimePaid: failed to marksignup %v\n", err)
 		return -1, internal.ErrInternalServerError
 	}
+	_, err = ledger.Post(ctx, repo, ledger.Entry{
+		Kind:          ledger.ENTRY_SIGNUP_BONUS,
+		Memo:          "Sign-up bonus",
+		ReferenceType: ledger.REF_USER,
+		Lines:         ledger.Move(ledger.SystemMint, ledger.UserWallet(userID), bonus),
+	})
+	if err != nil {
+		log.Printf("UpdateOneTimePaid: failed to award signup bonus %v\n", err)
+		return -1, internal.ErrInternalServerError
+	}
+	newBalance, err := repo.GetUserTokenBalance(ctx, userID)
+	if err != nil {
+		log.Printf("UpdateOneTimePaid: failed to get new balance %v\n", err)
+		return -1, internal.ErrInternalServerError
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		log.Printf("UpdateOneTimePaid: failed to commit %v\n", err)
@@ -509,3 +716,80 @@ func (pus *PostgresUserService) UpdateUserAboutMe(ctx context.Context, userID st
 	}
 	return nil
 }
+
+// GetAccountStanding returns the user's account status. A suspension whose
+// end date has passed is lifted here, so users don't have to wait for the
+// periodic sweep.
+// returns ErrNoRecord if the user has not signed up yet.
+func (pus *PostgresUserService) GetAccountStanding(ctx context.Context, userID string) (internal.AccountStanding, error) {
+	repo := repository.New(pus.DB)
+	dbStanding, err := repo.GetAccountStanding(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.AccountStanding{}, internal.ErrNoRecord
+		}
+		log.Printf("GetAccountStanding: failed to get account status: %s\n", err)
+		return internal.AccountStanding{}, internal.ErrInternalServerError
+	}
+	standing := internal.AccountStanding{
+		Status:         dbStanding.Status,
+		SuspendedUntil: dbStanding.SuspendedUntil.Time,
+	}
+	if standing.Status == repository.AccountStatusSuspended && dbStanding.SuspendedUntil.Valid && !time.Now().Before(standing.SuspendedUntil) {
+		if _, err = repo.LiftExpiredSuspensions(ctx); err != nil {
+			log.Printf("GetAccountStanding: failed to lift expired suspensions: %s\n", err)
+			return internal.AccountStanding{}, internal.ErrInternalServerError
+		}
+		standing = internal.AccountStanding{Status: repository.AccountStatusActive}
+	}
+	return standing, nil
+}
+
+// LiftExpiredSuspensions reactivates every account whose suspension has run
+// out and returns how many were lifted.
+func (pus *PostgresUserService) LiftExpiredSuspensions(ctx context.Context) (int64, error) {
+	repo := repository.New(pus.DB)
+	n, err := repo.LiftExpiredSuspensions(ctx)
+	if err != nil {
+		log.Printf("LiftExpiredSuspensions: failed to lift suspensions: %s\n", err)
+		return 0, internal.ErrInternalServerError
+	}
+	return n, nil
+}
+
+// GetLocation returns the user's location.
+// returns ErrNoRecord if the user has none.
+func (pus *PostgresUserService) GetLocation(ctx context.Context, userID string) (Location, error) {
+	repo := repository.New(pus.DB)
+	dbLocation, err := repo.GetUserLocation(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Location{}, internal.ErrNoRecord
+		}
+		log.Printf("GetLocation: failed to get location: %s\n", err)
+		return Location{}, internal.ErrInternalServerError
+	}
+	return Location{
+		Point:           geo.Point{Latitude: dbLocation.Latitude, Longitude: dbLocation.Longitude},
+		ServiceRadiusKm: dbLocation.ServiceRadiusKm.Float64,
+		Source:          dbLocation.Source,
+	}, nil
+}
+
+// SetLocation pins the user's location by hand, which stops later address
+// changes from moving it.
+func (pus *PostgresUserService) SetLocation(ctx context.Context, userID string, loc Location) error {
+	repo := repository.New(pus.DB)
+	err := repo.UpsertUserLocation(ctx, repository.UpsertUserLocationParams{
+		UserID:          userID,
+		Latitude:        loc.Latitude,
+		Longitude:       loc.Longitude,
+		ServiceRadiusKm: pgtype.Float8{Float64: loc.ServiceRadiusKm, Valid: loc.ServiceRadiusKm > 0},
+		Source:          LOCATION_MANUAL,
+	})
+	if err != nil {
+		log.Printf("SetLocation: failed to save location: %s\n", err)
+		return internal.ErrInternalServerError
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/geo"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+	"github.com/set-kaung/senior_project_1/internal/repository/repotest"
+)
+
+func TestLocateUser(t *testing.T) {
+	geocoder := geo.StaticGeocoder{
+		"10110,thailand":      {Latitude: 13.7367, Longitude: 100.5232},
+		"chiang mai,thailand": {Latitude: 18.7883, Longitude: 98.9853},
+	}
+	tests := []struct {
+		name string
+		user User
+		want *geo.Point
+	}{
+		{"postal code", User{ID: "u1", City: "Bangkok", ZipPostalCode: "10110", Country: "Thailand"}, &geo.Point{Latitude: 13.7367, Longitude: 100.5232}},
+		{"city", User{ID: "u2", City: " Chiang Mai ", ZipPostalCode: "99999", Country: "THAILAND"}, &geo.Point{Latitude: 18.7883, Longitude: 98.9853}},
+		{"unknown", User{ID: "u3", City: "Atlantis", Country: "Thailand"}, nil},
+		{"no address", User{ID: "u4"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repotest.New()
+			locateUser(context.Background(), geocoder, repository.New(db), tt.user)
+
+			calls := db.Calls("UpsertUserLocation")
+			if tt.want == nil {
+				if len(calls) != 0 {
+					t.Fatalf("saved a location for an address that could not be geocoded: %v", calls)
+				}
+				return
+			}
+			if len(calls) != 1 {
+				t.Fatalf("UpsertUserLocation called %d times, want 1", len(calls))
+			}
+			args := calls[0].Args
+			if args[0] != tt.user.ID || args[1] != tt.want.Latitude || args[2] != tt.want.Longitude || args[4] != LOCATION_GEOCODED {
+				t.Errorf("UpsertUserLocation args = %v, want %s at %v from %q", args, tt.user.ID, *tt.want, LOCATION_GEOCODED)
+			}
+		})
+	}
+}
+
+func TestInsertAdsHistory(t *testing.T) {
+	policy := AdPolicy{DailyLimit: 3, Window: AD_WINDOW_ROLLING, Reward: 1}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		watched int64
+		wantErr error
+	}{
+		{"first ad", 0, nil},
+		{"last ad under the limit", 2, nil},
+		{"at the limit", 3, internal.ErrAdLimitReached},
+		{"past the limit", 4, internal.ErrAdLimitReached},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repotest.New()
+			db.Returns("LockUserBalance", []any{int32(0)})
+			db.Returns("GetAdsWatched", []any{tt.watched})
+			db.Returns("InsertAdsHistory", []any{int32(9)})
+			db.Returns("InsertLedgerAccount", []any{int32(1)})
+			db.Returns("GetUserTokenBalance", []any{int32(0)})
+			db.Returns("CreditUserBalance", []any{int32(1)})
+			db.Returns("InsertJournalEntry", []any{int64(42)})
+
+			err := insertAdsHistory(context.Background(), repository.New(db), policy, now, "u1", "", 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("insertAdsHistory() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// the lock must be held before counting, or two claims could
+			// both see the last ad left under the limit
+			calls := db.Calls("")
+			if len(calls) < 2 || calls[0].Query != "LockUserBalance" || calls[1].Query != "GetAdsWatched" {
+				t.Fatalf("calls = %v, want LockUserBalance then GetAdsWatched", calls)
+			}
+			if since := calls[1].Args[1]; since != now.Add(-24*time.Hour) {
+				t.Errorf("counted ads since %v, want the last 24 hours", since)
+			}
+			inserts := db.Calls("InsertAdsHistory")
+			if tt.wantErr != nil {
+				if len(inserts) != 0 || len(db.Calls("CreditUserBalance")) != 0 {
+					t.Errorf("rewarded an ad over the limit")
+				}
+				return
+			}
+			if len(inserts) != 1 {
+				t.Errorf("InsertAdsHistory called %d times, want 1", len(inserts))
+			}
+		})
+	}
+
+	t.Run("no user", func(t *testing.T) {
+		db := repotest.New()
+		err := insertAdsHistory(context.Background(), repository.New(db), policy, now, "u1", "", 1)
+		if !errors.Is(err, internal.ErrNoRecord) {
+			t.Fatalf("insertAdsHistory() error = %v, want ErrNoRecord", err)
+		}
+		if calls := db.Calls("GetAdsWatched"); len(calls) != 0 {
+			t.Errorf("counted ads without holding the lock")
+		}
+	})
+}
+
+func TestAdPolicyWindowStart(t *testing.T) {
+	bangkok, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 01:30 on the 19th in Bangkok is still the 18th in UTC
+	now := time.Date(2026, 10, 18, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		policy AdPolicy
+		want   time.Time
+	}{
+		{"rolling", AdPolicy{Window: AD_WINDOW_ROLLING, Location: bangkok}, time.Date(2026, 10, 17, 18, 30, 0, 0, time.UTC)},
+		{"calendar in Bangkok", AdPolicy{Window: AD_WINDOW_CALENDAR, Location: bangkok}, time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC)},
+		{"calendar in UTC", AdPolicy{Window: AD_WINDOW_CALENDAR, Location: time.UTC}, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"calendar without a time zone", AdPolicy{Window: AD_WINDOW_CALENDAR}, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.WindowStart(now); !got.Equal(tt.want) {
+				t.Errorf("WindowStart() = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAdPolicyRewardFor(t *testing.T) {
+	policy := AdPolicy{Reward: 1, Rewards: map[string]int32{"rewarded-video": 3}}
+	tests := []struct {
+		adUnit  string
+		want    int32
+		wantErr error
+	}{
+		{"", 1, nil},
+		{"rewarded-video", 3, nil},
+		{"banner", 0, internal.ErrUnknownAdUnit},
+	}
+	for _, tt := range tests {
+		got, err := policy.RewardFor(tt.adUnit)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("RewardFor(%q) = %d, %v, want %d, %v", tt.adUnit, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if _, err := DEFAULT_AD_POLICY.RewardFor("banner"); !errors.Is(err, internal.ErrUnknownAdUnit) {
+		t.Errorf("a policy without ad units accepted one, error = %v", err)
+	}
+}
@@ -1,6 +1,16 @@
 package user
 
-import "time"
+import (
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal/geo"
+)
+
+// Location sources. A manual location is never overwritten by geocoding.
+const (
+	LOCATION_GEOCODED = "geocoded"
+	LOCATION_MANUAL   = "manual"
+)
 
 type User struct {
 	ID               string    `json:"id"`
@@ -21,18 +31,42 @@ type User struct {
 	IsPaid           bool      `json:"is_paid"`
 	Rating           float32   `json:"rating"`
 	AboutMe          string    `json:"about_me"`
+	SuspendedUntil   time.Time `json:"suspended_until,omitzero"`
 }
 
 type Notification struct {
-	ID              int32     `json:"id"`
-	Message         string    `json:"message"`
-	RecipientUserID string    `json:"recipient_user_id"`
-	ActionUserID    string    `json:"action_user_id"`
-	IsRead          bool      `json:"is_read"`
-	EventID         int64     `json:"event_id"`
-	CreatedAt       time.Time `json:"created_at"`
-	EventType       string    `json:"event_type"`
-	EventTargetID   int32     `json:"event_target_id"`
+	ID               int32     `json:"id"`
+	Message          string    `json:"message"`
+	RecipientUserID  string    `json:"recipient_user_id"`
+	ActionUserID     string    `json:"action_user_id"`
+	IsRead           bool      `json:"is_read"`
+	EventID          int64     `json:"event_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	EventType        string    `json:"event_type"`
+	EventDescription string    `json:"event_description"`
+	EventTargetID    int32     `json:"event_target_id"`
+	ArchivedAt       time.Time `json:"archived_at,omitzero"`
+}
+
+const (
+	DEFAULT_NOTIFICATION_PAGE_SIZE = 20
+	MAX_NOTIFICATION_PAGE_SIZE     = 100
+)
+
+// NotificationQuery filters and pages a user's notifications. Archived
+// selects the archived notifications instead of the inbox.
+type NotificationQuery struct {
+	UnreadOnly bool
+	EventType  string
+	Archived   bool
+	Cursor     string
+	Limit      int32
+}
+
+// NotificationPage is a page of notifications, newest event first.
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
 }
 
 type InteractionHistory struct {
@@ -59,3 +93,11 @@ type UserSummary struct {
 	User     `json:"user"`
 	Listings []PartialListing `json:"active_listings"`
 }
+
+// Location is where a user is based and how far they travel to provide a
+// service. New listings start out with their provider's location.
+type Location struct {
+	geo.Point
+	ServiceRadiusKm float64 `json:"service_radius_km,omitempty"`
+	Source          string  `json:"source,omitempty"`
+}
@@ -3,10 +3,10 @@ package user
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +14,7 @@ import (
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/clerk/clerk-sdk-go/v2/user"
 	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/domain"
 	"github.com/set-kaung/senior_project_1/internal/helpers"
 )
 
@@ -100,6 +101,15 @@ func (h *UserHandler) HandleUpdateUserProfile(w http.ResponseWriter, r *http.Req
 
 func (uh *UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	// checked before the clerk account goes, since that cannot be undone
+	if err := uh.UserService.EnsureDeletable(r.Context(), userID); err != nil {
+		if errors.Is(err, internal.ErrEscrowFrozen) {
+			helpers.WriteError(w, http.StatusConflict, "account cannot be deleted while a dispute is open", nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
 	deletedResource, err := user.Delete(r.Context(), userID)
 	if err != nil {
 		log.Printf("UserHandler -> HandleDeleteUser: error deleting clerk user: %v", err)
@@ -117,40 +127,146 @@ func (uh *UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request)
 
 func (uh *UserHandler) HandleAdWatched(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
-	err := uh.UserService.InsertAdsHistory(r.Context(), userID)
+	// the body is optional, ads without an ad unit pay the default reward
+	var data struct {
+		AdUnit string `json:"ad_unit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("user_handler -> HandleAdWatched: %s\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	tokens, err := uh.UserService.InsertAdsHistory(r.Context(), userID, strings.TrimSpace(data.AdUnit))
 	if err != nil {
-		helpers.WriteServerError(w, nil)
+		switch {
+		case errors.Is(err, internal.ErrAdLimitReached):
+			helpers.WriteError(w, http.StatusTooManyRequests, err.Error(), nil)
+		case errors.Is(err, internal.ErrUnknownAdUnit):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, internal.ErrNoRecord):
+			helpers.WriteError(w, http.StatusNotFound, "user not found", nil)
+		default:
+			helpers.WriteServerError(w, nil)
+		}
 		return
 	}
-	helpers.WriteSuccess(w, http.StatusCreated, "ad watched", nil)
+	helpers.WriteData(w, http.StatusCreated, map[string]int32{"tokens": tokens}, nil)
 }
 
 func (uh *UserHandler) HandleGetAdsWatched(w http.ResponseWriter, r *http.Request) {
-	type AdsStatus struct {
-		Count     int64 `json:"count"`
-		IsAtLimit bool  `json:"is_at_limit"`
-	}
 	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
-	count, err := uh.UserService.GetAdsHistory(r.Context(), userID)
+	status, err := uh.UserService.GetAdsStatus(r.Context(), userID)
 	if err != nil {
 		helpers.WriteServerError(w, nil)
 		return
 	}
-	helpers.WriteData(w, http.StatusOK, AdsStatus{
-		Count:     count,
-		IsAtLimit: fmt.Sprintf("%d", count) == os.Getenv("DAILY_ADS_LIMIT"),
-	}, nil)
+	helpers.WriteData(w, http.StatusOK, status, nil)
 }
 
 func (uh *UserHandler) GetUserNotifications(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
-	notifications, err := uh.UserService.GetNotifications(r.Context(), userID)
+	query, err := parseNotificationQuery(r.URL.Query())
 	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	page, err := uh.UserService.GetNotifications(r.Context(), userID, query)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidCursor) {
+			helpers.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
 		helpers.WriteError(w, http.StatusInternalServerError, err.Error(), nil)
 		return
 	}
 
-	helpers.WriteData(w, http.StatusOK, notifications, nil)
+	helpers.WriteData(w, http.StatusOK, page, nil)
+}
+
+// parseNotificationQuery reads the unread, event_type, archived, cursor and
+// limit parameters of GET /notifications.
+func parseNotificationQuery(v url.Values) (NotificationQuery, error) {
+	q := NotificationQuery{Cursor: v.Get("cursor")}
+	var err error
+	if s := v.Get("unread"); s != "" {
+		if q.UnreadOnly, err = strconv.ParseBool(s); err != nil {
+			return q, errors.New("unread must be true or false")
+		}
+	}
+	if s := v.Get("archived"); s != "" {
+		if q.Archived, err = strconv.ParseBool(s); err != nil {
+			return q, errors.New("archived must be true or false")
+		}
+	}
+	switch q.EventType = v.Get("event_type"); q.EventType {
+	case "", domain.REQUEST_EVENT, domain.REVIEW_EVENT, domain.LISTING_EVENT:
+	default:
+		return q, errors.New("event_type must be request, review or listing")
+	}
+	if s := v.Get("limit"); s != "" {
+		limit, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || limit < 0 {
+			return q, errors.New("limit must be a non-negative integer")
+		}
+		q.Limit = int32(limit)
+	}
+	return q, nil
+}
+
+func (uh *UserHandler) HandleGetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	count, err := uh.UserService.GetUnreadNotificationCount(r.Context(), userID)
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, map[string]int64{"unread": count}, nil)
+}
+
+func (uh *UserHandler) HandleArchiveNotification(w http.ResponseWriter, r *http.Request) {
+	uh.setNotificationArchived(w, r, true)
+}
+
+func (uh *UserHandler) HandleUnarchiveNotification(w http.ResponseWriter, r *http.Request) {
+	uh.setNotificationArchived(w, r, false)
+}
+
+func (uh *UserHandler) setNotificationArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	notiID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid notification id", nil)
+		return
+	}
+	err = uh.UserService.SetNotificationArchived(r.Context(), userID, int32(notiID), archived)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) {
+			helpers.WriteError(w, http.StatusNotFound, "notification not found", nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "", nil)
+}
+
+func (uh *UserHandler) HandleDeleteNotification(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	notiID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, "invalid notification id", nil)
+		return
+	}
+	err = uh.UserService.DeleteNotification(r.Context(), userID, int32(notiID))
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) {
+			helpers.WriteError(w, http.StatusNotFound, "notification not found", nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "", nil)
 }
 
 func (uh *UserHandler) HandleUpdateNotificationStatus(w http.ResponseWriter, r *http.Request) {
@@ -257,3 +373,40 @@ func (h *UserHandler) HandleUpdateAboutMe(w http.ResponseWriter, r *http.Request
 		helpers.WriteServerError(w, nil)
 	}
 }
+
+func (h *UserHandler) HandleGetLocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	loc, err := h.UserService.GetLocation(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoRecord) {
+			helpers.WriteError(w, http.StatusNotFound, "no location on file", nil)
+			return
+		}
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, loc, nil)
+}
+
+func (h *UserHandler) HandleSetLocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	loc := Location{}
+	if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
+		log.Printf("user_handler -> HandleSetLocation: %s\n", err)
+		helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+		return
+	}
+	if !loc.Valid() {
+		helpers.WriteError(w, http.StatusBadRequest, "latitude must be within -90 and 90, longitude within -180 and 180", nil)
+		return
+	}
+	if loc.ServiceRadiusKm < 0 {
+		helpers.WriteError(w, http.StatusBadRequest, "service_radius_km cannot be negative", nil)
+		return
+	}
+	if err := h.UserService.SetLocation(r.Context(), userID, loc); err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteSuccess(w, http.StatusOK, "location updated", nil)
+}
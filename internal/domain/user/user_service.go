@@ -3,6 +3,8 @@ package user
 import (
 	"context"
 	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
 )
 
 type UserService interface {
@@ -10,9 +12,13 @@ type UserService interface {
 	InsertUser(context.Context, User) error
 	UpdateUser(context.Context, User) error
 	DeleteUser(context.Context, string) error
-	InsertAdsHistory(context.Context, string) error
-	GetAdsHistory(context.Context, string) (int64, error)
-	GetNotifications(context.Context, string) ([]Notification, error)
+	EnsureDeletable(ctx context.Context, userID string) error
+	InsertAdsHistory(ctx context.Context, userID string, adUnit string) (int32, error)
+	GetAdsStatus(ctx context.Context, userID string) (AdsStatus, error)
+	GetNotifications(ctx context.Context, userID string, q NotificationQuery) (NotificationPage, error)
+	GetUnreadNotificationCount(ctx context.Context, userID string) (int64, error)
+	SetNotificationArchived(ctx context.Context, userID string, notiID int32, archived bool) error
+	DeleteNotification(ctx context.Context, userID string, notiID int32) error
 	UpdateNotificationStatus(context.Context, string, int32) error
 	UpdateFullName(ctx context.Context, newName string, userID string) error
 	MarkAllNotificationsRead(context.Context, string, time.Time) error
@@ -20,4 +26,8 @@ type UserService interface {
 	UpdateOneTimePaid(ctx context.Context, userID string) (int32, error)
 	GetUserDetailAndServices(ctx context.Context, userID string) (UserSummary, error)
 	UpdateUserAboutMe(ctx context.Context, userID string, aboutMe string) error
+	GetAccountStanding(ctx context.Context, userID string) (internal.AccountStanding, error)
+	LiftExpiredSuspensions(ctx context.Context) (int64, error)
+	GetLocation(ctx context.Context, userID string) (Location, error)
+	SetLocation(ctx context.Context, userID string, loc Location) error
 }
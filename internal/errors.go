@@ -12,4 +12,23 @@ var (
 	ErrInsufficientBalance = errors.New("not enough tokens")
 	ErrUnauthorized        = errors.New("unauthorized")
 	ErrMismatchAmount      = errors.New("invalid reward amount")
+	ErrUnbalancedEntry     = errors.New("journal entry does not balance")
+	ErrInvalidTransition   = errors.New("invalid request status transition")
+	ErrInvalidShare        = errors.New("split share must leave tokens for both parties")
+	ErrEscrowFrozen        = errors.New("escrow is frozen while a dispute is open")
+	ErrAccountBanned       = errors.New("account is banned")
+	ErrAccountSuspended    = errors.New("account is suspended, only read access is allowed")
+	ErrNothingToAppeal     = errors.New("listing has no warning or takedown to appeal")
+	ErrWarningDowngrade    = errors.New("listing was taken down, a milder warning cannot replace its severe one")
+	ErrInvalidCursor       = errors.New("invalid pagination cursor")
+	ErrInvalidCategory     = errors.New("unknown category")
+	ErrNoLocation          = errors.New("no location on file, set one or pass lat and lng")
+	ErrSlotRequired        = errors.New("this provider takes bookings, pick a slot")
+	ErrSlotUnavailable     = errors.New("slot is outside the provider's availability")
+	ErrSlotTaken           = errors.New("slot is already booked")
+	ErrThreadClosed        = errors.New("conversation is closed, the request is no longer active")
+	ErrIdempotencyKeyReuse = errors.New("Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInUse = errors.New("a request with this Idempotency-Key is still being processed")
+	ErrAdLimitReached      = errors.New("daily ad limit reached")
+	ErrUnknownAdUnit       = errors.New("unknown ad unit")
 )
@@ -0,0 +1,76 @@
+// Package geo holds coordinates, distances and the geocoders that turn
+// user addresses into coordinates.
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+)
+
+var ErrAddressNotFound = errors.New("address could not be geocoded")
+
+const earthRadiusKm = 6371
+
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Valid reports whether p lies within the latitude and longitude ranges.
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// DistanceKm is the great-circle distance between a and b. It uses the same
+// haversine formula as the listing queries so the two always agree,
+// including clamping h, which rounding can push just past 1 for points on
+// opposite sides of the earth.
+func DistanceKm(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := radians(b.Latitude - a.Latitude)
+	dLng := radians(b.Longitude - a.Longitude)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return earthRadiusKm * 2 * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+type Address struct {
+	Line1         string
+	City          string
+	StateProvince string
+	ZipPostalCode string
+	Country       string
+}
+
+// Geocoder resolves an address to coordinates. It returns ErrAddressNotFound
+// when the address is unknown to it.
+type Geocoder interface {
+	Geocode(ctx context.Context, addr Address) (Point, error)
+}
+
+// StaticGeocoder answers from a fixed table and never leaves the process,
+// for tests and for deployments without a geocoding service. Keys are
+// "<zip>,<country>" or "<city>,<country>", matched case-insensitively;
+// the postal code is tried first.
+type StaticGeocoder map[string]Point
+
+func (sg StaticGeocoder) Geocode(ctx context.Context, addr Address) (Point, error) {
+	for _, key := range []string{addr.ZipPostalCode, addr.City} {
+		if key == "" {
+			continue
+		}
+		if p, ok := sg[staticKey(key, addr.Country)]; ok {
+			return p, nil
+		}
+	}
+	return Point{}, ErrAddressNotFound
+}
+
+func staticKey(place, country string) string {
+	return strings.ToLower(strings.TrimSpace(place)) + "," + strings.ToLower(strings.TrimSpace(country))
+}
@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64
+	}{
+		{"same point", Point{13.7367, 100.5232}, Point{13.7367, 100.5232}, 0},
+		{"one degree of latitude", Point{0, 0}, Point{1, 0}, 111.19},
+		{"across the date line", Point{0, 179.5}, Point{0, -179.5}, 111.19},
+		{"antipodes", Point{13.7367, 100.5232}, Point{-13.7367, -79.4768}, math.Pi * earthRadiusKm},
+		{"poles", Point{90, 0}, Point{-90, 0}, math.Pi * earthRadiusKm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceKm(tt.a, tt.b)
+			if math.IsNaN(got) || math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("DistanceKm(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticGeocoder(t *testing.T) {
+	sg := StaticGeocoder{
+		"10110,thailand":   {13.7367, 100.5232},
+		"bangkok,thailand": {13.7563, 100.5018},
+	}
+	tests := []struct {
+		name    string
+		addr    Address
+		want    Point
+		wantErr error
+	}{
+		{"postal code first", Address{City: "Bangkok", ZipPostalCode: "10110", Country: "Thailand"}, Point{13.7367, 100.5232}, nil},
+		{"falls back to city", Address{City: " BANGKOK", ZipPostalCode: "10999", Country: "thailand "}, Point{13.7563, 100.5018}, nil},
+		{"other country", Address{City: "Bangkok", Country: "Laos"}, Point{}, ErrAddressNotFound},
+		{"empty", Address{}, Point{}, ErrAddressNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sg.Geocode(context.Background(), tt.addr)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Geocode(%+v) = %v, %v, want %v, %v", tt.addr, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// NominatimGeocoder looks addresses up with a Nominatim server such as
+// https://nominatim.openstreetmap.org. The public server requires an
+// identifying UserAgent and allows about one request per second.
+type NominatimGeocoder struct {
+	BaseURL   string
+	UserAgent string
+	Client    *http.Client
+}
+
+func (ng *NominatimGeocoder) Geocode(ctx context.Context, addr Address) (Point, error) {
+	params := url.Values{}
+	params.Set("format", "jsonv2")
+	params.Set("limit", "1")
+	for key, value := range map[string]string{
+		"street":     addr.Line1,
+		"city":       addr.City,
+		"state":      addr.StateProvince,
+		"postalcode": addr.ZipPostalCode,
+		"country":    addr.Country,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ng.BaseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return Point{}, err
+	}
+	req.Header.Set("User-Agent", ng.UserAgent)
+
+	client := ng.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Point{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Point{}, fmt.Errorf("nominatim: unexpected status %s", resp.Status)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return Point{}, fmt.Errorf("nominatim: %w", err)
+	}
+	if len(results) == 0 {
+		return Point{}, ErrAddressNotFound
+	}
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("nominatim: %w", err)
+	}
+	lng, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("nominatim: %w", err)
+	}
+	return Point{Latitude: lat, Longitude: lng}, nil
+}
@@ -0,0 +1,106 @@
+// Package ical writes iCalendar (RFC 5545) calendars of timed events.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const PRODID = "-//Ontime//Service Requests//EN"
+
+// CONTENT_TYPE is the media type to serve calendars with.
+const CONTENT_TYPE = "text/calendar; charset=utf-8"
+
+// Event statuses.
+const (
+	STATUS_CONFIRMED = "CONFIRMED"
+	STATUS_TENTATIVE = "TENTATIVE"
+)
+
+type Event struct {
+	// UID identifies the event across feed refreshes so calendars update it
+	// in place instead of adding a copy.
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Status      string
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// lineLimit is the longest content line in octets, excluding the CRLF.
+const lineLimit = 75
+
+// Encode writes c with times in UTC. stamp is the DTSTAMP of every event,
+// normally the time the calendar was generated.
+func (c Calendar) Encode(w io.Writer, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", PRODID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(e.UID))
+		line("DTSTAMP", formatTime(stamp))
+		line("DTSTART", formatTime(e.Start))
+		line("DTEND", formatTime(e.End))
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Status != "" {
+			line("STATUS", e.Status)
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape quotes a TEXT value. Every kind of line break becomes \n, since a
+// raw one would end the content line.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// writeFolded writes s as one content line, folding it onto continuation
+// lines that start with a space so no line exceeds lineLimit octets. Folds
+// never split a UTF-8 sequence.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := lineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// the leading space counts towards the continuation line
+		limit = lineLimit - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,128 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Guitar lessons", "Guitar lessons"},
+		{"Tutoring; math, physics", `Tutoring\; math\, physics`},
+		{`C:\path`, `C:\\path`},
+		{"line one\nline two", `line one\nline two`},
+		{"line one\r\nline two", `line one\nline two`},
+		{"line one\rline two", `line one\nline two`},
+		{`already \n escaped`, `already \\n escaped`},
+		{"ภาษาไทย: 10:00", "ภาษาไทย: 10:00"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantLines int
+	}{
+		{"short", "SUMMARY:Guitar lessons", 1},
+		{"exactly the limit", "SUMMARY:" + strings.Repeat("a", lineLimit-len("SUMMARY:")), 1},
+		{"one over the limit", "SUMMARY:" + strings.Repeat("a", lineLimit-len("SUMMARY:")+1), 2},
+		{"continuation lines count their space", strings.Repeat("a", lineLimit+lineLimit-1+1), 3},
+		// three-octet runes: the fold would land inside one at any offset
+		{"thai", "SUMMARY:" + strings.Repeat("ภาษา", 30), 6},
+		{"thai shifted by one", "SUMMARY:x" + strings.Repeat("ภาษา", 30), 6},
+		{"thai shifted by two", "SUMMARY:xy" + strings.Repeat("ภาษา", 30), 6},
+		// four-octet runes
+		{"emoji", "DESCRIPTION:" + strings.Repeat("🎸", 40), 3},
+		{"mixed", "DESCRIPTION:" + strings.Repeat("é🎸a", 25), 3},
+		{"empty", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeFolded(w, tt.line)
+			w.Flush()
+
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end with CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(lines) != tt.wantLines {
+				t.Errorf("folded into %d lines, want %d", len(lines), tt.wantLines)
+			}
+			var unfolded strings.Builder
+			for i, l := range lines {
+				if len(l) > lineLimit {
+					t.Errorf("line %d is %d octets, over the %d limit", i, len(l), lineLimit)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d %q splits a UTF-8 sequence", i, l)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Errorf("continuation line %d %q does not start with a space", i, l)
+					}
+					l = l[1:]
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolds to %q, want %q", unfolded.String(), tt.line)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.FixedZone("ICT", 7*60*60))
+	c := Calendar{
+		Name: "Ontime, requests",
+		Events: []Event{{
+			UID:         "request-4@ontime",
+			Start:       start,
+			End:         start.Add(time.Hour),
+			Summary:     "Guitar lessons",
+			Description: "Bring a capo;\nno amp needed",
+			Status:      STATUS_CONFIRMED,
+		}},
+	}
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + PRODID,
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		`X-WR-CALNAME:Ontime\, requests`,
+		"BEGIN:VEVENT",
+		"UID:request-4@ontime",
+		"DTSTAMP:20261018T120000Z",
+		"DTSTART:20261019T020000Z",
+		"DTEND:20261019T030000Z",
+		"SUMMARY:Guitar lessons",
+		`DESCRIPTION:Bring a capo\;\nno amp needed`,
+		"STATUS:CONFIRMED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode() =\n%s\nwant\n%s", got, want)
+	}
+}
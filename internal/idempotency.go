@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+const (
+	IDEMPOTENCY_HEADER         = "Idempotency-Key"
+	MAX_IDEMPOTENCY_KEY_LENGTH = 255
+	// IDEMPOTENCY_LOCK_TIMEOUT is how long a key stays taken by a request
+	// that has not finished. A retry after that takes the key over, since
+	// the first attempt must have died.
+	IDEMPOTENCY_LOCK_TIMEOUT = time.Minute
+	// IDEMPOTENCY_RETENTION is how long responses are kept for replay.
+	IDEMPOTENCY_RETENTION = 24 * time.Hour
+	// MAX_IDEMPOTENT_BODY caps the request bodies read for hashing.
+	MAX_IDEMPOTENT_BODY = 1 << 20
+)
+
+// IdempotentResponse is the response recorded for a key.
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore records the first response to each key of a user.
+type IdempotencyStore interface {
+	// Claim takes the key for a request with the given hash. If the key
+	// has been used already, claimed is false and the recorded response
+	// is returned. returns ErrIdempotencyKeyReuse if the key was used for a
+	// request with another hash, and ErrIdempotencyKeyInUse if the first
+	// request with the key has not finished.
+	Claim(ctx context.Context, userID, key, hash string) (res IdempotentResponse, claimed bool, err error)
+	// Complete records the response to a claimed key.
+	Complete(ctx context.Context, userID, key string, res IdempotentResponse) error
+	// Release gives up a claimed key so it can be retried.
+	Release(ctx context.Context, userID, key string) error
+}
+
+// IdempotencyMiddleware makes retries of a request with the same
+// Idempotency-Key header replay the first response instead of running the
+// handler again. Requests without the header pass through. Server errors
+// are not recorded, so a request that failed can be retried with its key.
+// It must run after AuthMiddleware, since keys are scoped to the user.
+func IdempotencyMiddleware(store IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IDEMPOTENCY_HEADER)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MAX_IDEMPOTENCY_KEY_LENGTH {
+				helpers.WriteError(w, http.StatusBadRequest, "Idempotency-Key is too long", nil)
+				return
+			}
+			userID, _ := r.Context().Value(UserIDContextKey).(string)
+			body, err := io.ReadAll(io.LimitReader(r.Body, MAX_IDEMPOTENT_BODY+1))
+			if err != nil {
+				helpers.WriteError(w, http.StatusBadRequest, "bad request", nil)
+				return
+			}
+			if len(body) > MAX_IDEMPOTENT_BODY {
+				helpers.WriteError(w, http.StatusRequestEntityTooLarge, "request body is too large", nil)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// the method and URL are part of the hash so a key cannot be
+			// reused across endpoints
+			h := sha256.New()
+			io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+			h.Write(body)
+			hash := hex.EncodeToString(h.Sum(nil))
+
+			stored, claimed, err := store.Claim(r.Context(), userID, key, hash)
+			switch {
+			case errors.Is(err, ErrIdempotencyKeyReuse):
+				helpers.WriteError(w, http.StatusUnprocessableEntity, err.Error(), nil)
+				return
+			case errors.Is(err, ErrIdempotencyKeyInUse):
+				helpers.WriteError(w, http.StatusConflict, err.Error(), nil)
+				return
+			case err != nil:
+				helpers.WriteServerError(w, nil)
+				return
+			}
+			if !claimed {
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK, body: &bytes.Buffer{}}
+			next.ServeHTTP(rec, r)
+
+			// the client may be gone by now, but the outcome still has to be
+			// recorded for its retry
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			defer cancel()
+			if rec.status >= http.StatusInternalServerError {
+				err = store.Release(ctx, userID, key)
+			} else {
+				err = store.Complete(ctx, userID, key, IdempotentResponse{
+					Status:      rec.status,
+					ContentType: rec.Header().Get("Content-Type"),
+					Body:        rec.body.Bytes(),
+				})
+			}
+			if err != nil {
+				log.Printf("IdempotencyMiddleware: failed to record response for key %q: %s\n", key, err)
+			}
+		})
+	}
+}
+
+type PostgresIdempotencyStore struct {
+	DB *pgxpool.Pool
+}
+
+func (s *PostgresIdempotencyStore) Claim(ctx context.Context, userID, key, hash string) (IdempotentResponse, bool, error) {
+	repo := repository.New(s.DB)
+	n, err := repo.ClaimIdempotencyKey(ctx, repository.ClaimIdempotencyKeyParams{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: hash,
+		StaleBefore: time.Now().Add(-IDEMPOTENCY_LOCK_TIMEOUT),
+	})
+	if err != nil {
+		log.Printf("Claim: failed to claim idempotency key: %s\n", err)
+		return IdempotentResponse{}, false, ErrInternalServerError
+	}
+	if n == 1 {
+		return IdempotentResponse{}, true, nil
+	}
+	row, err := repo.GetIdempotencyKey(ctx, repository.GetIdempotencyKeyParams{UserID: userID, Key: key})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// released by a failed first request between the two queries
+		return IdempotentResponse{}, false, ErrIdempotencyKeyInUse
+	}
+	if err != nil {
+		log.Printf("Claim: failed to get idempotency key: %s\n", err)
+		return IdempotentResponse{}, false, ErrInternalServerError
+	}
+	if row.RequestHash != hash {
+		return IdempotentResponse{}, false, ErrIdempotencyKeyReuse
+	}
+	if !row.StatusCode.Valid {
+		return IdempotentResponse{}, false, ErrIdempotencyKeyInUse
+	}
+	return IdempotentResponse{
+		Status:      int(row.StatusCode.Int32),
+		ContentType: row.ContentType.String,
+		Body:        row.ResponseBody,
+	}, false, nil
+}
+
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, userID, key string, res IdempotentResponse) error {
+	return repository.New(s.DB).CompleteIdempotencyKey(ctx, repository.CompleteIdempotencyKeyParams{
+		StatusCode:   pgtype.Int4{Int32: int32(res.Status), Valid: true},
+		ContentType:  pgtype.Text{String: res.ContentType, Valid: res.ContentType != ""},
+		ResponseBody: res.Body,
+		UserID:       userID,
+		Key:          key,
+	})
+}
+
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, userID, key string) error {
+	return repository.New(s.DB).ReleaseIdempotencyKey(ctx, repository.ReleaseIdempotencyKeyParams{UserID: userID, Key: key})
+}
+
+// Prune removes keys older than IDEMPOTENCY_RETENTION.
+func (s *PostgresIdempotencyStore) Prune(ctx context.Context) error {
+	n, err := repository.New(s.DB).PruneIdempotencyKeys(ctx, time.Now().Add(-IDEMPOTENCY_RETENTION))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("idempotency: pruned %d keys\n", n)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryStore is an IdempotencyStore that keeps keys in memory, with the
+// same rules as PostgresIdempotencyStore.
+type memoryStore struct {
+	mu       sync.Mutex
+	keys     map[string]*storedKey
+	claimErr error
+}
+
+type storedKey struct {
+	hash string
+	res  *IdempotentResponse
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{keys: map[string]*storedKey{}}
+}
+
+func (s *memoryStore) Claim(ctx context.Context, userID, key, hash string) (IdempotentResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimErr != nil {
+		return IdempotentResponse{}, false, s.claimErr
+	}
+	k, ok := s.keys[userID+"\x00"+key]
+	switch {
+	case !ok:
+		s.keys[userID+"\x00"+key] = &storedKey{hash: hash}
+		return IdempotentResponse{}, true, nil
+	case k.hash != hash:
+		return IdempotentResponse{}, false, ErrIdempotencyKeyReuse
+	case k.res == nil:
+		return IdempotentResponse{}, false, ErrIdempotencyKeyInUse
+	}
+	return *k.res, false, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, userID, key string, res IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[userID+"\x00"+key].res = &res
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, userID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, userID+"\x00"+key)
+	return nil
+}
+
+func (s *memoryStore) has(userID, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[userID+"\x00"+key]
+	return ok
+}
+
+// countingHandler answers with the statuses in turn and counts its calls.
+type countingHandler struct {
+	mu       sync.Mutex
+	calls    int
+	statuses []int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	status := h.statuses[min(h.calls, len(h.statuses)-1)]
+	h.calls++
+	n := h.calls
+	h.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"call":%d}`, n)
+}
+
+func (h *countingHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls
+}
+
+func idempotentRequest(userID, key, method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(IDEMPOTENCY_HEADER, key)
+	}
+	return r.WithContext(context.WithValue(r.Context(), UserIDContextKey, userID))
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	type step struct {
+		userID, key, method, target, body string
+		wantStatus                        int
+		wantBody                          string
+		wantReplayed                      bool
+	}
+	tests := []struct {
+		name      string
+		statuses  []int
+		steps     []step
+		wantCalls int
+	}{
+		{
+			name:     "no key",
+			statuses: []int{http.StatusCreated},
+			steps: []step{
+				{"alice", "", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":1}`, false},
+				{"alice", "", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":2}`, false},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:     "replay",
+			statuses: []int{http.StatusCreated},
+			steps: []step{
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":1}`, false},
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":1}`, true},
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":1}`, true},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:     "client errors are replayed",
+			statuses: []int{http.StatusPaymentRequired, http.StatusCreated},
+			steps: []step{
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusPaymentRequired, `{"call":1}`, false},
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusPaymentRequired, `{"call":1}`, true},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:     "different body",
+			statuses: []int{http.StatusCreated},
+			steps: []step{
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":1}`, false},
+				{"alice", "k1", "POST", "/requests", `{"listing_id":2}`, http.StatusUnprocessableEntity, "", false},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:     "different endpoint",
+			statuses: []int{http.StatusCreated},
+			steps: []step{
+				{"alice", "k1", "POST", "/requests/4/accept", "", http.StatusCreated, `{"call":1}`, false},
+				{"alice", "k1", "POST", "/requests/4/decline", "", http.StatusUnprocessableEntity, "", false},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:     "keys are per user",
+			statuses: []int{http.StatusCreated},
+			steps: []step{
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":1}`, false},
+				{"bob", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":2}`, false},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:     "released after a server error",
+			statuses: []int{http.StatusInternalServerError, http.StatusCreated},
+			steps: []step{
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusInternalServerError, `{"call":1}`, false},
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":2}`, false},
+				{"alice", "k1", "POST", "/requests", `{"listing_id":1}`, http.StatusCreated, `{"call":2}`, true},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:     "key too long",
+			statuses: []int{http.StatusCreated},
+			steps: []step{
+				{"alice", strings.Repeat("k", MAX_IDEMPOTENCY_KEY_LENGTH+1), "POST", "/requests", "", http.StatusBadRequest, "", false},
+			},
+			wantCalls: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingHandler{statuses: tt.statuses}
+			h := IdempotencyMiddleware(newMemoryStore())(next)
+			for i, s := range tt.steps {
+				rec := serve(h, idempotentRequest(s.userID, s.key, s.method, s.target, s.body))
+				if rec.Code != s.wantStatus {
+					t.Errorf("step %d: status = %d, want %d", i, rec.Code, s.wantStatus)
+				}
+				if s.wantBody != "" && rec.Body.String() != s.wantBody {
+					t.Errorf("step %d: body = %s, want %s", i, rec.Body, s.wantBody)
+				}
+				if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != s.wantReplayed {
+					t.Errorf("step %d: replayed = %v, want %v", i, replayed, s.wantReplayed)
+				}
+				if s.wantReplayed && rec.Header().Get("Content-Type") != "application/json" {
+					t.Errorf("step %d: replayed Content-Type = %q, want the original", i, rec.Header().Get("Content-Type"))
+				}
+			}
+			if got := next.count(); got != tt.wantCalls {
+				t.Errorf("handler ran %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareInFlight(t *testing.T) {
+	store := newMemoryStore()
+	started, finish := make(chan struct{}), make(chan struct{})
+	var calls int
+	h := IdempotencyMiddleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		close(started)
+		<-finish
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		first <- serve(h, idempotentRequest("alice", "k1", "POST", "/requests", `{"listing_id":1}`))
+	}()
+	<-started
+
+	rec := serve(h, idempotentRequest("alice", "k1", "POST", "/requests", `{"listing_id":1}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("retry while in flight: status = %d, want 409", rec.Code)
+	}
+	close(finish)
+	if rec := <-first; rec.Code != http.StatusCreated {
+		t.Errorf("first request: status = %d, want 201", rec.Code)
+	}
+
+	rec = serve(h, idempotentRequest("alice", "k1", "POST", "/requests", `{"listing_id":1}`))
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after it finished: status = %d replayed = %q, want a replayed 201", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareStoreFailure(t *testing.T) {
+	store := newMemoryStore()
+	store.claimErr = errors.New("connection refused")
+	next := &countingHandler{statuses: []int{http.StatusCreated}}
+	rec := serve(IdempotencyMiddleware(store)(next), idempotentRequest("alice", "k1", "POST", "/requests", ""))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if next.count() != 0 {
+		t.Error("handler ran without its key being claimed")
+	}
+	if store.has("alice", "k1") {
+		t.Error("key was taken by a request that never ran")
+	}
+}
@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+const (
+	DEFAULT_RUNS_PAGE_SIZE = 20
+	MAX_RUNS_PAGE_SIZE     = 100
+)
+
+// Run is one recorded run of a job.
+type Run struct {
+	ID         int64     `json:"id"`
+	Job        string    `json:"job"`
+	Instance   string    `json:"instance"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	// Duration is how long the run took, or has taken so far if it is
+	// still running.
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Status is a scheduled job with its latest run.
+type Status struct {
+	Name      string        `json:"name"`
+	Schedule  string        `json:"schedule"`
+	Timeout   time.Duration `json:"timeout"`
+	NextRunAt time.Time     `json:"next_run_at,omitzero"`
+	LastRun   *Run          `json:"last_run"`
+}
+
+func toRun(r repository.JobRun) Run {
+	run := Run{
+		ID:        r.ID,
+		Job:       r.Job,
+		Instance:  r.Instance,
+		Status:    string(r.Status),
+		StartedAt: r.StartedAt,
+		Error:     r.Error.String,
+	}
+	if r.FinishedAt.Valid {
+		run.FinishedAt = r.FinishedAt.Time
+		run.Duration = r.FinishedAt.Time.Sub(r.StartedAt)
+	} else {
+		run.Duration = time.Since(r.StartedAt)
+	}
+	return run
+}
+
+// Statuses lists the scheduled jobs with their latest runs. The next run of
+// a job that has never run is unknown until a leader schedules it.
+func (s *Scheduler) Statuses(ctx context.Context) ([]Status, error) {
+	repo := repository.New(s.DB)
+	latest, err := repo.GetLatestJobRuns(ctx)
+	if err != nil {
+		log.Printf("Statuses: failed to get latest runs: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	last, err := s.lastRuns(ctx)
+	if err != nil {
+		log.Printf("Statuses: failed to get settled runs: %s\n", err)
+		return nil, internal.ErrInternalServerError
+	}
+	runs := make(map[string]Run, len(latest))
+	for _, r := range latest {
+		runs[r.Job] = toRun(r)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, len(s.jobs))
+	for i, j := range s.jobs {
+		statuses[i] = Status{Name: j.Name, Schedule: j.Schedule, Timeout: j.Timeout}
+		if r, ok := runs[j.Name]; ok {
+			statuses[i].LastRun = &r
+		}
+		if anchor, ok := last[j.Name]; ok {
+			statuses[i].NextRunAt = j.schedule.Next(anchor)
+		} else if s.leader != nil {
+			statuses[i].NextRunAt = j.schedule.Next(s.electedAt)
+		}
+	}
+	return statuses, nil
+}
+
+// Runs returns the latest runs of the named job, newest first.
+// returns ErrNoRecord if no such job is scheduled.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int32) ([]Run, error) {
+	if !s.has(name) {
+		return nil, internal.ErrNoRecord
+	}
+	rows, err := repository.New(s.DB).GetJobRuns(ctx, repository.GetJobRunsParams{Job: name, Limit: limit})
+	if err != nil {
+		log.Printf("Runs: failed to get runs of %s: %s\n", name, err)
+		return nil, internal.ErrInternalServerError
+	}
+	runs := make([]Run, len(rows))
+	for i, r := range rows {
+		runs[i] = toRun(r)
+	}
+	return runs, nil
+}
+
+// Prune removes runs older than RETENTION.
+func (s *Scheduler) Prune(ctx context.Context) error {
+	n, err := repository.New(s.DB).PruneJobRuns(ctx, time.Now().Add(-RETENTION))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("jobs: pruned %d runs\n", n)
+	}
+	return nil
+}
+
+func (s *Scheduler) has(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return true
+		}
+	}
+	return false
+}
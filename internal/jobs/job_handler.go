@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+)
+
+type JobHandler struct {
+	Scheduler *Scheduler
+}
+
+// HandleGetJobs lists the scheduled jobs with their latest runs, and whether
+// the instance serving the request is the leader.
+func (jh *JobHandler) HandleGetJobs(w http.ResponseWriter, r *http.Request) {
+	statuses, err := jh.Scheduler.Statuses(r.Context())
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, map[string]any{
+		"instance": jh.Scheduler.Instance,
+		"leader":   jh.Scheduler.Leader(),
+		"jobs":     statuses,
+	}, nil)
+}
+
+func (jh *JobHandler) HandleGetJobRuns(w http.ResponseWriter, r *http.Request) {
+	limit := DEFAULT_RUNS_PAGE_SIZE
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MAX_RUNS_PAGE_SIZE {
+			helpers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", MAX_RUNS_PAGE_SIZE), nil)
+			return
+		}
+		limit = n
+	}
+	runs, err := jh.Scheduler.Runs(r.Context(), r.PathValue("name"), int32(limit))
+	if errors.Is(err, internal.ErrNoRecord) {
+		helpers.WriteError(w, http.StatusNotFound, "no such job", nil)
+		return
+	}
+	if err != nil {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	helpers.WriteData(w, http.StatusOK, runs, nil)
+}
@@ -0,0 +1,288 @@
+// Package jobs runs background jobs on one instance of the API at a time.
+// Instances elect a leader by taking a Postgres advisory lock on a dedicated
+// connection, and only the leader runs jobs. Every run is recorded in the
+// job_run table and a job's next run is scheduled from its last recorded
+// one, so restarts and leader changes neither reset nor repeat schedules.
+// Runs that were cut off by a crash are marked abandoned by the next leader
+// and run again.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/robfig/cron"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+const (
+	// LOCK_KEY is the advisory lock held by the leader.
+	LOCK_KEY int64 = 0x6f6e74696d65
+	// DEFAULT_TICK is how often instances try to become leader and the
+	// leader looks for due jobs.
+	DEFAULT_TICK = 30 * time.Second
+	// DEFAULT_TIMEOUT bounds a run when its job sets no timeout.
+	DEFAULT_TIMEOUT = 5 * time.Minute
+	// RETENTION is how long runs are kept before Prune removes them.
+	RETENTION = 30 * 24 * time.Hour
+)
+
+var ErrDuplicateJob = errors.New("a job with this name is already scheduled")
+
+// Job is a function run on a schedule.
+type Job struct {
+	Name string
+	// Schedule is a cron spec with seconds, such as "0 0 8 * * *", or a
+	// descriptor such as "@hourly" or "@every 6h".
+	Schedule string
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+
+	schedule cron.Schedule
+}
+
+// Scheduler runs its jobs while its instance is the leader.
+type Scheduler struct {
+	DB *pgxpool.Pool
+	// Instance names this process in the run history.
+	Instance string
+	Tick     time.Duration
+
+	mu      sync.Mutex
+	jobs    []*Job
+	running map[string]bool
+	// leader holds the advisory lock while this instance leads.
+	leader *pgxpool.Conn
+	// electedAt anchors the schedules of jobs that have never run.
+	electedAt time.Time
+	// runCtx is cancelled when this instance stops leading.
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	runs       sync.WaitGroup
+}
+
+// Add schedules j. returns an error if its schedule does not parse or its
+// name is taken.
+func (s *Scheduler) Add(j Job) error {
+	schedule, err := cron.Parse(j.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	j.schedule = schedule
+	if j.Timeout <= 0 {
+		j.Timeout = DEFAULT_TIMEOUT
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.jobs {
+		if existing.Name == j.Name {
+			return fmt.Errorf("job %s: %w", j.Name, ErrDuplicateJob)
+		}
+	}
+	s.jobs = append(s.jobs, &j)
+	return nil
+}
+
+// Run elects a leader and runs due jobs every Tick until ctx is cancelled,
+// then waits for running jobs to stop and gives up leadership.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.Tick <= 0 {
+		s.Tick = DEFAULT_TICK
+	}
+	ticker := time.NewTicker(s.Tick)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			s.resign()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Leader reports whether this instance currently runs the jobs.
+func (s *Scheduler) Leader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader != nil
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	if !s.Leader() {
+		if err := s.elect(ctx); err != nil {
+			log.Printf("jobs: failed to elect leader: %s\n", err)
+			return
+		}
+		if !s.Leader() {
+			return
+		}
+	}
+	if err := s.leader.Ping(ctx); err != nil {
+		log.Printf("jobs: %s lost its lock connection, stepping down: %s\n", s.Instance, err)
+		s.resign()
+		return
+	}
+	due, err := s.due(ctx, time.Now())
+	if err != nil {
+		log.Printf("jobs: failed to find due jobs: %s\n", err)
+		return
+	}
+	for _, j := range due {
+		s.start(j)
+	}
+}
+
+// elect takes the leader lock if no other instance holds it. A new leader
+// abandons the runs its predecessor left unfinished so they are run again.
+func (s *Scheduler) elect(ctx context.Context) error {
+	conn, err := s.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	ok, err := repository.New(conn).TryJobLock(ctx, LOCK_KEY)
+	if err != nil || !ok {
+		conn.Release()
+		return err
+	}
+	n, err := repository.New(s.DB).AbandonJobRuns(ctx)
+	if err != nil {
+		repository.New(conn).ReleaseJobLock(ctx, LOCK_KEY)
+		conn.Release()
+		return fmt.Errorf("abandon runs: %w", err)
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.leader = conn
+	s.electedAt = time.Now()
+	s.runCtx, s.cancelRuns = runCtx, cancel
+	s.running = map[string]bool{}
+	s.mu.Unlock()
+	log.Printf("jobs: %s is the leader, %d unfinished runs abandoned\n", s.Instance, n)
+	return nil
+}
+
+// resign cancels running jobs, waits for them to record their outcome and
+// drops the leader lock.
+func (s *Scheduler) resign() {
+	s.mu.Lock()
+	conn, cancel := s.leader, s.cancelRuns
+	s.leader, s.runCtx, s.cancelRuns = nil, nil, nil
+	s.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	cancel()
+	s.runs.Wait()
+	// closing the connection ends its session, which releases the lock even
+	// if the connection is broken
+	ctx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	conn.Conn().Close(ctx)
+	conn.Release()
+}
+
+// due returns the jobs whose next run, counted from their last settled run,
+// is not after now and that are not running already.
+func (s *Scheduler) due(ctx context.Context, now time.Time) ([]*Job, error) {
+	last, err := s.lastRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*Job
+	for _, j := range s.jobs {
+		if s.running[j.Name] {
+			continue
+		}
+		anchor, ok := last[j.Name]
+		if !ok {
+			anchor = s.electedAt
+		}
+		if !j.schedule.Next(anchor).After(now) {
+			due = append(due, j)
+		}
+	}
+	return due, nil
+}
+
+func (s *Scheduler) lastRuns(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := repository.New(s.DB).GetLastSettledJobRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	last := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		last[row.Job] = row.StartedAt
+	}
+	return last, nil
+}
+
+func (s *Scheduler) start(j *Job) {
+	s.mu.Lock()
+	if s.leader == nil || s.running[j.Name] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[j.Name] = true
+	s.runs.Add(1)
+	ctx := s.runCtx
+	s.mu.Unlock()
+	go func() {
+		defer s.runs.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, j.Name)
+			s.mu.Unlock()
+		}()
+		s.run(ctx, j)
+	}()
+}
+
+// run records a run of j and its outcome. A run whose start cannot be
+// recorded is skipped and retried on the next tick.
+func (s *Scheduler) run(parent context.Context, j *Job) {
+	repo := repository.New(s.DB)
+	id, err := repo.StartJobRun(parent, repository.StartJobRunParams{Job: j.Name, Instance: s.Instance})
+	if err != nil {
+		log.Printf("jobs: failed to record start of %s: %s\n", j.Name, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(parent, j.Timeout)
+	started := time.Now()
+	err = call(ctx, j)
+	cancel()
+
+	params := repository.FinishJobRunParams{Status: repository.JobRunStatusSucceeded, ID: id}
+	if err != nil {
+		log.Printf("jobs: %s failed after %s: %s\n", j.Name, time.Since(started).Round(time.Millisecond), err)
+		params.Status = repository.JobRunStatusFailed
+		params.Error = pgtype.Text{String: err.Error(), Valid: true}
+	}
+	// the run's context may be cancelled by now, but its outcome still
+	// has to be recorded
+	finishCtx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stop()
+	if err = repo.FinishJobRun(finishCtx, params); err != nil {
+		log.Printf("jobs: failed to record end of %s: %s\n", j.Name, err)
+	}
+}
+
+// call runs j, turning a panic into an error so it is recorded like any
+// other failure.
+func call(ctx context.Context, j *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.Run(ctx)
+}
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/user"
+)
+
+var ErrNoAddress = errors.New("user has no email address")
+
+// Directory finds a user's email address.
+type Directory interface {
+	Address(ctx context.Context, userID string) (string, error)
+}
+
+// ClerkDirectory reads the primary email address of the user's Clerk
+// account, which is where users sign up with their email.
+type ClerkDirectory struct{}
+
+func (ClerkDirectory) Address(ctx context.Context, userID string) (string, error) {
+	u, err := user.Get(ctx, userID)
+	if apiErr, ok := err.(*clerk.APIErrorResponse); ok && apiErr.HTTPStatusCode == http.StatusNotFound {
+		return "", ErrNoAddress
+	}
+	if err != nil {
+		return "", err
+	}
+	if u.PrimaryEmailAddressID == nil {
+		return "", ErrNoAddress
+	}
+	for _, a := range u.EmailAddresses {
+		if a.ID == *u.PrimaryEmailAddressID {
+			return a.EmailAddress, nil
+		}
+	}
+	return "", ErrNoAddress
+}
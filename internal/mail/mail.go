@@ -0,0 +1,112 @@
+// Package mail sends notification emails. Emails are queued through the
+// outbox as Email values naming a template and a recipient by user ID; a
+// Sender looks up the recipient's address, renders the template and hands
+// the message to a Mailer, which talks SMTP or, for local development,
+// writes it to a file or stdout.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"time"
+)
+
+// CHANNEL is the outbox channel emails are queued on.
+const CHANNEL = "email"
+
+// Message is a rendered email with a plain text and an HTML body.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends a message.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Notice is one notification as shown in an email.
+type Notice struct {
+	EventType   string    `json:"event_type"`
+	Description string    `json:"description"`
+	Message     string    `json:"message"`
+	At          time.Time `json:"at"`
+}
+
+// Email asks for Template to be sent to UserID about Notices. The event
+// type templates show a single notice; TEMPLATE_DIGEST shows them all.
+type Email struct {
+	UserID   string   `json:"user_id"`
+	Template string   `json:"template"`
+	Notices  []Notice `json:"notices"`
+}
+
+// Sender delivers queued emails.
+type Sender struct {
+	Mailer    Mailer
+	Directory Directory
+	From      string
+}
+
+// Deliver renders e and sends it to the user's address. A user without an
+// address is skipped, since retrying would not give them one.
+func (s *Sender) Deliver(ctx context.Context, e Email) error {
+	to, err := s.Directory.Address(ctx, e.UserID)
+	if errors.Is(err, ErrNoAddress) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("look up address: %w", err)
+	}
+	m, err := Render(e)
+	if err != nil {
+		return err
+	}
+	m.From, m.To = s.From, to
+	return s.Mailer.Send(ctx, m)
+}
+
+// bytes formats m as a multipart/alternative MIME message.
+func (m Message) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,158 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSMTPNotConfigured = errors.New("SMTP_HOST and MAIL_FROM must be set")
+
+// DEFAULT_SMTP_TIMEOUT bounds a whole SMTP session when the caller's
+// context allows longer.
+const DEFAULT_SMTP_TIMEOUT = 30 * time.Second
+
+// SMTPConfigured reports whether an SMTP server is set in the environment.
+func SMTPConfigured() bool {
+	return os.Getenv("SMTP_HOST") != ""
+}
+
+// SMTPMailer sends messages through an SMTP server, upgrading to TLS when the
+// server offers STARTTLS and authenticating with PLAIN when Username is set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// Timeout bounds a whole session, dial included. Zero means
+	// DEFAULT_SMTP_TIMEOUT.
+	Timeout time.Duration
+}
+
+// NewSMTP returns an SMTPMailer configured from the environment.
+// returns ErrSMTPNotConfigured if the server or sender address is missing.
+func NewSMTP() (*SMTPMailer, error) {
+	if !SMTPConfigured() || os.Getenv("MAIL_FROM") == "" {
+		return nil, ErrSMTPNotConfigured
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPMailer{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}, nil
+}
+
+// Send delivers m, giving up when ctx is done or the session outlasts
+// Timeout, so a hung server cannot hold up the caller.
+func (sm *SMTPMailer) Send(ctx context.Context, m Message) error {
+	msg, err := m.bytes()
+	if err != nil {
+		return fmt.Errorf("format message: %w", err)
+	}
+	timeout := sm.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_SMTP_TIMEOUT
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(sm.Host, sm.Port))
+	if err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return fmt.Errorf("send mail: %w", err)
+	}
+	// the deadline covers a hung server, closing the connection covers ctx
+	// being cancelled early
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err = sm.session(conn, m, msg); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w (%w)", ctx.Err(), err)
+		}
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
+
+// session runs the SMTP conversation smtp.SendMail would, on conn.
+func (sm *SMTPMailer) session(conn net.Conn, m Message, msg []byte) error {
+	c, err := smtp.NewClient(conn, sm.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: sm.Host}); err != nil {
+			return err
+		}
+	}
+	if sm.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("server does not support AUTH")
+		}
+		if err = c.Auth(smtp.PlainAuth("", sm.Username, sm.Password, sm.Host)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(m.From); err != nil {
+		return err
+	}
+	if err = c.Rcpt(m.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// FileMailer writes each message to its own .eml file in Dir, or to stdout
+// when Dir is empty. It is meant for local development.
+type FileMailer struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+func (fm *FileMailer) Send(ctx context.Context, m Message) error {
+	msg, err := m.bytes()
+	if err != nil {
+		return fmt.Errorf("format message: %w", err)
+	}
+	if fm.Dir == "" {
+		fm.mu.Lock()
+		defer fm.mu.Unlock()
+		_, err = io.WriteString(os.Stdout, "----- mail -----\n"+string(msg)+"\n")
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(m.To))
+	return os.WriteFile(filepath.Join(fm.Dir, name), msg, 0o644)
+}
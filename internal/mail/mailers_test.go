@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hungServer accepts connections and never says anything, like an SMTP
+// server that stopped responding.
+func hungServer(t *testing.T) (host, port string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+func TestSMTPMailerSendHungServer(t *testing.T) {
+	host, port := hungServer(t)
+	msg := Message{From: "no-reply@localhost", To: "user@localhost", Subject: "hi", Text: "hi"}
+
+	t.Run("timeout", func(t *testing.T) {
+		sm := &SMTPMailer{Host: host, Port: port, Timeout: 100 * time.Millisecond}
+		start := time.Now()
+		err := sm.Send(context.Background(), msg)
+		if err == nil {
+			t.Fatal("Send succeeded against a silent server")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Send took %s, want about the 100ms timeout", elapsed)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		sm := &SMTPMailer{Host: host, Port: port, Timeout: time.Minute}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		err := sm.Send(ctx, msg)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Send() = %v, want context.Canceled", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Send took %s after cancel", elapsed)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"text/template"
+
+	"github.com/set-kaung/senior_project_1/internal/domain"
+)
+
+// TEMPLATE_DIGEST lists every notice of an Email. The other templates are
+// named after the event type they announce.
+const TEMPLATE_DIGEST = "digest"
+
+//go:embed templates
+var templateFS embed.FS
+
+// HEADLINES are the human-readable titles of event descriptions.
+var HEADLINES = map[string]string{
+	domain.INITIATE_REQUEST:   "New service request",
+	domain.ACCEPT_REQUEST:     "Request accepted",
+	domain.DECLINE_REQUEST:    "Request declined",
+	domain.CONFIRM_COMPLETION: "Completion confirmed",
+	domain.REQUEST_EXPIRED:    "Request expired",
+	domain.CANCELLED_REQUEST:  "Request cancelled",
+	domain.DISPUTE_OPENED:     "Dispute opened",
+	domain.DISPUTE_RESPONDED:  "Dispute response",
+	domain.DISPUTE_RESOLVED:   "Dispute resolved",
+	domain.SESSION_REMINDER:   "Upcoming session",
+	domain.PENDING_REMINDER:   "Request awaiting your reply",
+	domain.AUTO_COMPLETED:     "Request completed",
+	domain.USER_DO_NOT_EXIST:  "Tokens refunded",
+	domain.REVIEWED_REQUEST:   "New review",
+	domain.WARNING_ISSUED:     "Warning issued",
+	domain.LISTING_HIDDEN:     "Listing hidden",
+	domain.LISTING_REMOVED:    "Listing removed",
+	domain.LISTING_RESTORED:   "Listing restored",
+	domain.APPEAL_DECIDED:     "Appeal decided",
+}
+
+func headline(description string) string {
+	if h, ok := HEADLINES[description]; ok {
+		return h
+	}
+	return "Notification"
+}
+
+type templates struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+var registry = map[string]templates{}
+
+func init() {
+	funcs := map[string]any{"headline": headline}
+	for _, name := range []string{domain.REQUEST_EVENT, domain.REVIEW_EVENT, domain.LISTING_EVENT, TEMPLATE_DIGEST} {
+		registry[name] = templates{
+			text: template.Must(template.New(name+".txt").Funcs(funcs).ParseFS(templateFS, "templates/"+name+".txt")),
+			html: htmltemplate.Must(htmltemplate.New(name+".html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")),
+		}
+	}
+}
+
+// Render fills in the subject and bodies of e's template. The text
+// template defines the subject as its "subject" block.
+func Render(e Email) (Message, error) {
+	t, ok := registry[e.Template]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", e.Template)
+	}
+	if len(e.Notices) == 0 {
+		return Message{}, fmt.Errorf("email %q has no notices", e.Template)
+	}
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", e); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", e.Template, err)
+	}
+	if err := t.text.Execute(&text, e); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", e.Template, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", e); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", e.Template, err)
+	}
+	return Message{Subject: subject.String(), Text: text.String(), HTML: html.String()}, nil
+}
@@ -3,15 +3,18 @@ package internal
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/clerk/clerk-sdk-go/v2/user"
 	"github.com/set-kaung/senior_project_1/internal/helpers"
+	"github.com/set-kaung/senior_project_1/internal/repository"
 )
 
 type ctxKey string
@@ -76,6 +79,16 @@ func getClerkUserID(ctx context.Context) (string, error) {
 	return clerkUser.ID, nil
 }
 
+// StreamTokenExtractor reads the session token from the Authorization header
+// or, failing that, the token query parameter. Browsers cannot set headers
+// on an EventSource, so streams need the second form.
+func StreamTokenExtractor(r *http.Request) string {
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID, err := getClerkUserID(r.Context())
@@ -94,6 +107,72 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// AccountStanding is the local status of an account. SuspendedUntil is zero
+// for a suspension without an end date.
+type AccountStanding struct {
+	Status         repository.AccountStatus
+	SuspendedUntil time.Time
+}
+
+// StandingLookup returns the standing of a user, or ErrNoRecord if the user
+// has not finished signing up yet.
+type StandingLookup func(ctx context.Context, userID string) (AccountStanding, error)
+
+// AccountStatusMiddleware shuts banned users out and keeps suspended users
+// read-only. It must run after AuthMiddleware.
+func AccountStatusMiddleware(lookup StandingLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(UserIDContextKey).(string)
+			standing, err := lookup(r.Context(), userID)
+			if err != nil && !errors.Is(err, ErrNoRecord) {
+				helpers.WriteServerError(w, nil)
+				return
+			}
+			switch standing.Status {
+			case repository.AccountStatusBanned:
+				helpers.WriteError(w, http.StatusForbidden, ErrAccountBanned.Error(), nil)
+				return
+			case repository.AccountStatusSuspended:
+				if r.Method != http.MethodGet && r.Method != http.MethodHead {
+					message := ErrAccountSuspended.Error()
+					if !standing.SuspendedUntil.IsZero() {
+						message = fmt.Sprintf("account is suspended until %s, only read access is allowed", standing.SuspendedUntil.Format(time.RFC3339))
+					}
+					helpers.WriteError(w, http.StatusForbidden, message, nil)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RoleLookup returns the roles granted to a user.
+type RoleLookup func(ctx context.Context, userID string) ([]string, error)
+
+// RequireRole only lets a request through if the authenticated user holds
+// one of the given roles. It must run after AuthMiddleware.
+func RequireRole(lookup RoleLookup, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(UserIDContextKey).(string)
+			granted, err := lookup(r.Context(), userID)
+			if err != nil {
+				helpers.WriteServerError(w, nil)
+				return
+			}
+			for _, role := range granted {
+				if slices.Contains(roles, role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			helpers.WriteError(w, http.StatusForbidden, "forbidden", nil)
+		})
+	}
+}
+
 func CORS(next http.Handler) http.Handler {
 	allowedOrigins := strings.Split(os.Getenv("REMOTE_ORIGINS"), ",")
 
@@ -110,7 +189,7 @@ func CORS(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, PATCH, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if r.Method == http.MethodOptions {
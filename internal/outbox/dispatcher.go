@@ -0,0 +1,209 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/set-kaung/senior_project_1/internal/mail"
+	"github.com/set-kaung/senior_project_1/internal/realtime"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+const (
+	DEFAULT_BATCH_SIZE = 100
+	// DEFAULT_MAIL_BATCH_SIZE is smaller than DEFAULT_BATCH_SIZE because
+	// a batch stays locked while each email in it is sent.
+	DEFAULT_MAIL_BATCH_SIZE = 10
+	// DEFAULT_MAIL_TIMEOUT bounds the delivery of one email.
+	DEFAULT_MAIL_TIMEOUT = 30 * time.Second
+	DEFAULT_MAX_ATTEMPTS = 10
+	DEFAULT_BASE_DELAY   = 5 * time.Second
+	DEFAULT_MAX_DELAY    = 30 * time.Minute
+	// RETENTION is how long delivered events are kept before Prune removes them.
+	RETENTION = 7 * 24 * time.Hour
+)
+
+// DB is the database a Dispatcher works on. *pgxpool.Pool implements it.
+type DB interface {
+	repository.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Dispatcher delivers outbox events to a Notifier and emails to Mail. A failed
+// event is retried with exponential backoff, and after MaxAttempts failures it
+// is dead-lettered: kept in the table with dead_at set but never sent again.
+// Realtime events and emails are claimed and sent in separate batches, so a
+// slow mail server cannot hold up realtime events.
+type Dispatcher struct {
+	DB            DB
+	Notifier      realtime.Notifier
+	Mail          *mail.Sender
+	BatchSize     int32
+	MailBatchSize int32
+	MailTimeout   time.Duration
+	MaxAttempts   int32
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+
+	init sync.Once
+}
+
+func (d *Dispatcher) defaults() {
+	if d.BatchSize <= 0 {
+		d.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	if d.MailBatchSize <= 0 {
+		d.MailBatchSize = DEFAULT_MAIL_BATCH_SIZE
+	}
+	if d.MailTimeout <= 0 {
+		d.MailTimeout = DEFAULT_MAIL_TIMEOUT
+	}
+	if d.MaxAttempts <= 0 {
+		d.MaxAttempts = DEFAULT_MAX_ATTEMPTS
+	}
+	if d.BaseDelay <= 0 {
+		d.BaseDelay = DEFAULT_BASE_DELAY
+	}
+	if d.MaxDelay <= 0 {
+		d.MaxDelay = DEFAULT_MAX_DELAY
+	}
+}
+
+// Run dispatches due realtime events and emails every interval until ctx is
+// cancelled, each in its own loop. A full batch is followed straight away by
+// the next one so a backlog drains without waiting out the interval.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	d.init.Do(d.defaults)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		d.loop(ctx, interval, d.Dispatch, d.BatchSize)
+	}()
+	go func() {
+		defer wg.Done()
+		d.loop(ctx, interval, d.DispatchMail, d.MailBatchSize)
+	}()
+	wg.Wait()
+}
+
+func (d *Dispatcher) loop(ctx context.Context, interval time.Duration, dispatch func(context.Context) (int, error), batchSize int32) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := dispatch(ctx)
+		if err != nil {
+			log.Printf("outbox: dispatch failed: %s\n", err)
+		}
+		if err == nil && n == int(batchSize) && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch sends one batch of due realtime events and returns how many it
+// handled, delivered or not. Events stay locked until the batch commits, so
+// several instances of the API can dispatch at once.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	d.init.Do(d.defaults)
+	return d.dispatch(ctx, false, d.BatchSize)
+}
+
+// DispatchMail sends one batch of due emails like Dispatch, giving each at
+// most MailTimeout.
+func (d *Dispatcher) DispatchMail(ctx context.Context) (int, error) {
+	d.init.Do(d.defaults)
+	return d.dispatch(ctx, true, d.MailBatchSize)
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, emails bool, batchSize int32) (int, error) {
+	tx, err := d.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	repo := repository.New(d.DB).WithTx(tx)
+
+	events, err := repo.ClaimOutbox(ctx, repository.ClaimOutboxParams{Emails: emails, BatchSize: batchSize})
+	if err != nil {
+		return 0, fmt.Errorf("claim events: %w", err)
+	}
+	for _, e := range events {
+		sendErr := d.send(ctx, e)
+		if sendErr == nil {
+			if err = repo.MarkOutboxDelivered(ctx, e.ID); err != nil {
+				return 0, fmt.Errorf("mark event %d delivered: %w", e.ID, err)
+			}
+			continue
+		}
+		attempts := e.Attempts + 1
+		dead := attempts >= d.MaxAttempts
+		if dead {
+			log.Printf("outbox: giving up on event %d (%s on %s) after %d attempts: %s\n", e.ID, e.Event, e.Channel, attempts, sendErr)
+		}
+		err = repo.MarkOutboxFailed(ctx, repository.MarkOutboxFailedParams{
+			LastError:     sendErr.Error(),
+			NextAttemptAt: time.Now().Add(d.backoff(attempts)),
+			Dead:          dead,
+			ID:            e.ID,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("mark event %d failed: %w", e.ID, err)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return len(events), nil
+}
+
+// send delivers e through the Notifier, or through Mail if it is an email.
+func (d *Dispatcher) send(ctx context.Context, e repository.ClaimOutboxRow) error {
+	if e.Channel != mail.CHANNEL {
+		return d.Notifier.Trigger(e.Channel, e.Event, e.Payload)
+	}
+	if d.Mail == nil {
+		return errors.New("no mailer configured")
+	}
+	var email mail.Email
+	if err := json.Unmarshal(e.Payload, &email); err != nil {
+		return fmt.Errorf("decode email: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.MailTimeout)
+	defer cancel()
+	return d.Mail.Deliver(ctx, email)
+}
+
+// backoff is the wait before retrying an event that has failed attempts
+// times: BaseDelay doubled per earlier failure, capped at MaxDelay.
+func (d *Dispatcher) backoff(attempts int32) time.Duration {
+	delay := d.BaseDelay
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxDelay {
+			return d.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Prune deletes events delivered more than RETENTION ago. Dead-lettered
+// events are kept for inspection.
+func (d *Dispatcher) Prune(ctx context.Context) (int64, error) {
+	n, err := repository.New(d.DB).DeleteDeliveredOutbox(ctx, time.Now().Add(-RETENTION))
+	if err != nil {
+		return 0, fmt.Errorf("delete delivered events: %w", err)
+	}
+	return n, nil
+}
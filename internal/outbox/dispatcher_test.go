@@ -0,0 +1,176 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal/mail"
+	"github.com/set-kaung/senior_project_1/internal/realtime"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+	"github.com/set-kaung/senior_project_1/internal/repository/repotest"
+)
+
+// claimEnqueued scripts db to claim, as events 1, 2, ..., everything
+// enqueued on it so far.
+func claimEnqueued(t *testing.T, db *repotest.DB) {
+	t.Helper()
+	var rows [][]any
+	for i, c := range db.Calls("EnqueueOutbox") {
+		rows = append(rows, []any{int64(i + 1), c.Args[0], c.Args[1], c.Args[2], int32(0)})
+	}
+	db.Returns("ClaimOutbox", rows...)
+}
+
+func TestDispatchDeliversToNotifier(t *testing.T) {
+	ctx := context.Background()
+	db := repotest.New()
+	repo := repository.New(db)
+	if err := Notify(ctx, repo, "alice", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Enqueue(ctx, repo, "alice", "new-message", map[string]int{"request_id": 4}); err != nil {
+		t.Fatal(err)
+	}
+	claimEnqueued(t, db)
+
+	notifier := &realtime.Memory{}
+	d := &Dispatcher{DB: db, Notifier: notifier}
+	n, err := d.Dispatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Dispatch() = %d, want 3", n)
+	}
+	claims := db.Calls("ClaimOutbox")
+	if len(claims) != 1 || claims[0].Args[0] != false || claims[0].Args[1] != int32(DEFAULT_BATCH_SIZE) {
+		t.Errorf("ClaimOutbox calls = %v, want realtime events in batches of %d", claims, DEFAULT_BATCH_SIZE)
+	}
+
+	want := []realtime.Event{
+		{Channel: "user-alice", Name: EVENT_NEW_NOTIFICATION, Data: []byte("null")},
+		{Channel: "user-bob", Name: EVENT_NEW_NOTIFICATION, Data: []byte("null")},
+		{Channel: "user-alice", Name: "new-message", Data: []byte(`{"request_id":4}`)},
+	}
+	got := notifier.Events()
+	if len(got) != len(want) {
+		t.Fatalf("triggered %d events, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Channel != want[i].Channel || got[i].Name != want[i].Name || string(got[i].Data) != string(want[i].Data) {
+			t.Errorf("event %d = %s %s %s, want %s %s %s", i, got[i].Channel, got[i].Name, got[i].Data, want[i].Channel, want[i].Name, want[i].Data)
+		}
+	}
+	if delivered := db.Calls("MarkOutboxDelivered"); len(delivered) != 3 {
+		t.Errorf("marked %d events delivered, want 3", len(delivered))
+	}
+	if db.Commits() != 1 {
+		t.Errorf("committed %d times, want 1", db.Commits())
+	}
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Trigger(channel string, eventName string, data interface{}) error {
+	return errors.New("pusher unavailable")
+}
+
+func TestDispatchRetriesFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int32
+		wantDead bool
+		wantWait time.Duration
+	}{
+		{"first failure", 0, false, time.Second},
+		{"third failure", 2, false, 4 * time.Second},
+		{"capped", 7, false, 10 * time.Second},
+		{"last attempt", 9, true, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repotest.New()
+			db.Returns("ClaimOutbox", []any{int64(5), "user-alice", EVENT_NEW_NOTIFICATION, []byte("null"), tt.attempts})
+			d := &Dispatcher{DB: db, Notifier: failingNotifier{}, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+			start := time.Now()
+			if _, err := d.Dispatch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			failed := db.Calls("MarkOutboxFailed")
+			if len(failed) != 1 {
+				t.Fatalf("marked %d events failed, want 1", len(failed))
+			}
+			args := failed[0].Args
+			if args[0] != "pusher unavailable" || args[2] != tt.wantDead || args[3] != int64(5) {
+				t.Errorf("MarkOutboxFailed args = %v, want the error, dead %v and event 5", args, tt.wantDead)
+			}
+			wait := args[1].(time.Time).Sub(start)
+			if wait < tt.wantWait || wait > tt.wantWait+time.Second {
+				t.Errorf("retried after %s, want %s", wait, tt.wantWait)
+			}
+			if len(db.Calls("MarkOutboxDelivered")) != 0 {
+				t.Error("a failed event was marked delivered")
+			}
+		})
+	}
+}
+
+type directory map[string]string
+
+func (d directory) Address(ctx context.Context, userID string) (string, error) {
+	if addr, ok := d[userID]; ok {
+		return addr, nil
+	}
+	return "", mail.ErrNoAddress
+}
+
+// stalledMailer never finishes sending, like a mail server that stopped
+// responding.
+type stalledMailer struct{}
+
+func (stalledMailer) Send(ctx context.Context, m mail.Message) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDispatchMailTimeout(t *testing.T) {
+	db := repotest.New()
+	payload, err := json.Marshal(mail.Email{UserID: "alice", Template: mail.TEMPLATE_DIGEST, Notices: []mail.Notice{{EventType: "request", Message: "Your request was accepted"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Returns("ClaimOutbox", []any{int64(8), mail.CHANNEL, mail.TEMPLATE_DIGEST, payload, int32(0)})
+	d := &Dispatcher{
+		DB:          db,
+		Notifier:    &realtime.Memory{},
+		Mail:        &mail.Sender{Mailer: stalledMailer{}, Directory: directory{"alice": "alice@example.com"}, From: "no-reply@example.com"},
+		MailTimeout: 50 * time.Millisecond,
+	}
+	start := time.Now()
+	if _, err := d.DispatchMail(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("DispatchMail took %s, want about the 50ms timeout", elapsed)
+	}
+
+	claims := db.Calls("ClaimOutbox")
+	if len(claims) != 1 || claims[0].Args[0] != true || claims[0].Args[1] != int32(DEFAULT_MAIL_BATCH_SIZE) {
+		t.Errorf("ClaimOutbox calls = %v, want emails in batches of %d", claims, DEFAULT_MAIL_BATCH_SIZE)
+	}
+	failed := db.Calls("MarkOutboxFailed")
+	if len(failed) != 1 || failed[0].Args[3] != int64(8) {
+		t.Fatalf("MarkOutboxFailed calls = %v, want email 8 to fail", failed)
+	}
+	if lastError := failed[0].Args[0].(string); !strings.Contains(lastError, context.DeadlineExceeded.Error()) {
+		t.Errorf("email failed with %q, want it to time out", lastError)
+	}
+	if db.Commits() != 1 {
+		t.Errorf("committed %d times, want 1", db.Commits())
+	}
+}
@@ -0,0 +1,63 @@
+// Package outbox delivers realtime events and emails reliably. Services write
+// them to the outbox table in the same transaction as the change they
+// announce, so one is sent if and only if that change commits, and a
+// Dispatcher delivers them in the background, retrying failures.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/set-kaung/senior_project_1/internal/mail"
+	"github.com/set-kaung/senior_project_1/internal/realtime"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+// EVENT_NEW_NOTIFICATION tells a client to refetch its notifications.
+const EVENT_NEW_NOTIFICATION = "new-notification"
+
+// Enqueue records event for userID with data as its JSON payload. repo
+// should be bound to the transaction of the change the event announces.
+func Enqueue(ctx context.Context, repo *repository.Queries, userID string, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal outbox payload: %w", err)
+	}
+	err = repo.EnqueueOutbox(ctx, repository.EnqueueOutboxParams{
+		Channel: realtime.UserChannel(userID),
+		Event:   event,
+		Payload: payload,
+	})
+	if err != nil {
+		return fmt.Errorf("enqueue outbox event: %w", err)
+	}
+	return nil
+}
+
+// Notify enqueues a new-notification event for each recipient.
+func Notify(ctx context.Context, repo *repository.Queries, recipients ...string) error {
+	for _, r := range recipients {
+		if err := Enqueue(ctx, repo, r, EVENT_NEW_NOTIFICATION, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Email enqueues e on the email channel.
+func Email(ctx context.Context, repo *repository.Queries, e mail.Email) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal email: %w", err)
+	}
+	err = repo.EnqueueOutbox(ctx, repository.EnqueueOutboxParams{
+		Channel: mail.CHANNEL,
+		Event:   e.Template,
+		Payload: payload,
+	})
+	if err != nil {
+		return fmt.Errorf("enqueue email: %w", err)
+	}
+	return nil
+}
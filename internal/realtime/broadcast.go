@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/set-kaung/senior_project_1/internal/repository"
+)
+
+// maxNotifyPayload is the largest payload Postgres NOTIFY accepts.
+const maxNotifyPayload = 8000
+
+var ErrEventTooLarge = errors.New("event is too large to broadcast")
+
+type broadcastMessage struct {
+	Channel string `json:"c"`
+	Event   string `json:"e"`
+	Data    []byte `json:"d"`
+}
+
+// Broadcast is a Notifier that hands events to the Hub of every instance of
+// the API through Postgres NOTIFY, so a client receives its events whichever
+// instance it is streaming from and whichever one dispatched them. Each
+// instance must run Listen.
+type Broadcast struct {
+	DB  *pgxpool.Pool
+	Hub *Hub
+}
+
+// Trigger publishes the event to every instance. returns ErrEventTooLarge if
+// it does not fit in a notification.
+func (b *Broadcast) Trigger(channel string, eventName string, data interface{}) error {
+	d, err := encode(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(broadcastMessage{Channel: channel, Event: eventName, Data: d})
+	if err != nil {
+		return err
+	}
+	if len(payload) >= maxNotifyPayload {
+		return fmt.Errorf("%w: %d bytes", ErrEventTooLarge, len(payload))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return repository.New(b.DB).NotifyRealtime(ctx, string(payload))
+}
+
+// Listen relays broadcast events to the local Hub until ctx is cancelled,
+// reconnecting when its connection drops. Events broadcast while it is
+// disconnected are lost, so every stream is closed on reconnect and clients
+// refetch instead of silently missing them.
+func (b *Broadcast) Listen(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("realtime: lost broadcast connection, reconnecting: %s\n", err)
+		b.Hub.disconnectAll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *Broadcast) listen(ctx context.Context) error {
+	pooled, err := b.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// a listening connection must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+	if err = repository.New(conn).ListenRealtime(ctx); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var m broadcastMessage
+		if err = json.Unmarshal([]byte(n.Payload), &m); err != nil {
+			log.Printf("realtime: dropping malformed broadcast: %s\n", err)
+			continue
+		}
+		b.Hub.Trigger(m.Channel, m.Event, m.Data)
+	}
+}
@@ -0,0 +1,32 @@
+package realtime
+
+import (
+	"errors"
+	"os"
+
+	"github.com/pusher/pusher-http-go/v5"
+)
+
+var ErrPusherNotConfigured = errors.New("PUSHER_APP_ID, PUSHER_KEY, PUSHER_SECRET and PUSHER_CLUSTER must all be set")
+
+// PusherConfigured reports whether the Pusher credentials are in the
+// environment.
+func PusherConfigured() bool {
+	return os.Getenv("PUSHER_APP_ID") != "" && os.Getenv("PUSHER_KEY") != "" &&
+		os.Getenv("PUSHER_SECRET") != "" && os.Getenv("PUSHER_CLUSTER") != ""
+}
+
+// NewPusher returns a Pusher client configured from the environment.
+// returns ErrPusherNotConfigured if any credential is missing.
+func NewPusher() (*pusher.Client, error) {
+	if !PusherConfigured() {
+		return nil, ErrPusherNotConfigured
+	}
+	return &pusher.Client{
+		AppID:   os.Getenv("PUSHER_APP_ID"),
+		Key:     os.Getenv("PUSHER_KEY"),
+		Secret:  os.Getenv("PUSHER_SECRET"),
+		Cluster: os.Getenv("PUSHER_CLUSTER"),
+		Secure:  true,
+	}, nil
+}
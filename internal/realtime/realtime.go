@@ -0,0 +1,75 @@
+// Package realtime pushes events to connected clients. Each user listens on
+// their own channel, and a Notifier carries events to it through Pusher,
+// a self-hosted Server-Sent Events stream fed across instances by Postgres
+// NOTIFY, or, in tests, nowhere at all.
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Notifier sends an event on a channel. Sending to a channel nobody listens
+// on is not an error. *pusher.Client implements it.
+type Notifier interface {
+	Trigger(channel string, eventName string, data interface{}) error
+}
+
+// UserChannel is the channel a user's client listens on.
+func UserChannel(userID string) string {
+	return fmt.Sprintf("user-%s", userID)
+}
+
+// encode turns event data into its wire form the way Pusher does: bytes and
+// strings are sent as they are and anything else as JSON.
+func encode(data interface{}) ([]byte, error) {
+	switch d := data.(type) {
+	case []byte:
+		return d, nil
+	case string:
+		return []byte(d), nil
+	default:
+		return json.Marshal(d)
+	}
+}
+
+// Event is an event recorded by Memory.
+type Event struct {
+	Channel string
+	Name    string
+	Data    []byte
+}
+
+// Memory records events instead of sending them, for tests and for running
+// without any realtime backend.
+type Memory struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (m *Memory) Trigger(channel string, eventName string, data interface{}) error {
+	b, err := encode(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, Event{Channel: channel, Name: eventName, Data: b})
+	return nil
+}
+
+// Events returns the events triggered so far, oldest first.
+func (m *Memory) Events() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.events)
+}
+
+// Reset forgets all recorded events.
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = nil
+}
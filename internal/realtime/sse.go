@@ -0,0 +1,174 @@
+package realtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
+	"github.com/set-kaung/senior_project_1/internal/helpers"
+)
+
+const (
+	// HEARTBEAT is how often an idle stream sends a comment, so proxies do
+	// not time it out.
+	HEARTBEAT = 25 * time.Second
+	// streamBuffer is how many events a stream may fall behind before it is
+	// disconnected.
+	streamBuffer = 32
+)
+
+type sseMessage struct {
+	event string
+	data  []byte
+}
+
+// Hub is a Notifier that streams events to clients connected to this
+// process with Server-Sent Events. It needs no third-party service, but a
+// client only receives events triggered by the instance it is connected to;
+// with more than one instance, trigger events through a Broadcast instead.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[string]map[chan sseMessage]struct{}
+	closed bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[string]map[chan sseMessage]struct{}{}}
+}
+
+// Trigger sends the event to every stream on channel. A stream too far
+// behind to take it is disconnected; the client reconnects and refetches
+// instead of silently missing the event.
+func (h *Hub) Trigger(channel string, eventName string, data interface{}) error {
+	b, err := encode(data)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[channel] {
+		select {
+		case ch <- sseMessage{event: eventName, data: b}:
+		default:
+			h.removeLocked(channel, ch)
+		}
+	}
+	return nil
+}
+
+func (h *Hub) subscribe(channel string) (chan sseMessage, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, false
+	}
+	ch := make(chan sseMessage, streamBuffer)
+	if h.subs[channel] == nil {
+		h.subs[channel] = map[chan sseMessage]struct{}{}
+	}
+	h.subs[channel][ch] = struct{}{}
+	return ch, true
+}
+
+func (h *Hub) unsubscribe(channel string, ch chan sseMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(channel, ch)
+}
+
+func (h *Hub) removeLocked(channel string, ch chan sseMessage) {
+	if _, ok := h.subs[channel][ch]; !ok {
+		return
+	}
+	delete(h.subs[channel], ch)
+	if len(h.subs[channel]) == 0 {
+		delete(h.subs, channel)
+	}
+	close(ch)
+}
+
+// Close ends every open stream and refuses new ones. Register it with
+// http.Server.RegisterOnShutdown so streams do not hold up shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	h.disconnectAllLocked()
+}
+
+// disconnectAll ends every open stream, leaving clients to reconnect.
+func (h *Hub) disconnectAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.disconnectAllLocked()
+}
+
+func (h *Hub) disconnectAllLocked() {
+	for channel, chans := range h.subs {
+		for ch := range chans {
+			h.removeLocked(channel, ch)
+		}
+	}
+}
+
+// HandleStream streams the events of the authenticated user's channel until
+// the client disconnects.
+func (h *Hub) HandleStream(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(internal.UserIDContextKey).(string)
+	rc := http.NewResponseController(w)
+	// the server's write timeout is meant for ordinary responses, not for
+	// a stream that stays open
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		helpers.WriteServerError(w, nil)
+		return
+	}
+	channel := UserChannel(userID)
+	ch, ok := h.subscribe(channel)
+	if !ok {
+		helpers.WriteError(w, http.StatusServiceUnavailable, "server is shutting down", nil)
+		return
+	}
+	defer h.unsubscribe(channel, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(HEARTBEAT)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case m, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, m)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes m in the event stream format, one data field per line
+// of its payload.
+func writeEvent(w http.ResponseWriter, m sseMessage) {
+	fmt.Fprintf(w, "event: %s\n", m.event)
+	for _, line := range bytes.Split(m.data, []byte("\n")) {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
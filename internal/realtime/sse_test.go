@@ -0,0 +1,146 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/set-kaung/senior_project_1/internal"
+)
+
+// stream connects to hub as userID and returns the lines it receives.
+func stream(t *testing.T, hub *Hub, userID string) <-chan string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.HandleStream(w, r.WithContext(context.WithValue(r.Context(), internal.UserIDContextKey, userID)))
+	}))
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	lines := make(chan string)
+	go func() {
+		defer resp.Body.Close()
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// nextEvent reads lines up to the end of the next event.
+func nextEvent(t *testing.T, lines <-chan string) []string {
+	t.Helper()
+	var event []string
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return event
+			}
+			if line == "" {
+				if len(event) > 0 && !strings.HasPrefix(event[0], "retry:") {
+					return event
+				}
+				event = nil
+				continue
+			}
+			event = append(event, line)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no event after %v", event)
+		}
+	}
+}
+
+// waitSubscribed waits until channel has a stream, since HandleStream
+// subscribes after the response headers can already have been read.
+func waitSubscribed(t *testing.T, hub *Hub, channel string) {
+	t.Helper()
+	for range 200 {
+		hub.mu.Lock()
+		n := len(hub.subs[channel])
+		hub.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("nobody subscribed to %s", channel)
+}
+
+func TestHubStreamsUserEvents(t *testing.T) {
+	hub := NewHub()
+	alice := stream(t, hub, "alice")
+	bob := stream(t, hub, "bob")
+	waitSubscribed(t, hub, UserChannel("alice"))
+	waitSubscribed(t, hub, UserChannel("bob"))
+
+	if err := hub.Trigger(UserChannel("alice"), "new-message", map[string]int{"request_id": 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.Trigger(UserChannel("bob"), "new-notification", "line one\nline two"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := nextEvent(t, alice), []string{"event: new-message", `data: {"request_id":4}`}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("alice got %q, want %q", got, want)
+	}
+	if got, want := nextEvent(t, bob), []string{"event: new-notification", "data: line one", "data: line two"}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("bob got %q, want %q", got, want)
+	}
+
+	hub.Close()
+	if rest := nextEvent(t, alice); rest != nil {
+		t.Errorf("alice got %q after Close, want the stream to end", rest)
+	}
+}
+
+// TestBroadcastMessage checks that an event survives the trip through a
+// NOTIFY payload into another instance's Hub unchanged.
+func TestBroadcastMessage(t *testing.T) {
+	d, err := encode(map[string]string{"text": "héllo\n<b>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(broadcastMessage{Channel: UserChannel("alice"), Event: "new-message", Data: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m broadcastMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := NewHub()
+	ch, _ := hub.subscribe(UserChannel("alice"))
+	if err := hub.Trigger(m.Channel, m.Event, m.Data); err != nil {
+		t.Fatal(err)
+	}
+	got := <-ch
+	if got.event != "new-message" || string(got.data) != string(d) {
+		t.Errorf("hub got %s %s, want new-message %s", got.event, got.data, d)
+	}
+}
+
+func TestBroadcastTriggerTooLarge(t *testing.T) {
+	b := &Broadcast{}
+	err := b.Trigger(UserChannel("alice"), "new-message", strings.Repeat("x", maxNotifyPayload))
+	if !errors.Is(err, ErrEventTooLarge) {
+		t.Fatalf("Trigger() error = %v, want ErrEventTooLarge", err)
+	}
+}
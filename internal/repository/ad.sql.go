@@ -7,12 +7,13 @@ package repository
 
 import (
 	"context"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const getAdsHistory = `-- name: GetAdsHistory :many
-SELECT id, user_id, date_time FROM ads_watching_history
+SELECT id, user_id, date_time, ad_unit, tokens FROM ads_watching_history
 WHERE user_id = $1
 `
 
@@ -25,7 +26,13 @@ func (q *Queries) GetAdsHistory(ctx context.Context, userID string) ([]AdsWatchi
 	var items []AdsWatchingHistory
 	for rows.Next() {
 		var i AdsWatchingHistory
-		if err := rows.Scan(&i.ID, &i.UserID, &i.DateTime); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.DateTime,
+			&i.AdUnit,
+			&i.Tokens,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -38,21 +45,36 @@ func (q *Queries) GetAdsHistory(ctx context.Context, userID string) ([]AdsWatchi
 
 const getAdsWatched = `-- name: GetAdsWatched :one
 SELECT count(id) FROM ads_watching_history
-WHERE user_id = $1 AND date_time > (NOW() - INTERVAL '24 hour')
+WHERE user_id = $1 AND date_time > $2::timestamptz
 `
 
-func (q *Queries) GetAdsWatched(ctx context.Context, userID string) (int64, error) {
-	row := q.db.QueryRow(ctx, getAdsWatched, userID)
+type GetAdsWatchedParams struct {
+	UserID string    `json:"user_id"`
+	Since  time.Time `json:"since"`
+}
+
+func (q *Queries) GetAdsWatched(ctx context.Context, arg GetAdsWatchedParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getAdsWatched, arg.UserID, arg.Since)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
-const insertAdsHistory = `-- name: InsertAdsHistory :execresult
-INSERT INTO ads_watching_history (user_id,date_time)
-VALUES ($1,NOW())
+const insertAdsHistory = `-- name: InsertAdsHistory :one
+INSERT INTO ads_watching_history (user_id, date_time, ad_unit, tokens)
+VALUES ($1, NOW(), $2, $3)
+RETURNING id
 `
 
-func (q *Queries) InsertAdsHistory(ctx context.Context, userID string) (pgconn.CommandTag, error) {
-	return q.db.Exec(ctx, insertAdsHistory, userID)
+type InsertAdsHistoryParams struct {
+	UserID string      `json:"user_id"`
+	AdUnit pgtype.Text `json:"ad_unit"`
+	Tokens int32       `json:"tokens"`
+}
+
+func (q *Queries) InsertAdsHistory(ctx context.Context, arg InsertAdsHistoryParams) (int32, error) {
+	row := q.db.QueryRow(ctx, insertAdsHistory, arg.UserID, arg.AdUnit, arg.Tokens)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
 }
@@ -55,6 +55,94 @@ func (ns NullAccountStatus) Value() (driver.Value, error) {
 	return string(ns.AccountStatus), nil
 }
 
+type JobRunStatus string
+
+const (
+	JobRunStatusRunning   JobRunStatus = "running"
+	JobRunStatusSucceeded JobRunStatus = "succeeded"
+	JobRunStatusFailed    JobRunStatus = "failed"
+	JobRunStatusAbandoned JobRunStatus = "abandoned"
+)
+
+func (e *JobRunStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = JobRunStatus(s)
+	case string:
+		*e = JobRunStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for JobRunStatus: %T", src)
+	}
+	return nil
+}
+
+type NullJobRunStatus struct {
+	JobRunStatus JobRunStatus `json:"job_run_status"`
+	Valid        bool         `json:"valid"` // Valid is true if JobRunStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullJobRunStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.JobRunStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.JobRunStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullJobRunStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.JobRunStatus), nil
+}
+
+type LedgerAccountType string
+
+const (
+	LedgerAccountTypeUserWallet LedgerAccountType = "user_wallet"
+	LedgerAccountTypeEscrow     LedgerAccountType = "escrow"
+	LedgerAccountTypeSystemMint LedgerAccountType = "system_mint"
+	LedgerAccountTypeRewardSink LedgerAccountType = "reward_sink"
+)
+
+func (e *LedgerAccountType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LedgerAccountType(s)
+	case string:
+		*e = LedgerAccountType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LedgerAccountType: %T", src)
+	}
+	return nil
+}
+
+type NullLedgerAccountType struct {
+	LedgerAccountType LedgerAccountType `json:"ledger_account_type"`
+	Valid             bool              `json:"valid"` // Valid is true if LedgerAccountType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLedgerAccountType) Scan(value interface{}) error {
+	if value == nil {
+		ns.LedgerAccountType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LedgerAccountType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLedgerAccountType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LedgerAccountType), nil
+}
+
 type PaymentStatus string
 
 const (
@@ -62,6 +150,8 @@ const (
 	PaymentStatusHolding   PaymentStatus = "holding"
 	PaymentStatusReleased  PaymentStatus = "released"
 	PaymentStatusRefunded  PaymentStatus = "refunded"
+	PaymentStatusSplit     PaymentStatus = "split"
+	PaymentStatusDisputed  PaymentStatus = "disputed"
 )
 
 func (e *PaymentStatus) Scan(src interface{}) error {
@@ -99,6 +189,48 @@ func (ns NullPaymentStatus) Value() (driver.Value, error) {
 	return string(ns.PaymentStatus), nil
 }
 
+type Role string
+
+const (
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func (e *Role) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = Role(s)
+	case string:
+		*e = Role(s)
+	default:
+		return fmt.Errorf("unsupported scan type for Role: %T", src)
+	}
+	return nil
+}
+
+type NullRole struct {
+	Role  Role `json:"role"`
+	Valid bool `json:"valid"` // Valid is true if Role is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.Role, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.Role.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.Role), nil
+}
+
 type ServiceActivity string
 
 const (
@@ -320,9 +452,52 @@ func (ns NullWarningSeverity) Value() (driver.Value, error) {
 }
 
 type AdsWatchingHistory struct {
-	ID       int32     `json:"id"`
-	UserID   string    `json:"user_id"`
-	DateTime time.Time `json:"date_time"`
+	ID       int32       `json:"id"`
+	UserID   string      `json:"user_id"`
+	DateTime time.Time   `json:"date_time"`
+	AdUnit   pgtype.Text `json:"ad_unit"`
+	Tokens   int32       `json:"tokens"`
+}
+
+type AvailabilityException struct {
+	ID         int32       `json:"id"`
+	ProviderID string      `json:"provider_id"`
+	StartsAt   time.Time   `json:"starts_at"`
+	EndsAt     time.Time   `json:"ends_at"`
+	Reason     pgtype.Text `json:"reason"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type AvailabilityRule struct {
+	ID         int32       `json:"id"`
+	ProviderID string      `json:"provider_id"`
+	Weekday    int16       `json:"weekday"`
+	StartTime  pgtype.Time `json:"start_time"`
+	EndTime    pgtype.Time `json:"end_time"`
+	Timezone   string      `json:"timezone"`
+}
+
+type CalendarFeed struct {
+	UserID    string    `json:"user_id"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Category struct {
+	ID        int32       `json:"id"`
+	Slug      string      `json:"slug"`
+	Name      string      `json:"name"`
+	ParentID  pgtype.Int4 `json:"parent_id"`
+	Icon      pgtype.Text `json:"icon"`
+	SortOrder int32       `json:"sort_order"`
+}
+
+type CategoryExpiryPolicy struct {
+	CategoryID        int32           `json:"category_id"`
+	PendingTimeout    pgtype.Interval `json:"pending_timeout"`
+	InProgressTimeout pgtype.Interval `json:"in_progress_timeout"`
+	AutoCompleteAfter pgtype.Interval `json:"auto_complete_after"`
+	UpdatedAt         time.Time       `json:"updated_at"`
 }
 
 type CouponCode struct {
@@ -340,13 +515,103 @@ type Event struct {
 	Description string    `json:"description"`
 }
 
+type IdempotencyKey struct {
+	UserID       string             `json:"user_id"`
+	Key          string             `json:"key"`
+	RequestHash  string             `json:"request_hash"`
+	StatusCode   pgtype.Int4        `json:"status_code"`
+	ContentType  pgtype.Text        `json:"content_type"`
+	ResponseBody []byte             `json:"response_body"`
+	CreatedAt    time.Time          `json:"created_at"`
+	CompletedAt  pgtype.Timestamptz `json:"completed_at"`
+}
+
+type JobRun struct {
+	ID         int64              `json:"id"`
+	Job        string             `json:"job"`
+	Instance   string             `json:"instance"`
+	Status     JobRunStatus       `json:"status"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt pgtype.Timestamptz `json:"finished_at"`
+	Error      pgtype.Text        `json:"error"`
+}
+
+type JournalEntry struct {
+	ID            int64       `json:"id"`
+	Kind          string      `json:"kind"`
+	Memo          string      `json:"memo"`
+	ReferenceType pgtype.Text `json:"reference_type"`
+	ReferenceID   pgtype.Int4 `json:"reference_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+type JournalLine struct {
+	ID        int64 `json:"id"`
+	EntryID   int64 `json:"entry_id"`
+	AccountID int32 `json:"account_id"`
+	Amount    int32 `json:"amount"`
+}
+
+type LedgerAccount struct {
+	ID        int32             `json:"id"`
+	Code      string            `json:"code"`
+	Type      LedgerAccountType `json:"type"`
+	UserID    pgtype.Text       `json:"user_id"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type ListingAppeal struct {
+	ID           int32              `json:"id"`
+	ListingID    int32              `json:"listing_id"`
+	AppellantID  string             `json:"appellant_id"`
+	Reason       string             `json:"reason"`
+	Status       string             `json:"status"`
+	CreatedAt    time.Time          `json:"created_at"`
+	DecidedBy    pgtype.Text        `json:"decided_by"`
+	DecisionNote pgtype.Text        `json:"decision_note"`
+	DecidedAt    pgtype.Timestamptz `json:"decided_at"`
+}
+
+type ListingLocation struct {
+	ListingID       int32         `json:"listing_id"`
+	Latitude        float64       `json:"latitude"`
+	Longitude       float64       `json:"longitude"`
+	ServiceRadiusKm pgtype.Float8 `json:"service_radius_km"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
 type Notification struct {
-	ID              int32       `json:"id"`
-	Message         string      `json:"message"`
-	RecipientUserID string      `json:"recipient_user_id"`
-	ActionUserID    pgtype.Text `json:"action_user_id"`
-	IsRead          bool        `json:"is_read"`
-	EventID         int64       `json:"event_id"`
+	ID              int32              `json:"id"`
+	Message         string             `json:"message"`
+	RecipientUserID string             `json:"recipient_user_id"`
+	ActionUserID    pgtype.Text        `json:"action_user_id"`
+	IsRead          bool               `json:"is_read"`
+	EventID         int64              `json:"event_id"`
+	EmailPending    bool               `json:"email_pending"`
+	ArchivedAt      pgtype.Timestamptz `json:"archived_at"`
+}
+
+type NotificationPreference struct {
+	UserID      string    `json:"user_id"`
+	EventType   string    `json:"event_type"`
+	Description string    `json:"description"`
+	InApp       bool      `json:"in_app"`
+	Push        bool      `json:"push"`
+	Email       bool      `json:"email"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Outbox struct {
+	ID            int64              `json:"id"`
+	Channel       string             `json:"channel"`
+	Event         string             `json:"event"`
+	Payload       []byte             `json:"payload"`
+	Attempts      int32              `json:"attempts"`
+	NextAttemptAt time.Time          `json:"next_attempt_at"`
+	LastError     pgtype.Text        `json:"last_error"`
+	CreatedAt     time.Time          `json:"created_at"`
+	DeliveredAt   pgtype.Timestamptz `json:"delivered_at"`
+	DeadAt        pgtype.Timestamptz `json:"dead_at"`
 }
 
 type Payment struct {
@@ -359,6 +624,12 @@ type Payment struct {
 	UpdatedAt        time.Time     `json:"updated_at"`
 }
 
+type PendingRequestReminder struct {
+	RequestID   int32     `json:"request_id"`
+	LeadMinutes int32     `json:"lead_minutes"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
 type Rating struct {
 	UserID       string `json:"user_id"`
 	TotalRatings int32  `json:"total_ratings"`
@@ -375,23 +646,50 @@ type RedeemedReward struct {
 }
 
 type Report struct {
-	ID               int32       `json:"id"`
-	ListingID        int32       `json:"listing_id"`
-	ReporterID       string      `json:"reporter_id"`
-	Datetime         time.Time   `json:"datetime"`
-	ReportReason     pgtype.Text `json:"report_reason"`
-	Status           string      `json:"status"`
-	AdditionalDetail pgtype.Text `json:"additional_detail"`
+	ID               int32              `json:"id"`
+	ListingID        int32              `json:"listing_id"`
+	ReporterID       string             `json:"reporter_id"`
+	Datetime         time.Time          `json:"datetime"`
+	ReportReason     pgtype.Text        `json:"report_reason"`
+	Status           string             `json:"status"`
+	AdditionalDetail pgtype.Text        `json:"additional_detail"`
+	ReviewedBy       pgtype.Text        `json:"reviewed_by"`
+	ReviewedAt       pgtype.Timestamptz `json:"reviewed_at"`
+}
+
+type RequestMessage struct {
+	ID        int32              `json:"id"`
+	RequestID int32              `json:"request_id"`
+	SenderID  string             `json:"sender_id"`
+	Body      string             `json:"body"`
+	CreatedAt time.Time          `json:"created_at"`
+	ReadAt    pgtype.Timestamptz `json:"read_at"`
 }
 
 type RequestReport struct {
-	ID         int32     `json:"id"`
-	ReporterID string    `json:"reporter_id"`
-	RequestID  int32     `json:"request_id"`
-	TicketID   string    `json:"ticket_id"`
-	CreatedAt  time.Time `json:"created_at"`
-	Status     string    `json:"status"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID             int32              `json:"id"`
+	ReporterID     string             `json:"reporter_id"`
+	RequestID      int32              `json:"request_id"`
+	TicketID       string             `json:"ticket_id"`
+	CreatedAt      time.Time          `json:"created_at"`
+	Status         string             `json:"status"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	Description    string             `json:"description"`
+	EvidenceUrls   []string           `json:"evidence_urls"`
+	Resolution     pgtype.Text        `json:"resolution"`
+	RequesterShare pgtype.Int4        `json:"requester_share"`
+	ResolutionNote pgtype.Text        `json:"resolution_note"`
+	ResolvedBy     pgtype.Text        `json:"resolved_by"`
+	ResolvedAt     pgtype.Timestamptz `json:"resolved_at"`
+}
+
+type RequestReportMessage struct {
+	ID           int32     `json:"id"`
+	ReportID     int32     `json:"report_id"`
+	AuthorID     string    `json:"author_id"`
+	Body         string    `json:"body"`
+	EvidenceUrls []string  `json:"evidence_urls"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Review struct {
@@ -440,11 +738,21 @@ type ServiceRequest struct {
 }
 
 type ServiceRequestCompletion struct {
-	ID                 int32 `json:"id"`
-	RequestID          int32 `json:"request_id"`
-	RequesterCompleted bool  `json:"requester_completed"`
-	ProviderCompleted  bool  `json:"provider_completed"`
-	IsActive           bool  `json:"is_active"`
+	ID                 int32              `json:"id"`
+	RequestID          int32              `json:"request_id"`
+	RequesterCompleted bool               `json:"requester_completed"`
+	ProviderCompleted  bool               `json:"provider_completed"`
+	IsActive           bool               `json:"is_active"`
+	ConfirmedAt        pgtype.Timestamptz `json:"confirmed_at"`
+}
+
+type ServiceRequestSlot struct {
+	RequestID  int32              `json:"request_id"`
+	ProviderID string             `json:"provider_id"`
+	StartsAt   time.Time          `json:"starts_at"`
+	EndsAt     time.Time          `json:"ends_at"`
+	Released   bool               `json:"released"`
+	RemindedAt pgtype.Timestamptz `json:"reminded_at"`
 }
 
 type Transaction struct {
@@ -456,21 +764,38 @@ type Transaction struct {
 }
 
 type User struct {
-	ID              string        `json:"id"`
-	Phone           string        `json:"phone"`
-	TokenBalance    int32         `json:"token_balance"`
-	Status          AccountStatus `json:"status"`
-	AddressLine1    string        `json:"address_line_1"`
-	AddressLine2    string        `json:"address_line_2"`
-	City            string        `json:"city"`
-	StateProvince   string        `json:"state_province"`
-	ZipPostalCode   string        `json:"zip_postal_code"`
-	Country         string        `json:"country"`
-	JoinedAt        time.Time     `json:"joined_at"`
-	IsEmailSignedup bool          `json:"is_email_signedup"`
-	FullName        string        `json:"full_name"`
-	IsPaid          bool          `json:"is_paid"`
-	AboutMe         pgtype.Text   `json:"about_me"`
+	ID              string             `json:"id"`
+	Phone           string             `json:"phone"`
+	TokenBalance    int32              `json:"token_balance"`
+	Status          AccountStatus      `json:"status"`
+	AddressLine1    string             `json:"address_line_1"`
+	AddressLine2    string             `json:"address_line_2"`
+	City            string             `json:"city"`
+	StateProvince   string             `json:"state_province"`
+	ZipPostalCode   string             `json:"zip_postal_code"`
+	Country         string             `json:"country"`
+	JoinedAt        time.Time          `json:"joined_at"`
+	IsEmailSignedup bool               `json:"is_email_signedup"`
+	FullName        string             `json:"full_name"`
+	IsPaid          bool               `json:"is_paid"`
+	AboutMe         pgtype.Text        `json:"about_me"`
+	SuspendedUntil  pgtype.Timestamptz `json:"suspended_until"`
+}
+
+type UserLocation struct {
+	UserID          string        `json:"user_id"`
+	Latitude        float64       `json:"latitude"`
+	Longitude       float64       `json:"longitude"`
+	ServiceRadiusKm pgtype.Float8 `json:"service_radius_km"`
+	Source          string        `json:"source"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+type UserRole struct {
+	UserID    string      `json:"user_id"`
+	Role      Role        `json:"role"`
+	GrantedBy pgtype.Text `json:"granted_by"`
+	GrantedAt time.Time   `json:"granted_at"`
 }
 
 type Warning struct {
@@ -13,6 +13,38 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const clearEmailPending = `-- name: ClearEmailPending :execrows
+UPDATE notification
+SET email_pending = false
+WHERE id = ANY($1::int[]) OR (email_pending AND (is_read OR archived_at IS NOT NULL))
+`
+
+func (q *Queries) ClearEmailPending(ctx context.Context, ids []int32) (int64, error) {
+	result, err := q.db.Exec(ctx, clearEmailPending, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteNotification = `-- name: DeleteNotification :execrows
+DELETE FROM notification
+WHERE id = $1 AND recipient_user_id = $2
+`
+
+type DeleteNotificationParams struct {
+	ID              int32  `json:"id"`
+	RecipientUserID string `json:"recipient_user_id"`
+}
+
+func (q *Queries) DeleteNotification(ctx context.Context, arg DeleteNotificationParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteNotification, arg.ID, arg.RecipientUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getAllEventOfARequest = `-- name: GetAllEventOfARequest :many
 SELECT e.type, e.target_id, e.created_at, e.id, e.description, n.action_user_id FROM "event" e
 JOIN notification n
@@ -58,27 +90,54 @@ func (q *Queries) GetAllEventOfARequest(ctx context.Context, targetID int32) ([]
 }
 
 const getNotifications = `-- name: GetNotifications :many
-SELECT n.id, message, recipient_user_id, action_user_id, is_read, event_id, type, target_id, created_at, ne.id, description FROM notification n
+SELECT n.id, n.message, n.recipient_user_id, n.action_user_id, n.is_read, n.event_id, n.archived_at,
+       ne.type, ne.target_id, ne.created_at, ne.description
+FROM notification n
 JOIN "event" ne ON ne.id = n.event_id
-WHERE recipient_user_id = $1
+WHERE n.recipient_user_id = $1
+  AND (n.archived_at IS NOT NULL) = $2::boolean
+  AND (NOT $3::boolean OR NOT n.is_read)
+  AND ($4::text IS NULL OR ne.type = $4::text)
+  AND ($5::timestamptz IS NULL
+       OR (ne.created_at, n.id) < ($5::timestamptz, $6::int))
+ORDER BY ne.created_at DESC, n.id DESC
+LIMIT $7
 `
 
+type GetNotificationsParams struct {
+	RecipientUserID string             `json:"recipient_user_id"`
+	Archived        bool               `json:"archived"`
+	UnreadOnly      bool               `json:"unread_only"`
+	EventType       pgtype.Text        `json:"event_type"`
+	BeforeTime      pgtype.Timestamptz `json:"before_time"`
+	BeforeID        pgtype.Int4        `json:"before_id"`
+	PageSize        int32              `json:"page_size"`
+}
+
 type GetNotificationsRow struct {
-	ID              int32       `json:"id"`
-	Message         string      `json:"message"`
-	RecipientUserID string      `json:"recipient_user_id"`
-	ActionUserID    pgtype.Text `json:"action_user_id"`
-	IsRead          bool        `json:"is_read"`
-	EventID         int64       `json:"event_id"`
-	Type            string      `json:"type"`
-	TargetID        int32       `json:"target_id"`
-	CreatedAt       time.Time   `json:"created_at"`
-	ID_2            int64       `json:"id_2"`
-	Description     string      `json:"description"`
+	ID              int32              `json:"id"`
+	Message         string             `json:"message"`
+	RecipientUserID string             `json:"recipient_user_id"`
+	ActionUserID    pgtype.Text        `json:"action_user_id"`
+	IsRead          bool               `json:"is_read"`
+	EventID         int64              `json:"event_id"`
+	ArchivedAt      pgtype.Timestamptz `json:"archived_at"`
+	Type            string             `json:"type"`
+	TargetID        int32              `json:"target_id"`
+	CreatedAt       time.Time          `json:"created_at"`
+	Description     string             `json:"description"`
 }
 
-func (q *Queries) GetNotifications(ctx context.Context, recipientUserID string) ([]GetNotificationsRow, error) {
-	rows, err := q.db.Query(ctx, getNotifications, recipientUserID)
+func (q *Queries) GetNotifications(ctx context.Context, arg GetNotificationsParams) ([]GetNotificationsRow, error) {
+	rows, err := q.db.Query(ctx, getNotifications,
+		arg.RecipientUserID,
+		arg.Archived,
+		arg.UnreadOnly,
+		arg.EventType,
+		arg.BeforeTime,
+		arg.BeforeID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -93,10 +152,10 @@ func (q *Queries) GetNotifications(ctx context.Context, recipientUserID string)
 			&i.ActionUserID,
 			&i.IsRead,
 			&i.EventID,
+			&i.ArchivedAt,
 			&i.Type,
 			&i.TargetID,
 			&i.CreatedAt,
-			&i.ID_2,
 			&i.Description,
 		); err != nil {
 			return nil, err
@@ -109,9 +168,54 @@ func (q *Queries) GetNotifications(ctx context.Context, recipientUserID string)
 	return items, nil
 }
 
+const getPendingEmailNotifications = `-- name: GetPendingEmailNotifications :many
+SELECT n.id, n.recipient_user_id, n.message, e.type, e.description, e.created_at
+FROM notification n
+JOIN "event" e ON e.id = n.event_id
+WHERE n.email_pending AND NOT n.is_read AND n.archived_at IS NULL
+ORDER BY n.recipient_user_id, e.created_at
+FOR UPDATE OF n SKIP LOCKED
+`
+
+type GetPendingEmailNotificationsRow struct {
+	ID              int32     `json:"id"`
+	RecipientUserID string    `json:"recipient_user_id"`
+	Message         string    `json:"message"`
+	Type            string    `json:"type"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (q *Queries) GetPendingEmailNotifications(ctx context.Context) ([]GetPendingEmailNotificationsRow, error) {
+	rows, err := q.db.Query(ctx, getPendingEmailNotifications)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPendingEmailNotificationsRow
+	for rows.Next() {
+		var i GetPendingEmailNotificationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RecipientUserID,
+			&i.Message,
+			&i.Type,
+			&i.Description,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUnreadNotificationCount = `-- name: GetUnreadNotificationCount :one
 SELECT COUNT(n.id) FROM notification n
-WHERE n.recipient_user_id = $1 AND n.is_read = false
+WHERE n.recipient_user_id = $1 AND n.is_read = false AND n.archived_at IS NULL
 `
 
 func (q *Queries) GetUnreadNotificationCount(ctx context.Context, recipientUserID string) (int64, error) {
@@ -141,8 +245,8 @@ func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (int64
 }
 
 const insertNotification = `-- name: InsertNotification :execresult
-INSERT INTO notification (message,recipient_user_id,action_user_id,is_read,event_id)
-VALUES ($1,$2,$3,false,$4)
+INSERT INTO notification (message,recipient_user_id,action_user_id,is_read,event_id,email_pending)
+VALUES ($1,$2,$3,false,$4,$5)
 `
 
 type InsertNotificationParams struct {
@@ -150,6 +254,7 @@ type InsertNotificationParams struct {
 	RecipientUserID string      `json:"recipient_user_id"`
 	ActionUserID    pgtype.Text `json:"action_user_id"`
 	EventID         int64       `json:"event_id"`
+	EmailPending    bool        `json:"email_pending"`
 }
 
 func (q *Queries) InsertNotification(ctx context.Context, arg InsertNotificationParams) (pgconn.CommandTag, error) {
@@ -158,6 +263,7 @@ func (q *Queries) InsertNotification(ctx context.Context, arg InsertNotification
 		arg.RecipientUserID,
 		arg.ActionUserID,
 		arg.EventID,
+		arg.EmailPending,
 	)
 }
 
@@ -181,6 +287,26 @@ func (q *Queries) SetAllNotificationsRead(ctx context.Context, arg SetAllNotific
 	return err
 }
 
+const setNotificationArchived = `-- name: SetNotificationArchived :execrows
+UPDATE notification
+SET archived_at = CASE WHEN $1::boolean THEN COALESCE(archived_at, NOW()) END
+WHERE id = $2 AND recipient_user_id = $3
+`
+
+type SetNotificationArchivedParams struct {
+	Archived        bool   `json:"archived"`
+	ID              int32  `json:"id"`
+	RecipientUserID string `json:"recipient_user_id"`
+}
+
+func (q *Queries) SetNotificationArchived(ctx context.Context, arg SetNotificationArchivedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setNotificationArchived, arg.Archived, arg.ID, arg.RecipientUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const setUserNotificationsRead = `-- name: SetUserNotificationsRead :execresult
 UPDATE notification
 SET is_read = true
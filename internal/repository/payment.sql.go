@@ -9,6 +9,33 @@ import (
 	"context"
 )
 
+const countUserDisputes = `-- name: CountUserDisputes :one
+SELECT count(p.id) FROM payment p
+JOIN service_request sr ON sr.id = p.service_request_id
+WHERE p.status = 'disputed' AND (sr.provider_id = $1 OR sr.requester_id = $1)
+`
+
+func (q *Queries) CountUserDisputes(ctx context.Context, userID string) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserDisputes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const freezePayment = `-- name: FreezePayment :execrows
+UPDATE payment
+SET status = 'disputed', updated_at = NOW()
+WHERE service_request_id = $1 AND status = 'holding'
+`
+
+func (q *Queries) FreezePayment(ctx context.Context, serviceRequestID int32) (int64, error) {
+	result, err := q.db.Exec(ctx, freezePayment, serviceRequestID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getPaymentHolding = `-- name: GetPaymentHolding :one
 SELECT id, service_request_id, payer_id, amount_tokens, status, created_at, updated_at FROM payment
 WHERE service_request_id = $1 AND payer_id = $2
@@ -77,6 +104,20 @@ func (q *Queries) InsertPaymentHolding(ctx context.Context, arg InsertPaymentHol
 	return id, err
 }
 
+const unfreezePayment = `-- name: UnfreezePayment :execrows
+UPDATE payment
+SET status = 'holding', updated_at = NOW()
+WHERE service_request_id = $1 AND status = 'disputed'
+`
+
+func (q *Queries) UnfreezePayment(ctx context.Context, serviceRequestID int32) (int64, error) {
+	result, err := q.db.Exec(ctx, unfreezePayment, serviceRequestID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updatePaymentHolding = `-- name: UpdatePaymentHolding :one
 UPDATE payment
 SET status = $1, updated_at = NOW()
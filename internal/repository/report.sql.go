@@ -7,10 +7,92 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countPendingListingReports = `-- name: CountPendingListingReports :one
+SELECT COUNT(*) FROM report
+WHERE listing_id = $1 AND status = 'pending'
+`
+
+func (q *Queries) CountPendingListingReports(ctx context.Context, listingID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countPendingListingReports, listingID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getListingReports = `-- name: GetListingReports :many
+SELECT
+  r.id,
+  r.listing_id,
+  sl.title AS listing_title,
+  sl.posted_by,
+  r.reporter_id,
+  u.full_name AS reporter_full_name,
+  r.datetime,
+  r.report_reason,
+  r.status,
+  r.additional_detail,
+  r.reviewed_by,
+  r.reviewed_at
+FROM report r
+JOIN service_listing sl ON sl.id = r.listing_id
+JOIN "user" u ON u.id = r.reporter_id
+WHERE r.status = $1
+ORDER BY r.datetime, r.id
+`
+
+type GetListingReportsRow struct {
+	ID               int32              `json:"id"`
+	ListingID        int32              `json:"listing_id"`
+	ListingTitle     string             `json:"listing_title"`
+	PostedBy         string             `json:"posted_by"`
+	ReporterID       string             `json:"reporter_id"`
+	ReporterFullName string             `json:"reporter_full_name"`
+	Datetime         time.Time          `json:"datetime"`
+	ReportReason     pgtype.Text        `json:"report_reason"`
+	Status           string             `json:"status"`
+	AdditionalDetail pgtype.Text        `json:"additional_detail"`
+	ReviewedBy       pgtype.Text        `json:"reviewed_by"`
+	ReviewedAt       pgtype.Timestamptz `json:"reviewed_at"`
+}
+
+func (q *Queries) GetListingReports(ctx context.Context, status string) ([]GetListingReportsRow, error) {
+	rows, err := q.db.Query(ctx, getListingReports, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetListingReportsRow
+	for rows.Next() {
+		var i GetListingReportsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ListingID,
+			&i.ListingTitle,
+			&i.PostedBy,
+			&i.ReporterID,
+			&i.ReporterFullName,
+			&i.Datetime,
+			&i.ReportReason,
+			&i.Status,
+			&i.AdditionalDetail,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const insertReport = `-- name: InsertReport :exec
 INSERT INTO report (listing_id,reporter_id,datetime,report_reason,status,additional_detail)
 VALUES ($1,$2,NOW(),$3,'pending',$4)
@@ -32,3 +114,43 @@ func (q *Queries) InsertReport(ctx context.Context, arg InsertReportParams) erro
 	)
 	return err
 }
+
+const reviewPendingListingReports = `-- name: ReviewPendingListingReports :execrows
+UPDATE report
+SET status = $1, reviewed_by = $2, reviewed_at = NOW()
+WHERE listing_id = $3 AND status = 'pending'
+`
+
+type ReviewPendingListingReportsParams struct {
+	Status     string      `json:"status"`
+	ReviewedBy pgtype.Text `json:"reviewed_by"`
+	ListingID  int32       `json:"listing_id"`
+}
+
+func (q *Queries) ReviewPendingListingReports(ctx context.Context, arg ReviewPendingListingReportsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reviewPendingListingReports, arg.Status, arg.ReviewedBy, arg.ListingID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const reviewReport = `-- name: ReviewReport :one
+UPDATE report
+SET status = $1, reviewed_by = $2, reviewed_at = NOW()
+WHERE id = $3 AND status = 'pending'
+RETURNING listing_id
+`
+
+type ReviewReportParams struct {
+	Status     string      `json:"status"`
+	ReviewedBy pgtype.Text `json:"reviewed_by"`
+	ID         int32       `json:"id"`
+}
+
+func (q *Queries) ReviewReport(ctx context.Context, arg ReviewReportParams) (int32, error) {
+	row := q.db.QueryRow(ctx, reviewReport, arg.Status, arg.ReviewedBy, arg.ID)
+	var listing_id int32
+	err := row.Scan(&listing_id)
+	return listing_id, err
+}
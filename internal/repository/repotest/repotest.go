@@ -0,0 +1,225 @@
+// Package repotest fakes the database behind repository.Queries so services
+// can be tested without Postgres. Queries are told apart by their sqlc name:
+// a test scripts the rows a query returns, runs the code under test and then
+// checks the arguments each query was called with.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Call is one query run against a DB.
+type Call struct {
+	Query string
+	Args  []any
+}
+
+// DB records the queries run against it and answers them from scripted
+// rows. Queries without rows return none: :one queries fail with
+// pgx.ErrNoRows and :exec queries affect no rows. It also stands in for a
+// transaction, which commits and rolls back without effect.
+type DB struct {
+	// the methods of pgx.Tx that DB does not fake panic if called
+	pgx.Tx
+
+	mu        sync.Mutex
+	calls     []Call
+	rows      map[string][][]any
+	errs      map[string]error
+	affected  map[string]int64
+	commits   int
+	rollbacks int
+}
+
+func New() *DB {
+	return &DB{rows: map[string][][]any{}, errs: map[string]error{}, affected: map[string]int64{}}
+}
+
+// Returns makes query answer with rows, each given as its column values in
+// order. Values are assigned to the scan targets as they are or converted
+// to their type; nil leaves the zero value.
+func (db *DB) Returns(query string, rows ...[]any) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.rows[query] = rows
+}
+
+// Fails makes query fail with err.
+func (db *DB) Fails(query string, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.errs[query] = err
+}
+
+// Affects makes the :exec query report n affected rows.
+func (db *DB) Affects(query string, n int64) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.affected[query] = n
+}
+
+// Calls returns the calls made to query, oldest first, or every call when
+// query is empty.
+func (db *DB) Calls(query string) []Call {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var calls []Call
+	for _, c := range db.calls {
+		if query == "" || c.Query == query {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Commits returns how many transactions begun on db were committed.
+func (db *DB) Commits() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.commits
+}
+
+func (db *DB) record(sql string, args []any) (string, error) {
+	name := queryName(sql)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.calls = append(db.calls, Call{Query: name, Args: args})
+	return name, db.errs[name]
+}
+
+// queryName reads the name sqlc puts on the first line of a query.
+func queryName(sql string) string {
+	line, _, _ := strings.Cut(sql, "\n")
+	fields := strings.Fields(line)
+	if len(fields) >= 3 && fields[0] == "--" && fields[1] == "name:" {
+		return fields[2]
+	}
+	return strings.TrimSpace(line)
+}
+
+func (db *DB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	name, err := db.record(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", db.affected[name])), nil
+}
+
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	name, err := db.record(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return &rows{rows: db.rows[name], i: -1}, nil
+}
+
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	name, err := db.record(sql, args)
+	if err != nil {
+		return row{err: err}
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.rows[name]) == 0 {
+		return row{err: pgx.ErrNoRows}
+	}
+	return row{values: db.rows[name][0]}
+}
+
+// Begin starts a transaction, which is db itself.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return db, nil
+}
+
+func (db *DB) Commit(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.commits++
+	return nil
+}
+
+func (db *DB) Rollback(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.rollbacks++
+	return nil
+}
+
+type row struct {
+	values []any
+	err    error
+}
+
+func (r row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scan(r.values, dest)
+}
+
+type rows struct {
+	rows [][]any
+	i    int
+	err  error
+}
+
+func (r *rows) Close()                                       {}
+func (r *rows) Err() error                                   { return r.err }
+func (r *rows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT") }
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *rows) RawValues() [][]byte                          { return nil }
+func (r *rows) Conn() *pgx.Conn                              { return nil }
+
+func (r *rows) Next() bool {
+	if r.err != nil || r.i+1 >= len(r.rows) {
+		return false
+	}
+	r.i++
+	return true
+}
+
+func (r *rows) Scan(dest ...any) error {
+	if err := scan(r.rows[r.i], dest); err != nil {
+		r.err = err
+		return err
+	}
+	return nil
+}
+
+func (r *rows) Values() ([]any, error) {
+	return r.rows[r.i], nil
+}
+
+func scan(values []any, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("repotest: row has %d values, scanning into %d targets", len(values), len(dest))
+	}
+	for i, v := range values {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if v == nil {
+			target.SetZero()
+			continue
+		}
+		value := reflect.ValueOf(v)
+		switch {
+		case value.Type().AssignableTo(target.Type()):
+			target.Set(value)
+		case value.Type().ConvertibleTo(target.Type()):
+			target.Set(value.Convert(target.Type()))
+		default:
+			return fmt.Errorf("repotest: cannot scan %T into %s for column %d", v, target.Type(), i)
+		}
+	}
+	return nil
+}
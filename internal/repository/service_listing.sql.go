@@ -35,15 +35,62 @@ join service_request sr
 on sr.listing_id = sl.id
 join review r
 on r.request_id  = sr.id
-group by sl.id)
-SELECT sl.id, sl.title, sl.description, sl.token_reward, sl.posted_by, sl.posted_at, sl.category, sl.image_url, sl.status, sl.contact_method, sl.session_duration,u.id uid,u.full_name,coalesce(lr.rating_count,0) as total_rating_count ,coalesce(lr.total_rating,0) as total_ratings FROM service_listing sl
+group by sl.id),
+listing_page as (
+SELECT sl.id, sl.title, sl.description, sl.token_reward, sl.posted_by, sl.posted_at, sl.category, sl.image_url, sl.status, sl.contact_method, sl.session_duration,u.id uid,u.full_name,coalesce(lr.rating_count,0) as total_rating_count ,coalesce(lr.total_rating,0) as total_ratings,
+  ll.latitude, ll.longitude, ll.service_radius_km,
+  (CASE $1::text
+    WHEN 'rating' THEN coalesce(lr.total_rating::float8 / nullif(lr.rating_count, 0), 0)
+    WHEN 'price' THEN -sl.token_reward::float8
+    WHEN 'distance' THEN -coalesce(6371 * 2 * asin(least(1, sqrt(power(sin(radians(ll.latitude - $2::float8) / 2), 2) + cos(radians($2::float8)) * cos(radians(ll.latitude)) * power(sin(radians(ll.longitude - $3::float8) / 2), 2)))), 0)
+    ELSE extract(epoch FROM sl.posted_at)::float8
+  END)::float8 AS sort_value
+FROM service_listing sl
 JOIN "user" u
 ON u.id = sl.posted_by
 LEFT JOIN listing_rating lr
 ON lr.listing_id = sl.id
-WHERE sl.posted_by != $1 AND sl.status = 'active'
+LEFT JOIN rating pr
+ON pr.user_id = sl.posted_by
+LEFT JOIN listing_location ll
+ON ll.listing_id = sl.id
+WHERE sl.posted_by != $4 AND sl.status = 'active'
+  AND ($5::text IS NULL OR sl.category IN (
+    WITH RECURSIVE subtree AS (
+      SELECT id, slug FROM category WHERE slug = $5
+      UNION ALL
+      SELECT c.id, c.slug FROM category c JOIN subtree ON c.parent_id = subtree.id)
+    SELECT slug FROM subtree))
+  AND ($6::int IS NULL OR sl.token_reward >= $6)
+  AND ($7::int IS NULL OR sl.token_reward <= $7)
+  AND ($8::float8 IS NULL OR coalesce(pr.total_ratings::float8 / nullif(pr.rating_count, 0), 0) >= $8)
+  AND ($9::timestamptz IS NULL OR sl.posted_at >= $9)
+  AND ($10::timestamptz IS NULL OR sl.posted_at < $10)
+  AND ($2::float8 IS NULL OR (ll.listing_id IS NOT NULL
+    AND 6371 * 2 * asin(least(1, sqrt(power(sin(radians(ll.latitude - $2::float8) / 2), 2) + cos(radians($2::float8)) * cos(radians(ll.latitude)) * power(sin(radians(ll.longitude - $3::float8) / 2), 2)))) <= least(coalesce(ll.service_radius_km, 'Infinity'::float8), coalesce($11::float8, 'Infinity'::float8)))))
+SELECT id, title, description, token_reward, posted_by, posted_at, category, image_url, status, contact_method, session_duration, uid, full_name, total_rating_count, total_ratings, latitude, longitude, service_radius_km, sort_value FROM listing_page
+WHERE $12::float8 IS NULL OR (sort_value, id) < ($12::float8, $13::int)
+ORDER BY sort_value DESC, id DESC
+LIMIT $14
 `
 
+type GetAllListingsParams struct {
+	Sort              string             `json:"sort"`
+	NearLat           pgtype.Float8      `json:"near_lat"`
+	NearLng           pgtype.Float8      `json:"near_lng"`
+	PostedBy          string             `json:"posted_by"`
+	Category          pgtype.Text        `json:"category"`
+	MinReward         pgtype.Int4        `json:"min_reward"`
+	MaxReward         pgtype.Int4        `json:"max_reward"`
+	MinProviderRating pgtype.Float8      `json:"min_provider_rating"`
+	PostedAfter       pgtype.Timestamptz `json:"posted_after"`
+	PostedBefore      pgtype.Timestamptz `json:"posted_before"`
+	MaxDistanceKm     pgtype.Float8      `json:"max_distance_km"`
+	CursorValue       pgtype.Float8      `json:"cursor_value"`
+	CursorID          pgtype.Int4        `json:"cursor_id"`
+	PageSize          int32              `json:"page_size"`
+}
+
 type GetAllListingsRow struct {
 	ID               int32           `json:"id"`
 	Title            string          `json:"title"`
@@ -60,10 +107,30 @@ type GetAllListingsRow struct {
 	FullName         string          `json:"full_name"`
 	TotalRatingCount int64           `json:"total_rating_count"`
 	TotalRatings     int64           `json:"total_ratings"`
+	Latitude         pgtype.Float8   `json:"latitude"`
+	Longitude        pgtype.Float8   `json:"longitude"`
+	ServiceRadiusKm  pgtype.Float8   `json:"service_radius_km"`
+	SortValue        float64         `json:"sort_value"`
 }
 
-func (q *Queries) GetAllListings(ctx context.Context, postedBy string) ([]GetAllListingsRow, error) {
-	rows, err := q.db.Query(ctx, getAllListings, postedBy)
+// the filters are repeated in SearchListings and the two must be kept identical
+func (q *Queries) GetAllListings(ctx context.Context, arg GetAllListingsParams) ([]GetAllListingsRow, error) {
+	rows, err := q.db.Query(ctx, getAllListings,
+		arg.Sort,
+		arg.NearLat,
+		arg.NearLng,
+		arg.PostedBy,
+		arg.Category,
+		arg.MinReward,
+		arg.MaxReward,
+		arg.MinProviderRating,
+		arg.PostedAfter,
+		arg.PostedBefore,
+		arg.MaxDistanceKm,
+		arg.CursorValue,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +154,10 @@ func (q *Queries) GetAllListings(ctx context.Context, postedBy string) ([]GetAll
 			&i.FullName,
 			&i.TotalRatingCount,
 			&i.TotalRatings,
+			&i.Latitude,
+			&i.Longitude,
+			&i.ServiceRadiusKm,
+			&i.SortValue,
 		); err != nil {
 			return nil, err
 		}
@@ -105,7 +176,7 @@ ON u.id = sl.posted_by
 LEFT JOIN service_request sr ON sr.listing_id = sl.id AND sr.activity = 'active' AND sr.requester_id = $2
 LEFT JOIN rating r ON r.user_id = sl.posted_by
 LEFT JOIN warning w
-ON w.listing_id = w.id
+ON w.listing_id = sl.id
 WHERE sl.id = $1 and sl.status = 'active'
 `
 
@@ -165,6 +236,32 @@ func (q *Queries) GetListingByID(ctx context.Context, arg GetListingByIDParams)
 	return i, err
 }
 
+const getListingModeration = `-- name: GetListingModeration :one
+SELECT sl.posted_by, sl.title, sl.status, w.id AS warning_id
+FROM service_listing sl
+LEFT JOIN warning w ON w.listing_id = sl.id
+WHERE sl.id = $1
+`
+
+type GetListingModerationRow struct {
+	PostedBy  string      `json:"posted_by"`
+	Title     string      `json:"title"`
+	Status    string      `json:"status"`
+	WarningID pgtype.Int4 `json:"warning_id"`
+}
+
+func (q *Queries) GetListingModeration(ctx context.Context, id int32) (GetListingModerationRow, error) {
+	row := q.db.QueryRow(ctx, getListingModeration, id)
+	var i GetListingModerationRow
+	err := row.Scan(
+		&i.PostedBy,
+		&i.Title,
+		&i.Status,
+		&i.WarningID,
+	)
+	return i, err
+}
+
 const getPartialListingsByUserID = `-- name: GetPartialListingsByUserID :many
 with listing_rating as (
 select sl.id as listing_id ,sum(r.rating ) as total_rating,count(r.id )as rating_count from service_listing sl
@@ -280,6 +377,31 @@ func (q *Queries) GetUserListings(ctx context.Context, postedBy string) ([]GetUs
 	return items, nil
 }
 
+const hideReportedListing = `-- name: HideReportedListing :one
+UPDATE service_listing
+SET status = 'under_review'
+WHERE id = $1 AND status = 'active'
+  AND (SELECT COUNT(*) FROM report WHERE listing_id = $1 AND status = 'pending') >= $2::int
+RETURNING posted_by, title
+`
+
+type HideReportedListingParams struct {
+	ID        int32 `json:"id"`
+	Threshold int32 `json:"threshold"`
+}
+
+type HideReportedListingRow struct {
+	PostedBy string `json:"posted_by"`
+	Title    string `json:"title"`
+}
+
+func (q *Queries) HideReportedListing(ctx context.Context, arg HideReportedListingParams) (HideReportedListingRow, error) {
+	row := q.db.QueryRow(ctx, hideReportedListing, arg.ID, arg.Threshold)
+	var i HideReportedListingRow
+	err := row.Scan(&i.PostedBy, &i.Title)
+	return i, err
+}
+
 const insertListing = `-- name: InsertListing :one
 INSERT INTO service_listing (title,"description",token_reward,posted_by,category,image_url,posted_at,status,session_duration,contact_method)
 VALUES ($1, $2, $3, $4,$5,$6, NOW(),'active',$7,$8)
@@ -313,6 +435,185 @@ func (q *Queries) InsertListing(ctx context.Context, arg InsertListingParams) (i
 	return id, err
 }
 
+const restoreListing = `-- name: RestoreListing :execrows
+UPDATE service_listing
+SET status = 'active'
+WHERE id = $1 AND status IN ('under_review', 'removed')
+`
+
+func (q *Queries) RestoreListing(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreListing, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const searchListings = `-- name: SearchListings :many
+with listing_rating as (
+select sl.id as listing_id ,sum(r.rating ) as total_rating,count(r.id )as rating_count from service_listing sl
+join service_request sr
+on sr.listing_id = sl.id
+join review r
+on r.request_id  = sr.id
+group by sl.id),
+listing_match as (
+SELECT sl.id, sl.title, sl.description, sl.token_reward, sl.posted_by, sl.posted_at, sl.category, sl.image_url, sl.status, sl.contact_method, sl.session_duration,u.id uid,u.full_name,coalesce(lr.rating_count,0) as total_rating_count ,coalesce(lr.total_rating,0) as total_ratings,
+  ll.latitude, ll.longitude, ll.service_radius_km,
+  (CASE $1::text
+    WHEN 'newest' THEN extract(epoch FROM sl.posted_at)::float8
+    WHEN 'rating' THEN coalesce(lr.total_rating::float8 / nullif(lr.rating_count, 0), 0)
+    WHEN 'price' THEN -sl.token_reward::float8
+    ELSE ts_rank_cd((setweight(to_tsvector('english', sl.title), 'A') || setweight(to_tsvector('english', sl.category), 'B') || setweight(to_tsvector('english', sl.description), 'C')), to_tsquery('english', $2::text))::float8
+  END)::float8 AS sort_value
+FROM service_listing sl
+JOIN "user" u
+ON u.id = sl.posted_by
+LEFT JOIN listing_rating lr
+ON lr.listing_id = sl.id
+LEFT JOIN rating pr
+ON pr.user_id = sl.posted_by
+LEFT JOIN listing_location ll
+ON ll.listing_id = sl.id
+WHERE (setweight(to_tsvector('english', sl.title), 'A') || setweight(to_tsvector('english', sl.category), 'B') || setweight(to_tsvector('english', sl.description), 'C')) @@ to_tsquery('english', $2::text)
+  AND sl.posted_by != $3 AND sl.status = 'active'
+  AND ($4::text IS NULL OR sl.category IN (
+    WITH RECURSIVE subtree AS (
+      SELECT id, slug FROM category WHERE slug = $4
+      UNION ALL
+      SELECT c.id, c.slug FROM category c JOIN subtree ON c.parent_id = subtree.id)
+    SELECT slug FROM subtree))
+  AND ($5::int IS NULL OR sl.token_reward >= $5)
+  AND ($6::int IS NULL OR sl.token_reward <= $6)
+  AND ($7::float8 IS NULL OR coalesce(pr.total_ratings::float8 / nullif(pr.rating_count, 0), 0) >= $7)
+  AND ($8::timestamptz IS NULL OR sl.posted_at >= $8)
+  AND ($9::timestamptz IS NULL OR sl.posted_at < $9)
+  AND ($10::float8 IS NULL OR (ll.listing_id IS NOT NULL
+    AND 6371 * 2 * asin(least(1, sqrt(power(sin(radians(ll.latitude - $10::float8) / 2), 2) + cos(radians($10::float8)) * cos(radians(ll.latitude)) * power(sin(radians(ll.longitude - $11::float8) / 2), 2)))) <= least(coalesce(ll.service_radius_km, 'Infinity'::float8), coalesce($12::float8, 'Infinity'::float8)))))
+SELECT listing_match.id, listing_match.title, listing_match.description, listing_match.token_reward, listing_match.posted_by, listing_match.posted_at, listing_match.category, listing_match.image_url, listing_match.status, listing_match.contact_method, listing_match.session_duration, listing_match.uid, listing_match.full_name, listing_match.total_rating_count, listing_match.total_ratings, listing_match.latitude, listing_match.longitude, listing_match.service_radius_km, listing_match.sort_value,
+  ts_headline('english', translate(description, chr(57344) || chr(57345), ''), to_tsquery('english', $2::text), 'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', MaxWords=30, MinWords=10, MaxFragments=2')::text AS snippet
+FROM listing_match
+WHERE $13::float8 IS NULL OR (sort_value, id) < ($13::float8, $14::int)
+ORDER BY sort_value DESC, id DESC
+LIMIT $15
+`
+
+type SearchListingsParams struct {
+	Sort              string             `json:"sort"`
+	Query             string             `json:"query"`
+	PostedBy          string             `json:"posted_by"`
+	Category          pgtype.Text        `json:"category"`
+	MinReward         pgtype.Int4        `json:"min_reward"`
+	MaxReward         pgtype.Int4        `json:"max_reward"`
+	MinProviderRating pgtype.Float8      `json:"min_provider_rating"`
+	PostedAfter       pgtype.Timestamptz `json:"posted_after"`
+	PostedBefore      pgtype.Timestamptz `json:"posted_before"`
+	NearLat           pgtype.Float8      `json:"near_lat"`
+	NearLng           pgtype.Float8      `json:"near_lng"`
+	MaxDistanceKm     pgtype.Float8      `json:"max_distance_km"`
+	CursorValue       pgtype.Float8      `json:"cursor_value"`
+	CursorID          pgtype.Int4        `json:"cursor_id"`
+	PageSize          int32              `json:"page_size"`
+}
+
+type SearchListingsRow struct {
+	ID               int32           `json:"id"`
+	Title            string          `json:"title"`
+	Description      string          `json:"description"`
+	TokenReward      int32           `json:"token_reward"`
+	PostedBy         string          `json:"posted_by"`
+	PostedAt         time.Time       `json:"posted_at"`
+	Category         string          `json:"category"`
+	ImageUrl         pgtype.Text     `json:"image_url"`
+	Status           string          `json:"status"`
+	ContactMethod    pgtype.Text     `json:"contact_method"`
+	SessionDuration  pgtype.Interval `json:"session_duration"`
+	Uid              string          `json:"uid"`
+	FullName         string          `json:"full_name"`
+	TotalRatingCount int64           `json:"total_rating_count"`
+	TotalRatings     int64           `json:"total_ratings"`
+	Latitude         pgtype.Float8   `json:"latitude"`
+	Longitude        pgtype.Float8   `json:"longitude"`
+	ServiceRadiusKm  pgtype.Float8   `json:"service_radius_km"`
+	SortValue        float64         `json:"sort_value"`
+	Snippet          string          `json:"snippet"`
+}
+
+// the tsvector expression must match idx_service_listing_search for the index to be used.
+// the filters are repeated from GetAllListings and the two must be kept identical.
+// the snippet marks matches with U+E000 and U+E001, stripped from the description
+// beforehand, for the caller to escape the text and swap in its own tags.
+func (q *Queries) SearchListings(ctx context.Context, arg SearchListingsParams) ([]SearchListingsRow, error) {
+	rows, err := q.db.Query(ctx, searchListings,
+		arg.Sort,
+		arg.Query,
+		arg.PostedBy,
+		arg.Category,
+		arg.MinReward,
+		arg.MaxReward,
+		arg.MinProviderRating,
+		arg.PostedAfter,
+		arg.PostedBefore,
+		arg.NearLat,
+		arg.NearLng,
+		arg.MaxDistanceKm,
+		arg.CursorValue,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchListingsRow
+	for rows.Next() {
+		var i SearchListingsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.TokenReward,
+			&i.PostedBy,
+			&i.PostedAt,
+			&i.Category,
+			&i.ImageUrl,
+			&i.Status,
+			&i.ContactMethod,
+			&i.SessionDuration,
+			&i.Uid,
+			&i.FullName,
+			&i.TotalRatingCount,
+			&i.TotalRatings,
+			&i.Latitude,
+			&i.Longitude,
+			&i.ServiceRadiusKm,
+			&i.SortValue,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unhideListing = `-- name: UnhideListing :execrows
+UPDATE service_listing
+SET status = 'active'
+WHERE id = $1 AND status = 'under_review'
+`
+
+func (q *Queries) UnhideListing(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, unhideListing, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateListing = `-- name: UpdateListing :execrows
 UPDATE service_listing
 SET title = $1, description = $2, token_reward = $3, category=$4, image_url = $5, session_duration = $6, contact_method = $7
@@ -348,3 +649,27 @@ func (q *Queries) UpdateListing(ctx context.Context, arg UpdateListingParams) (i
 	}
 	return result.RowsAffected(), nil
 }
+
+const updateListingStatus = `-- name: UpdateListingStatus :one
+UPDATE service_listing
+SET status = $1
+WHERE id = $2
+RETURNING posted_by, title
+`
+
+type UpdateListingStatusParams struct {
+	Status string `json:"status"`
+	ID     int32  `json:"id"`
+}
+
+type UpdateListingStatusRow struct {
+	PostedBy string `json:"posted_by"`
+	Title    string `json:"title"`
+}
+
+func (q *Queries) UpdateListingStatus(ctx context.Context, arg UpdateListingStatusParams) (UpdateListingStatusRow, error) {
+	row := q.db.QueryRow(ctx, updateListingStatus, arg.Status, arg.ID)
+	var i UpdateListingStatusRow
+	err := row.Scan(&i.PostedBy, &i.Title)
+	return i, err
+}
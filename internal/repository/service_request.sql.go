@@ -17,12 +17,15 @@ SELECT
     sr.id, sr.listing_id, sr.requester_id, sr.provider_id, sr.status_detail, sr.activity, sr.created_at, sr.updated_at, sr.token_reward,
     requester.full_name AS requester_name,
     provider.full_name  AS provider_name,
-    l.title
+    l.title,
+    rs.starts_at AS slot_starts_at,
+    rs.ends_at AS slot_ends_at
 FROM
     service_request sr
 JOIN "user" requester ON sr.requester_id = requester.id
 JOIN "user" provider  ON sr.provider_id = provider.id
 JOIN service_listing l on sr.listing_id  = l.id
+LEFT JOIN service_request_slot rs ON rs.request_id = sr.id
 WHERE
     (sr.provider_id = $1 OR sr.requester_id = $1)
     AND sr.activity = 'active'
@@ -41,6 +44,8 @@ type GetActiveUserServiceRequestsRow struct {
 	RequesterName string               `json:"requester_name"`
 	ProviderName  string               `json:"provider_name"`
 	Title         string               `json:"title"`
+	SlotStartsAt  pgtype.Timestamptz   `json:"slot_starts_at"`
+	SlotEndsAt    pgtype.Timestamptz   `json:"slot_ends_at"`
 }
 
 func (q *Queries) GetActiveUserServiceRequests(ctx context.Context, providerID string) ([]GetActiveUserServiceRequestsRow, error) {
@@ -65,6 +70,8 @@ func (q *Queries) GetActiveUserServiceRequests(ctx context.Context, providerID s
 			&i.RequesterName,
 			&i.ProviderName,
 			&i.Title,
+			&i.SlotStartsAt,
+			&i.SlotEndsAt,
 		); err != nil {
 			return nil, err
 		}
@@ -140,7 +147,7 @@ func (q *Queries) GetAllUserRequests(ctx context.Context, userID string) ([]GetA
 }
 
 const getAllUserTickets = `-- name: GetAllUserTickets :many
-select rr.id, rr.reporter_id, rr.request_id, rr.ticket_id, rr.created_at, rr.status, rr.updated_at,sl.title from request_report rr
+select rr.id, rr.reporter_id, rr.request_id, rr.ticket_id, rr.created_at, rr.status, rr.updated_at, rr.description, rr.evidence_urls, rr.resolution, rr.requester_share, rr.resolution_note, rr.resolved_by, rr.resolved_at,sl.title from request_report rr
 join service_request sr 
 on sr.id = rr.request_id
 join service_listing sl
@@ -149,14 +156,21 @@ where reporter_id = $1
 `
 
 type GetAllUserTicketsRow struct {
-	ID         int32     `json:"id"`
-	ReporterID string    `json:"reporter_id"`
-	RequestID  int32     `json:"request_id"`
-	TicketID   string    `json:"ticket_id"`
-	CreatedAt  time.Time `json:"created_at"`
-	Status     string    `json:"status"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	Title      string    `json:"title"`
+	ID             int32              `json:"id"`
+	ReporterID     string             `json:"reporter_id"`
+	RequestID      int32              `json:"request_id"`
+	TicketID       string             `json:"ticket_id"`
+	CreatedAt      time.Time          `json:"created_at"`
+	Status         string             `json:"status"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	Description    string             `json:"description"`
+	EvidenceUrls   []string           `json:"evidence_urls"`
+	Resolution     pgtype.Text        `json:"resolution"`
+	RequesterShare pgtype.Int4        `json:"requester_share"`
+	ResolutionNote pgtype.Text        `json:"resolution_note"`
+	ResolvedBy     pgtype.Text        `json:"resolved_by"`
+	ResolvedAt     pgtype.Timestamptz `json:"resolved_at"`
+	Title          string             `json:"title"`
 }
 
 func (q *Queries) GetAllUserTickets(ctx context.Context, reporterID string) ([]GetAllUserTicketsRow, error) {
@@ -176,6 +190,13 @@ func (q *Queries) GetAllUserTickets(ctx context.Context, reporterID string) ([]G
 			&i.CreatedAt,
 			&i.Status,
 			&i.UpdatedAt,
+			&i.Description,
+			&i.EvidenceUrls,
+			&i.Resolution,
+			&i.RequesterShare,
+			&i.ResolutionNote,
+			&i.ResolvedBy,
+			&i.ResolvedAt,
 			&i.Title,
 		); err != nil {
 			return nil, err
@@ -188,6 +209,204 @@ func (q *Queries) GetAllUserTickets(ctx context.Context, reporterID string) ([]G
 	return items, nil
 }
 
+const getDuePendingReminders = `-- name: GetDuePendingReminders :many
+SELECT
+  sr.id AS request_id,
+  sr.provider_id,
+  (sr.updated_at + COALESCE(cp.pending_timeout, pp.pending_timeout, $1::interval))::timestamptz AS expires_at,
+  sl.title AS listing_title,
+  ru.full_name AS requester_full_name
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN "user" AS ru ON ru.id = sr.requester_id
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'pending'
+  AND COALESCE(cp.pending_timeout, pp.pending_timeout, $1::interval) > INTERVAL '0'
+  AND sr.updated_at + COALESCE(cp.pending_timeout, pp.pending_timeout, $1::interval)
+      BETWEEN NOW() AND NOW() + make_interval(mins => $2::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM pending_request_reminder prr
+    WHERE prr.request_id = sr.id AND prr.lead_minutes <= $2::int
+  )
+FOR UPDATE OF sr SKIP LOCKED
+`
+
+type GetDuePendingRemindersParams struct {
+	PendingTimeout pgtype.Interval `json:"pending_timeout"`
+	LeadMinutes    int32           `json:"lead_minutes"`
+}
+
+type GetDuePendingRemindersRow struct {
+	RequestID         int32     `json:"request_id"`
+	ProviderID        string    `json:"provider_id"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	ListingTitle      string    `json:"listing_title"`
+	RequesterFullName string    `json:"requester_full_name"`
+}
+
+// pending requests that expire within a checkpoint and have not been
+// reminded at that checkpoint or a later one
+func (q *Queries) GetDuePendingReminders(ctx context.Context, arg GetDuePendingRemindersParams) ([]GetDuePendingRemindersRow, error) {
+	rows, err := q.db.Query(ctx, getDuePendingReminders, arg.PendingTimeout, arg.LeadMinutes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDuePendingRemindersRow
+	for rows.Next() {
+		var i GetDuePendingRemindersRow
+		if err := rows.Scan(
+			&i.RequestID,
+			&i.ProviderID,
+			&i.ExpiresAt,
+			&i.ListingTitle,
+			&i.RequesterFullName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredRequests = `-- name: GetExpiredRequests :many
+SELECT
+  sr.id as request_id,
+  sr.listing_id,
+  sr.status_detail,
+  sr.updated_at,
+  sr.requester_id,
+  sr.provider_id,
+  sr.token_reward,
+  sl.title AS listing_title,
+  ru.full_name AS requester_full_name
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN "user" AS ru ON ru.id = sr.requester_id
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'pending'
+  AND COALESCE(cp.pending_timeout, pp.pending_timeout, $1::interval) > INTERVAL '0'
+  AND sr.updated_at + COALESCE(cp.pending_timeout, pp.pending_timeout, $1::interval) < NOW()
+  AND NOT EXISTS (
+    SELECT 1 FROM payment p
+    WHERE p.service_request_id = sr.id AND p.status = 'disputed'
+  )
+FOR UPDATE OF sr SKIP LOCKED
+`
+
+type GetExpiredRequestsRow struct {
+	RequestID         int32                `json:"request_id"`
+	ListingID         int32                `json:"listing_id"`
+	StatusDetail      ServiceRequestStatus `json:"status_detail"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+	RequesterID       string               `json:"requester_id"`
+	ProviderID        string               `json:"provider_id"`
+	TokenReward       int32                `json:"token_reward"`
+	ListingTitle      string               `json:"listing_title"`
+	RequesterFullName string               `json:"requester_full_name"`
+}
+
+func (q *Queries) GetExpiredRequests(ctx context.Context, pendingTimeout pgtype.Interval) ([]GetExpiredRequestsRow, error) {
+	rows, err := q.db.Query(ctx, getExpiredRequests, pendingTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpiredRequestsRow
+	for rows.Next() {
+		var i GetExpiredRequestsRow
+		if err := rows.Scan(
+			&i.RequestID,
+			&i.ListingID,
+			&i.StatusDetail,
+			&i.UpdatedAt,
+			&i.RequesterID,
+			&i.ProviderID,
+			&i.TokenReward,
+			&i.ListingTitle,
+			&i.RequesterFullName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHalfConfirmedRequests = `-- name: GetHalfConfirmedRequests :many
+SELECT
+  sr.id AS request_id,
+  sr.status_detail,
+  sr.requester_id,
+  sr.provider_id,
+  sl.title AS listing_title,
+  src.requester_completed
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN service_request_completion AS src ON src.request_id = sr.id
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'in_progress'
+  AND src.is_active
+  AND src.requester_completed <> src.provider_completed
+  AND COALESCE(cp.auto_complete_after, pp.auto_complete_after, $1::interval) > INTERVAL '0'
+  AND COALESCE(src.confirmed_at, sr.updated_at) + COALESCE(cp.auto_complete_after, pp.auto_complete_after, $1::interval) < NOW()
+  AND NOT EXISTS (
+    SELECT 1 FROM payment p
+    WHERE p.service_request_id = sr.id AND p.status = 'disputed'
+  )
+FOR UPDATE OF sr SKIP LOCKED
+`
+
+type GetHalfConfirmedRequestsRow struct {
+	RequestID          int32                `json:"request_id"`
+	StatusDetail       ServiceRequestStatus `json:"status_detail"`
+	RequesterID        string               `json:"requester_id"`
+	ProviderID         string               `json:"provider_id"`
+	ListingTitle       string               `json:"listing_title"`
+	RequesterCompleted bool                 `json:"requester_completed"`
+}
+
+// accepted requests one side confirmed and the other left unanswered for
+// longer than the auto-complete delay
+func (q *Queries) GetHalfConfirmedRequests(ctx context.Context, autoCompleteAfter pgtype.Interval) ([]GetHalfConfirmedRequestsRow, error) {
+	rows, err := q.db.Query(ctx, getHalfConfirmedRequests, autoCompleteAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetHalfConfirmedRequestsRow
+	for rows.Next() {
+		var i GetHalfConfirmedRequestsRow
+		if err := rows.Scan(
+			&i.RequestID,
+			&i.StatusDetail,
+			&i.RequesterID,
+			&i.ProviderID,
+			&i.ListingTitle,
+			&i.RequesterCompleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getProvidingeRequests = `-- name: GetProvidingeRequests :many
 select sr.id,sl.title from service_request sr
 join service_listing sl 
@@ -248,6 +467,7 @@ SELECT
   rr.status as report_status,
   rr.reporter_id,
   rr.updated_at as report_updated_at,
+  p.status as escrow_status,
   COALESCE(sc.requester_completed,false),
   COALESCE(sc.provider_completed,false),
   COALESCE(
@@ -267,14 +487,19 @@ JOIN service_listing sl ON sr.listing_id = sl.id
 JOIN "user" ru ON sr.requester_id = ru.id
 JOIN "user" pu ON sr.provider_id = pu.id
 LEFT JOIN service_request_completion sc ON sr.id = sc.request_id
+LEFT JOIN payment p ON p.service_request_id = sr.id
 LEFT JOIN "event" e ON e.target_id = sr.id
 LEFT JOIN notification n
 ON n.event_id = e.id
-LEFT JOIN request_report rr
-ON rr.request_id = sr.id
+LEFT JOIN LATERAL (
+  SELECT id, reporter_id, request_id, ticket_id, created_at, status, updated_at FROM request_report
+  WHERE request_id = sr.id
+  ORDER BY created_at DESC, id DESC
+  LIMIT 1
+) rr ON true
 WHERE sr.id = $1
 GROUP BY
-  sr.id, sl.id, ru.id, pu.id, sc.requester_completed, sc.provider_completed,rr.id
+  sr.id, sl.id, ru.id, pu.id, sc.requester_completed, sc.provider_completed,rr.id, rr.status, rr.reporter_id, rr.updated_at, p.status
 `
 
 type GetRequestByIDRow struct {
@@ -301,6 +526,7 @@ type GetRequestByIDRow struct {
 	ReportStatus       pgtype.Text          `json:"report_status"`
 	ReporterID         pgtype.Text          `json:"reporter_id"`
 	ReportUpdatedAt    pgtype.Timestamptz   `json:"report_updated_at"`
+	EscrowStatus       NullPaymentStatus    `json:"escrow_status"`
 	RequesterCompleted bool                 `json:"requester_completed"`
 	ProviderCompleted  bool                 `json:"provider_completed"`
 	Events             []byte               `json:"events"`
@@ -333,6 +559,7 @@ func (q *Queries) GetRequestByID(ctx context.Context, id int32) (GetRequestByIDR
 		&i.ReportStatus,
 		&i.ReporterID,
 		&i.ReportUpdatedAt,
+		&i.EscrowStatus,
 		&i.RequesterCompleted,
 		&i.ProviderCompleted,
 		&i.Events,
@@ -340,33 +567,8 @@ func (q *Queries) GetRequestByID(ctx context.Context, id int32) (GetRequestByIDR
 	return i, err
 }
 
-const getRequestReport = `-- name: GetRequestReport :one
-SELECT id, reporter_id, request_id, ticket_id, created_at, status, updated_at FROM request_report
-WHERE request_id = $1 AND reporter_id = $2
-`
-
-type GetRequestReportParams struct {
-	RequestID  int32  `json:"request_id"`
-	ReporterID string `json:"reporter_id"`
-}
-
-func (q *Queries) GetRequestReport(ctx context.Context, arg GetRequestReportParams) (RequestReport, error) {
-	row := q.db.QueryRow(ctx, getRequestReport, arg.RequestID, arg.ReporterID)
-	var i RequestReport
-	err := row.Scan(
-		&i.ID,
-		&i.ReporterID,
-		&i.RequestID,
-		&i.TicketID,
-		&i.CreatedAt,
-		&i.Status,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
 const getServiceRequestCompletion = `-- name: GetServiceRequestCompletion :one
-SELECT id, request_id, requester_completed, provider_completed, is_active FROM service_request_completion
+SELECT id, request_id, requester_completed, provider_completed, is_active, confirmed_at FROM service_request_completion
 WHERE request_id = $1
 `
 
@@ -379,10 +581,92 @@ func (q *Queries) GetServiceRequestCompletion(ctx context.Context, requestID int
 		&i.RequesterCompleted,
 		&i.ProviderCompleted,
 		&i.IsActive,
+		&i.ConfirmedAt,
 	)
 	return i, err
 }
 
+const getStalledRequests = `-- name: GetStalledRequests :many
+SELECT
+  sr.id AS request_id,
+  sr.status_detail,
+  sr.requester_id,
+  sr.provider_id,
+  sl.title AS listing_title,
+  ru.full_name AS requester_full_name
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN "user" AS ru ON ru.id = sr.requester_id
+JOIN service_request_completion AS src ON src.request_id = sr.id
+LEFT JOIN service_request_slot AS rs ON rs.request_id = sr.id AND NOT rs.released
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'in_progress'
+  AND NOT src.requester_completed AND NOT src.provider_completed
+  AND COALESCE(cp.in_progress_timeout, pp.in_progress_timeout, $1::interval) > INTERVAL '0'
+  AND GREATEST(sr.updated_at, rs.ends_at) + COALESCE(cp.in_progress_timeout, pp.in_progress_timeout, $1::interval) < NOW()
+  AND NOT EXISTS (
+    SELECT 1 FROM payment p
+    WHERE p.service_request_id = sr.id AND p.status = 'disputed'
+  )
+FOR UPDATE OF sr SKIP LOCKED
+`
+
+type GetStalledRequestsRow struct {
+	RequestID         int32                `json:"request_id"`
+	StatusDetail      ServiceRequestStatus `json:"status_detail"`
+	RequesterID       string               `json:"requester_id"`
+	ProviderID        string               `json:"provider_id"`
+	ListingTitle      string               `json:"listing_title"`
+	RequesterFullName string               `json:"requester_full_name"`
+}
+
+// accepted requests neither side has confirmed within the in-progress
+// timeout, counted from the end of the booked session if there is one
+func (q *Queries) GetStalledRequests(ctx context.Context, inProgressTimeout pgtype.Interval) ([]GetStalledRequestsRow, error) {
+	rows, err := q.db.Query(ctx, getStalledRequests, inProgressTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStalledRequestsRow
+	for rows.Next() {
+		var i GetStalledRequestsRow
+		if err := rows.Scan(
+			&i.RequestID,
+			&i.StatusDetail,
+			&i.RequesterID,
+			&i.ProviderID,
+			&i.ListingTitle,
+			&i.RequesterFullName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertPendingReminder = `-- name: InsertPendingReminder :exec
+INSERT INTO pending_request_reminder (request_id, lead_minutes, sent_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type InsertPendingReminderParams struct {
+	RequestID   int32 `json:"request_id"`
+	LeadMinutes int32 `json:"lead_minutes"`
+}
+
+func (q *Queries) InsertPendingReminder(ctx context.Context, arg InsertPendingReminderParams) error {
+	_, err := q.db.Exec(ctx, insertPendingReminder, arg.RequestID, arg.LeadMinutes)
+	return err
+}
+
 const insertPendingServiceRequest = `-- name: InsertPendingServiceRequest :one
 INSERT INTO service_request (listing_id,requester_id,provider_id,status_detail,activity,created_at,updated_at,token_reward)
 SELECT
@@ -391,7 +675,7 @@ SELECT
     sl.posted_by,
     'pending', 'active', NOW(),NOW(),sl.token_reward
 FROM service_listing sl
-WHERE sl.id = $1 AND sl.posted_by != $2
+WHERE sl.id = $1 AND sl.posted_by != $2 AND sl.status = 'active'
 RETURNING id
 `
 
@@ -408,14 +692,16 @@ func (q *Queries) InsertPendingServiceRequest(ctx context.Context, arg InsertPen
 }
 
 const insertRequestReport = `-- name: InsertRequestReport :one
-INSERT INTO request_report (reporter_id, request_id, ticket_id, created_at,updated_at, "status")
-VALUES ($1, $2, '', NOW(),NOW(), 'ongoing')
+INSERT INTO request_report (reporter_id, request_id, ticket_id, description, evidence_urls, created_at,updated_at, "status")
+VALUES ($1, $2, '', $3, $4, NOW(),NOW(), 'ongoing')
 RETURNING id, created_at
 `
 
 type InsertRequestReportParams struct {
-	ReporterID string `json:"reporter_id"`
-	RequestID  int32  `json:"request_id"`
+	ReporterID   string   `json:"reporter_id"`
+	RequestID    int32    `json:"request_id"`
+	Description  string   `json:"description"`
+	EvidenceUrls []string `json:"evidence_urls"`
 }
 
 type InsertRequestReportRow struct {
@@ -424,7 +710,12 @@ type InsertRequestReportRow struct {
 }
 
 func (q *Queries) InsertRequestReport(ctx context.Context, arg InsertRequestReportParams) (InsertRequestReportRow, error) {
-	row := q.db.QueryRow(ctx, insertRequestReport, arg.ReporterID, arg.RequestID)
+	row := q.db.QueryRow(ctx, insertRequestReport,
+		arg.ReporterID,
+		arg.RequestID,
+		arg.Description,
+		arg.EvidenceUrls,
+	)
 	var i InsertRequestReportRow
 	err := row.Scan(&i.ID, &i.CreatedAt)
 	return i, err
@@ -440,65 +731,17 @@ func (q *Queries) InsertServiceRequestCompletion(ctx context.Context, requestID
 	return err
 }
 
-const updateExpiredRequest = `-- name: UpdateExpiredRequest :many
-UPDATE service_request AS sr
-SET status_detail = 'expired', activity = 'inactive', updated_at = NOW()
-FROM service_listing AS sl
-JOIN "user" AS ru ON ru.id = sr.requester_id
-WHERE sl.id = sr.listing_id AND sr.activity = 'active' AND sr.status_detail = 'pending' AND sr.status_detail != 'expired'
-  AND NOW() - sr.updated_at > INTERVAL '36 hour'
-RETURNING
-  sr.id as request_id,
-  sr.listing_id,
-  sr.status_detail,
-  sr.updated_at,
-  sr.requester_id,
-  sr.provider_id,
-  sr.token_reward,
-  sl.title AS listing_title,
-  ru.full_name AS requester_full_name
+const lockServiceRequest = `-- name: LockServiceRequest :one
+SELECT status_detail FROM service_request
+WHERE id = $1
+FOR UPDATE
 `
 
-type UpdateExpiredRequestRow struct {
-	RequestID         int32                `json:"request_id"`
-	ListingID         int32                `json:"listing_id"`
-	StatusDetail      ServiceRequestStatus `json:"status_detail"`
-	UpdatedAt         time.Time            `json:"updated_at"`
-	RequesterID       string               `json:"requester_id"`
-	ProviderID        string               `json:"provider_id"`
-	TokenReward       int32                `json:"token_reward"`
-	ListingTitle      string               `json:"listing_title"`
-	RequesterFullName string               `json:"requester_full_name"`
-}
-
-func (q *Queries) UpdateExpiredRequest(ctx context.Context) ([]UpdateExpiredRequestRow, error) {
-	rows, err := q.db.Query(ctx, updateExpiredRequest)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []UpdateExpiredRequestRow
-	for rows.Next() {
-		var i UpdateExpiredRequestRow
-		if err := rows.Scan(
-			&i.RequestID,
-			&i.ListingID,
-			&i.StatusDetail,
-			&i.UpdatedAt,
-			&i.RequesterID,
-			&i.ProviderID,
-			&i.TokenReward,
-			&i.ListingTitle,
-			&i.RequesterFullName,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) LockServiceRequest(ctx context.Context, id int32) (ServiceRequestStatus, error) {
+	row := q.db.QueryRow(ctx, lockServiceRequest, id)
+	var status_detail ServiceRequestStatus
+	err := row.Scan(&status_detail)
+	return status_detail, err
 }
 
 const updateRequestReportWithTicketID = `-- name: UpdateRequestReportWithTicketID :one
@@ -542,7 +785,8 @@ func (q *Queries) UpdateServiceRequest(ctx context.Context, arg UpdateServiceReq
 
 const updateServiceRequestCompletion = `-- name: UpdateServiceRequestCompletion :exec
 UPDATE service_request_completion
-SET requester_completed = $1, provider_completed = $2, is_active = $3
+SET requester_completed = $1, provider_completed = $2, is_active = $3,
+    confirmed_at = COALESCE(confirmed_at, CASE WHEN $1 OR $2 THEN NOW() END)
 WHERE request_id = $4
 `
 
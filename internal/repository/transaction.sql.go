@@ -27,3 +27,19 @@ func (q *Queries) InsertTransaction(ctx context.Context, arg InsertTransactionPa
 	_, err := q.db.Exec(ctx, insertTransaction, arg.UserID, arg.Type, arg.PaymentID)
 	return err
 }
+
+const insertTransactionAmount = `-- name: InsertTransactionAmount :exec
+INSERT INTO "transaction" (user_id,type,amount,created_at)
+VALUES ($1,$2,$3,NOW())
+`
+
+type InsertTransactionAmountParams struct {
+	UserID string `json:"user_id"`
+	Type   string `json:"type"`
+	Amount int32  `json:"amount"`
+}
+
+func (q *Queries) InsertTransactionAmount(ctx context.Context, arg InsertTransactionAmountParams) error {
+	_, err := q.db.Exec(ctx, insertTransactionAmount, arg.UserID, arg.Type, arg.Amount)
+	return err
+}
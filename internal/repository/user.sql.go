@@ -13,75 +13,34 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const addTokens = `-- name: AddTokens :one
-UPDATE "user"
-SET token_balance = token_balance + $1
-WHERE id = $2
-RETURNING token_balance
-`
-
-type AddTokensParams struct {
-	TokenBalance int32  `json:"token_balance"`
-	ID           string `json:"id"`
-}
-
-func (q *Queries) AddTokens(ctx context.Context, arg AddTokensParams) (int32, error) {
-	row := q.db.QueryRow(ctx, addTokens, arg.TokenBalance, arg.ID)
-	var token_balance int32
-	err := row.Scan(&token_balance)
-	return token_balance, err
-}
-
-const deductRewardTokensFromUser = `-- name: DeductRewardTokensFromUser :exec
-UPDATE "user"
-SET token_balance = token_balance - r.cost
-FROM reward r
-WHERE "user".id = $1 AND r.id = $2 AND "user".token_balance >= r.cost
+const deleteUser = `-- name: DeleteUser :execresult
+DELETE FROM "user" where id = $1
 `
 
-type DeductRewardTokensFromUserParams struct {
-	UserID   string `json:"user_id"`
-	RewardID int32  `json:"reward_id"`
-}
-
-func (q *Queries) DeductRewardTokensFromUser(ctx context.Context, arg DeductRewardTokensFromUserParams) error {
-	_, err := q.db.Exec(ctx, deductRewardTokensFromUser, arg.UserID, arg.RewardID)
-	return err
+func (q *Queries) DeleteUser(ctx context.Context, id string) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, deleteUser, id)
 }
 
-const deductTokens = `-- name: DeductTokens :execrows
-UPDATE "user"
-SET token_balance = token_balance - s.token_reward
-FROM service_listing s
-WHERE "user".id = $1
-  AND s.id = $2
-  AND "user".token_balance >= s.token_reward
+const getAccountStanding = `-- name: GetAccountStanding :one
+SELECT status, suspended_until FROM "user"
+WHERE id = $1
 `
 
-type DeductTokensParams struct {
-	UserID    string `json:"user_id"`
-	ListingID int32  `json:"listing_id"`
+type GetAccountStandingRow struct {
+	Status         AccountStatus      `json:"status"`
+	SuspendedUntil pgtype.Timestamptz `json:"suspended_until"`
 }
 
-func (q *Queries) DeductTokens(ctx context.Context, arg DeductTokensParams) (int64, error) {
-	result, err := q.db.Exec(ctx, deductTokens, arg.UserID, arg.ListingID)
-	if err != nil {
-		return 0, err
-	}
-	return result.RowsAffected(), nil
-}
-
-const deleteUser = `-- name: DeleteUser :execresult
-DELETE FROM "user" where id = $1
-`
-
-func (q *Queries) DeleteUser(ctx context.Context, id string) (pgconn.CommandTag, error) {
-	return q.db.Exec(ctx, deleteUser, id)
+func (q *Queries) GetAccountStanding(ctx context.Context, id string) (GetAccountStandingRow, error) {
+	row := q.db.QueryRow(ctx, getAccountStanding, id)
+	var i GetAccountStandingRow
+	err := row.Scan(&i.Status, &i.SuspendedUntil)
+	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
 SELECT
-    u.id, u.phone, u.token_balance, u.status, u.address_line_1, u.address_line_2, u.city, u.state_province, u.zip_postal_code, u.country, u.joined_at, u.is_email_signedup, u.full_name, u.is_paid, u.about_me,
+    u.id, u.phone, u.token_balance, u.status, u.address_line_1, u.address_line_2, u.city, u.state_province, u.zip_postal_code, u.country, u.joined_at, u.is_email_signedup, u.full_name, u.is_paid, u.about_me, u.suspended_until,
     COALESCE(sp.requested_count, 0) AS services_received,
     COALESCE(sp.provided_count, 0) AS services_provided,
     r.total_ratings,
@@ -107,25 +66,26 @@ WHERE u.id = $1
 `
 
 type GetUserByIDRow struct {
-	ID               string        `json:"id"`
-	Phone            string        `json:"phone"`
-	TokenBalance     int32         `json:"token_balance"`
-	Status           AccountStatus `json:"status"`
-	AddressLine1     string        `json:"address_line_1"`
-	AddressLine2     string        `json:"address_line_2"`
-	City             string        `json:"city"`
-	StateProvince    string        `json:"state_province"`
-	ZipPostalCode    string        `json:"zip_postal_code"`
-	Country          string        `json:"country"`
-	JoinedAt         time.Time     `json:"joined_at"`
-	IsEmailSignedup  bool          `json:"is_email_signedup"`
-	FullName         string        `json:"full_name"`
-	IsPaid           bool          `json:"is_paid"`
-	AboutMe          pgtype.Text   `json:"about_me"`
-	ServicesReceived int64         `json:"services_received"`
-	ServicesProvided int64         `json:"services_provided"`
-	TotalRatings     pgtype.Int4   `json:"total_ratings"`
-	RatingCount      pgtype.Int4   `json:"rating_count"`
+	ID               string             `json:"id"`
+	Phone            string             `json:"phone"`
+	TokenBalance     int32              `json:"token_balance"`
+	Status           AccountStatus      `json:"status"`
+	AddressLine1     string             `json:"address_line_1"`
+	AddressLine2     string             `json:"address_line_2"`
+	City             string             `json:"city"`
+	StateProvince    string             `json:"state_province"`
+	ZipPostalCode    string             `json:"zip_postal_code"`
+	Country          string             `json:"country"`
+	JoinedAt         time.Time          `json:"joined_at"`
+	IsEmailSignedup  bool               `json:"is_email_signedup"`
+	FullName         string             `json:"full_name"`
+	IsPaid           bool               `json:"is_paid"`
+	AboutMe          pgtype.Text        `json:"about_me"`
+	SuspendedUntil   pgtype.Timestamptz `json:"suspended_until"`
+	ServicesReceived int64              `json:"services_received"`
+	ServicesProvided int64              `json:"services_provided"`
+	TotalRatings     pgtype.Int4        `json:"total_ratings"`
+	RatingCount      pgtype.Int4        `json:"rating_count"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id string) (GetUserByIDRow, error) {
@@ -147,6 +107,7 @@ func (q *Queries) GetUserByID(ctx context.Context, id string) (GetUserByIDRow, e
 		&i.FullName,
 		&i.IsPaid,
 		&i.AboutMe,
+		&i.SuspendedUntil,
 		&i.ServicesReceived,
 		&i.ServicesProvided,
 		&i.TotalRatings,
@@ -239,26 +200,32 @@ func (q *Queries) InsertUser(ctx context.Context, arg InsertUserParams) (string,
 	return id, err
 }
 
-const markSignupPaidAndAward = `-- name: MarkSignupPaidAndAward :one
+const liftExpiredSuspensions = `-- name: LiftExpiredSuspensions :execrows
 UPDATE "user"
-SET
-  is_paid = true,
-  token_balance = token_balance + $2
-WHERE id = $1
-  AND is_paid = false
-RETURNING token_balance
+SET status = 'active', suspended_until = NULL
+WHERE status = 'suspended' AND suspended_until <= NOW()
 `
 
-type MarkSignupPaidAndAwardParams struct {
-	ID           string `json:"id"`
-	TokenBalance int32  `json:"token_balance"`
+func (q *Queries) LiftExpiredSuspensions(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, liftExpiredSuspensions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-func (q *Queries) MarkSignupPaidAndAward(ctx context.Context, arg MarkSignupPaidAndAwardParams) (int32, error) {
-	row := q.db.QueryRow(ctx, markSignupPaidAndAward, arg.ID, arg.TokenBalance)
-	var token_balance int32
-	err := row.Scan(&token_balance)
-	return token_balance, err
+const markSignupPaid = `-- name: MarkSignupPaid :one
+UPDATE "user"
+SET is_paid = true
+WHERE id = $1
+  AND is_paid = false
+RETURNING id
+`
+
+func (q *Queries) MarkSignupPaid(ctx context.Context, id string) (string, error) {
+	row := q.db.QueryRow(ctx, markSignupPaid, id)
+	err := row.Scan(&id)
+	return id, err
 }
 
 const updateAboutMe = `-- name: UpdateAboutMe :exec
@@ -327,3 +294,23 @@ func (q *Queries) UpdateUserFullNmae(ctx context.Context, arg UpdateUserFullNmae
 	}
 	return result.RowsAffected(), nil
 }
+
+const updateUserStatus = `-- name: UpdateUserStatus :execrows
+UPDATE "user"
+SET status = $1, suspended_until = $2
+WHERE id = $3
+`
+
+type UpdateUserStatusParams struct {
+	Status         AccountStatus      `json:"status"`
+	SuspendedUntil pgtype.Timestamptz `json:"suspended_until"`
+	ID             string             `json:"id"`
+}
+
+func (q *Queries) UpdateUserStatus(ctx context.Context, arg UpdateUserStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserStatus, arg.Status, arg.SuspendedUntil, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,7 @@
+package util
+
+// RandomToken returns a random base62 string for secrets that end up in
+// URLs, such as calendar feed tokens.
+func RandomToken(length int) (string, error) {
+	return randomPart(length)
+}
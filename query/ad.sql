@@ -1,10 +1,11 @@
 -- name: GetAdsWatched :one
 SELECT count(id) FROM ads_watching_history
-WHERE user_id = $1 AND date_time > (NOW() - INTERVAL '24 hour');
+WHERE user_id = $1 AND date_time > sqlc.arg(since)::timestamptz;
 
--- name: InsertAdsHistory :execresult
-INSERT INTO ads_watching_history (user_id,date_time)
-VALUES ($1,NOW());
+-- name: InsertAdsHistory :one
+INSERT INTO ads_watching_history (user_id, date_time, ad_unit, tokens)
+VALUES ($1, NOW(), $2, $3)
+RETURNING id;
 
 
 -- name: GetAdsHistory :many
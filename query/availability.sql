@@ -0,0 +1,72 @@
+-- name: GetAvailabilityRules :many
+SELECT * FROM availability_rule
+WHERE provider_id = $1
+ORDER BY weekday, start_time;
+
+-- name: DeleteAvailabilityRules :exec
+DELETE FROM availability_rule
+WHERE provider_id = $1;
+
+-- name: InsertAvailabilityRule :exec
+INSERT INTO availability_rule (provider_id, weekday, start_time, end_time, timezone)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetAvailabilityExceptions :many
+SELECT * FROM availability_exception
+WHERE provider_id = sqlc.arg(provider_id)
+  AND ends_at > sqlc.arg(range_start)::timestamptz
+  AND starts_at < sqlc.arg(range_end)::timestamptz
+ORDER BY starts_at;
+
+-- name: InsertAvailabilityException :one
+INSERT INTO availability_exception (provider_id, starts_at, ends_at, reason)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: DeleteAvailabilityException :execrows
+DELETE FROM availability_exception
+WHERE id = $1 AND provider_id = $2;
+
+-- name: GetListingSchedule :one
+SELECT posted_by, session_duration FROM service_listing
+WHERE id = $1;
+
+-- name: GetBookedSlots :many
+SELECT starts_at, ends_at FROM service_request_slot
+WHERE provider_id = sqlc.arg(provider_id)
+  AND NOT released
+  AND ends_at > sqlc.arg(range_start)::timestamptz
+  AND starts_at < sqlc.arg(range_end)::timestamptz
+ORDER BY starts_at;
+
+-- name: InsertRequestSlot :exec
+-- fails with an exclusion violation if the provider already has an
+-- unreleased slot overlapping this one
+INSERT INTO service_request_slot (request_id, provider_id, starts_at, ends_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetRequestSlot :one
+SELECT * FROM service_request_slot
+WHERE request_id = $1;
+
+-- name: ReleaseRequestSlot :exec
+UPDATE service_request_slot
+SET released = true
+WHERE request_id = $1;
+
+-- name: GetDueSlotReminders :many
+SELECT rs.request_id, rs.starts_at, sr.requester_id, sr.provider_id, sl.title AS listing_title
+FROM service_request_slot rs
+JOIN service_request sr ON sr.id = rs.request_id
+JOIN service_listing sl ON sl.id = sr.listing_id
+WHERE NOT rs.released
+  AND rs.reminded_at IS NULL
+  AND rs.starts_at > NOW()
+  AND rs.starts_at <= sqlc.arg(remind_before)::timestamptz
+  AND sr.status_detail = 'in_progress'
+FOR UPDATE OF rs SKIP LOCKED;
+
+-- name: MarkSlotReminded :exec
+UPDATE service_request_slot
+SET reminded_at = NOW()
+WHERE request_id = $1;
@@ -0,0 +1,48 @@
+-- name: EnsureCalendarFeedToken :one
+-- returns the user's existing token, or token if they had none
+INSERT INTO calendar_feed (user_id, token)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET token = calendar_feed.token
+RETURNING token;
+
+-- name: RotateCalendarFeedToken :one
+INSERT INTO calendar_feed (user_id, token)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET token = EXCLUDED.token, created_at = NOW()
+RETURNING token;
+
+-- name: GetCalendarFeedUser :one
+SELECT user_id FROM calendar_feed
+WHERE token = $1;
+
+-- name: GetCalendarEntries :many
+SELECT
+    sr.id,
+    sr.status_detail,
+    sr.updated_at,
+    coalesce(acc.accepted_at, sr.created_at)::timestamptz AS accepted_at,
+    sr.requester_id,
+    sr.provider_id,
+    sl.title,
+    sl.description,
+    sl.session_duration,
+    sl.contact_method,
+    ru.full_name AS requester_full_name,
+    pu.full_name AS provider_full_name,
+    rs.starts_at AS slot_starts_at,
+    rs.ends_at AS slot_ends_at
+FROM service_request sr
+JOIN service_listing sl ON sl.id = sr.listing_id
+JOIN "user" ru ON ru.id = sr.requester_id
+JOIN "user" pu ON pu.id = sr.provider_id
+LEFT JOIN service_request_slot rs ON rs.request_id = sr.id AND NOT rs.released
+LEFT JOIN LATERAL (
+    SELECT min(e.created_at) AS accepted_at FROM event e
+    WHERE e.type = 'request' AND e.target_id = sr.id AND e.description = 'accept'
+) acc ON true
+WHERE (sr.requester_id = sqlc.arg(user_id) OR sr.provider_id = sqlc.arg(user_id))
+  AND sr.status_detail IN ('accepted', 'in_progress')
+  AND (sqlc.narg(request_id)::int IS NULL OR sr.id = sqlc.narg(request_id)::int)
+ORDER BY coalesce(rs.starts_at, acc.accepted_at, sr.created_at);
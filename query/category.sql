@@ -0,0 +1,38 @@
+-- name: GetCategories :many
+SELECT * FROM category
+ORDER BY sort_order, name;
+
+-- name: GetCategoriesWithListingCounts :many
+SELECT c.id, c.slug, c.name, c.parent_id, c.icon, c.sort_order, COUNT(sl.id) AS listing_count
+FROM category c
+LEFT JOIN service_listing sl ON sl.category = c.slug AND sl.status = 'active'
+GROUP BY c.id
+ORDER BY c.sort_order, c.name;
+
+-- name: ResolveCategorySlug :one
+SELECT slug FROM category
+WHERE slug = $1 OR lower(name) = $1
+LIMIT 1;
+
+-- name: InsertCategory :one
+INSERT INTO category (slug, name, parent_id, icon, sort_order)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: UpdateCategory :execrows
+UPDATE category
+SET name = $1, parent_id = $2, icon = $3, sort_order = $4
+WHERE id = $5;
+
+-- name: GetUnmappedListingCategories :many
+SELECT sl.category, COUNT(*) AS listing_count
+FROM service_listing sl
+LEFT JOIN category c ON c.slug = sl.category
+WHERE c.id IS NULL
+GROUP BY sl.category
+ORDER BY sl.category;
+
+-- name: RemapListingCategory :execrows
+UPDATE service_listing
+SET category = sqlc.arg(slug)
+WHERE category = sqlc.arg(free_text);
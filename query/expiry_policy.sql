@@ -0,0 +1,18 @@
+-- name: GetCategoryExpiryPolicies :many
+SELECT p.category_id, c.slug, c.name, p.pending_timeout, p.in_progress_timeout, p.auto_complete_after, p.updated_at
+FROM category_expiry_policy p
+JOIN category c ON c.id = p.category_id
+ORDER BY c.slug;
+
+-- name: UpsertCategoryExpiryPolicy :exec
+INSERT INTO category_expiry_policy (category_id, pending_timeout, in_progress_timeout, auto_complete_after)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (category_id) DO UPDATE
+SET pending_timeout = EXCLUDED.pending_timeout,
+    in_progress_timeout = EXCLUDED.in_progress_timeout,
+    auto_complete_after = EXCLUDED.auto_complete_after,
+    updated_at = NOW();
+
+-- name: DeleteCategoryExpiryPolicy :execrows
+DELETE FROM category_expiry_policy
+WHERE category_id = $1;
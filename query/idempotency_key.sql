@@ -0,0 +1,26 @@
+-- name: ClaimIdempotencyKey :execrows
+-- takes a new key, or one whose first request died before it finished
+INSERT INTO idempotency_key (user_id, key, request_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, key) DO UPDATE
+SET created_at = NOW()
+WHERE idempotency_key.status_code IS NULL
+  AND idempotency_key.request_hash = EXCLUDED.request_hash
+  AND idempotency_key.created_at < sqlc.arg(stale_before)::timestamptz;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_key
+WHERE user_id = $1 AND key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_key
+SET status_code = $1, content_type = $2, response_body = $3, completed_at = NOW()
+WHERE user_id = $4 AND key = $5;
+
+-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_key
+WHERE user_id = $1 AND key = $2 AND status_code IS NULL;
+
+-- name: PruneIdempotencyKeys :execrows
+DELETE FROM idempotency_key
+WHERE created_at < $1;
@@ -0,0 +1,44 @@
+-- name: TryJobLock :one
+SELECT pg_try_advisory_lock(sqlc.arg(key)::bigint);
+
+-- name: ReleaseJobLock :one
+SELECT pg_advisory_unlock(sqlc.arg(key)::bigint);
+
+-- name: StartJobRun :one
+INSERT INTO job_run (job, instance)
+VALUES ($1, $2)
+RETURNING id;
+
+-- name: FinishJobRun :exec
+UPDATE job_run
+SET status = $1, finished_at = NOW(), error = $2
+WHERE id = $3 AND status = 'running';
+
+-- name: AbandonJobRuns :execrows
+-- runs left behind by a leader that died or lost its lock
+UPDATE job_run
+SET status = 'abandoned', finished_at = NOW(), error = 'the instance running the job stopped before it finished'
+WHERE status = 'running';
+
+-- name: GetLastSettledJobRuns :many
+-- the start of the last run of each job that ran to an end, which the next
+-- run is scheduled from
+SELECT DISTINCT ON (job) job, started_at
+FROM job_run
+WHERE status IN ('succeeded', 'failed')
+ORDER BY job, started_at DESC;
+
+-- name: GetLatestJobRuns :many
+SELECT DISTINCT ON (job) *
+FROM job_run
+ORDER BY job, started_at DESC, id DESC;
+
+-- name: GetJobRuns :many
+SELECT * FROM job_run
+WHERE job = $1
+ORDER BY started_at DESC, id DESC
+LIMIT $2;
+
+-- name: PruneJobRuns :execrows
+DELETE FROM job_run
+WHERE started_at < $1;
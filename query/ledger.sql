@@ -0,0 +1,114 @@
+-- name: InsertLedgerAccount :one
+INSERT INTO ledger_account (code,"type",user_id,created_at)
+VALUES ($1,$2,$3,NOW())
+ON CONFLICT (code) DO NOTHING
+RETURNING id;
+
+-- name: GetLedgerAccountByCode :one
+SELECT * FROM ledger_account
+WHERE code = $1;
+
+-- name: InsertJournalEntry :one
+INSERT INTO journal_entry (kind,memo,reference_type,reference_id,created_at)
+VALUES ($1,$2,$3,$4,NOW())
+RETURNING id;
+
+-- name: InsertJournalLine :exec
+INSERT INTO journal_line (entry_id,account_id,amount)
+VALUES ($1,$2,$3);
+
+-- name: CreditUserBalance :one
+UPDATE "user"
+SET token_balance = token_balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(user_id)
+RETURNING token_balance;
+
+-- name: DebitUserBalance :execrows
+UPDATE "user"
+SET token_balance = token_balance - sqlc.arg(amount)
+WHERE id = sqlc.arg(user_id)
+  AND token_balance >= sqlc.arg(amount);
+
+-- name: GetLedgerAccountBalance :one
+SELECT COALESCE(SUM(amount),0)::integer AS balance FROM journal_line
+WHERE account_id = $1;
+
+-- name: GetUserLedgerEntries :many
+SELECT
+  je.id AS entry_id,
+  je.kind,
+  je.memo,
+  je.reference_type,
+  je.reference_id,
+  je.created_at,
+  jl.amount,
+  cla.code AS counter_account_code,
+  cla.type AS counter_account_type
+FROM journal_line jl
+JOIN ledger_account la ON la.id = jl.account_id
+JOIN journal_entry je ON je.id = jl.entry_id
+JOIN journal_line cl ON cl.entry_id = je.id AND SIGN(cl.amount) != SIGN(jl.amount)
+JOIN ledger_account cla ON cla.id = cl.account_id
+WHERE la.user_id = $1 AND la.type = 'user_wallet'
+ORDER BY je.created_at DESC, je.id DESC;
+
+-- name: GetBalanceComponents :many
+SELECT
+  u.id AS user_id,
+  u.token_balance,
+  u.is_paid,
+  COALESCE(ads.tokens,0)::integer AS ad_tokens,
+  COALESCE(rr.spent,0)::integer AS rewards_spent,
+  COALESCE(paid.escrowed,0)::integer AS tokens_escrowed,
+  COALESCE(paid.refunded,0)::integer AS tokens_refunded,
+  COALESCE(earned.released,0)::integer AS tokens_earned,
+  COALESCE(tr.net,0)::integer AS transaction_net,
+  wallet.account_id AS wallet_account_id,
+  COALESCE(wallet.balance,0)::integer AS ledger_balance
+FROM "user" u
+LEFT JOIN (
+  SELECT user_id, SUM(tokens) AS tokens FROM ads_watching_history GROUP BY user_id
+) ads ON ads.user_id = u.id
+LEFT JOIN (
+  SELECT user_id, SUM(cost) AS spent FROM redeemed_reward GROUP BY user_id
+) rr ON rr.user_id = u.id
+LEFT JOIN (
+  SELECT p.payer_id,
+    SUM(p.amount_tokens) AS escrowed,
+    SUM(CASE p.status
+      WHEN 'refunded' THEN p.amount_tokens
+      WHEN 'split' THEN rr.requester_share
+      ELSE 0 END) AS refunded
+  FROM payment p
+  LEFT JOIN request_report rr ON rr.request_id = p.service_request_id AND rr.resolution = 'split'
+  GROUP BY p.payer_id
+) paid ON paid.payer_id = u.id
+LEFT JOIN (
+  SELECT sr.provider_id,
+    SUM(CASE p.status
+      WHEN 'released' THEN p.amount_tokens
+      ELSE p.amount_tokens - rr.requester_share END) AS released
+  FROM payment p
+  JOIN service_request sr ON sr.id = p.service_request_id
+  LEFT JOIN request_report rr ON rr.request_id = p.service_request_id AND rr.resolution = 'split'
+  WHERE p.status IN ('released', 'split')
+  GROUP BY sr.provider_id
+) earned ON earned.provider_id = u.id
+LEFT JOIN (
+  SELECT user_id,
+    SUM(CASE WHEN type = 'deduct' THEN -amount ELSE amount END) AS net
+  FROM "transaction" GROUP BY user_id
+) tr ON tr.user_id = u.id
+LEFT JOIN (
+  SELECT la.user_id, la.id AS account_id, SUM(jl.amount) AS balance FROM ledger_account la
+  LEFT JOIN journal_line jl ON jl.account_id = la.id
+  WHERE la.type = 'user_wallet'
+  GROUP BY la.id
+) wallet ON wallet.user_id = u.id
+WHERE sqlc.narg(user_id)::text IS NULL OR u.id = sqlc.narg(user_id)::text
+ORDER BY u.id;
+
+-- name: LockUserBalance :one
+SELECT token_balance FROM "user"
+WHERE id = $1
+FOR UPDATE;
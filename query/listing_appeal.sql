@@ -0,0 +1,37 @@
+-- name: InsertListingAppeal :one
+INSERT INTO listing_appeal (listing_id, appellant_id, reason)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: GetListingAppealForUpdate :one
+SELECT * FROM listing_appeal
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetListingAppeals :many
+SELECT
+  la.id,
+  la.listing_id,
+  sl.title AS listing_title,
+  sl.status AS listing_status,
+  la.appellant_id,
+  u.full_name AS appellant_full_name,
+  la.reason,
+  la.status,
+  la.created_at,
+  la.decided_by,
+  la.decision_note,
+  la.decided_at,
+  w.severity AS warning_severity,
+  w.reason AS warning_reason
+FROM listing_appeal la
+JOIN service_listing sl ON sl.id = la.listing_id
+JOIN "user" u ON u.id = la.appellant_id
+LEFT JOIN warning w ON w.listing_id = la.listing_id
+WHERE la.status = $1
+ORDER BY la.created_at, la.id;
+
+-- name: DecideListingAppeal :exec
+UPDATE listing_appeal
+SET status = $1, decided_by = $2, decision_note = $3, decided_at = NOW()
+WHERE id = $4;
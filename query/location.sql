@@ -0,0 +1,31 @@
+-- name: GetUserLocation :one
+SELECT * FROM user_location
+WHERE user_id = $1;
+
+-- name: UpsertUserLocation :exec
+-- a geocoded location never replaces one the user set by hand
+INSERT INTO user_location (user_id, latitude, longitude, service_radius_km, source)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET latitude = EXCLUDED.latitude,
+    longitude = EXCLUDED.longitude,
+    service_radius_km = EXCLUDED.service_radius_km,
+    source = EXCLUDED.source,
+    updated_at = NOW()
+WHERE user_location.source = 'geocoded' OR EXCLUDED.source = 'manual';
+
+-- name: UpsertListingLocation :exec
+INSERT INTO listing_location (listing_id, latitude, longitude, service_radius_km)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (listing_id) DO UPDATE
+SET latitude = EXCLUDED.latitude,
+    longitude = EXCLUDED.longitude,
+    service_radius_km = EXCLUDED.service_radius_km,
+    updated_at = NOW();
+
+-- name: CopyUserLocationToListing :exec
+INSERT INTO listing_location (listing_id, latitude, longitude, service_radius_km)
+SELECT $1, ul.latitude, ul.longitude, ul.service_radius_km
+FROM user_location ul
+WHERE ul.user_id = $2
+ON CONFLICT (listing_id) DO NOTHING;
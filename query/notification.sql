@@ -1,12 +1,21 @@
 -- name: InsertNotification :execresult
-INSERT INTO notification (message,recipient_user_id,action_user_id,is_read,event_id)
-VALUES ($1,$2,$3,false,$4);
+INSERT INTO notification (message,recipient_user_id,action_user_id,is_read,event_id,email_pending)
+VALUES ($1,$2,$3,false,$4,$5);
 
 
 -- name: GetNotifications :many
-SELECT * FROM notification n
+SELECT n.id, n.message, n.recipient_user_id, n.action_user_id, n.is_read, n.event_id, n.archived_at,
+       ne.type, ne.target_id, ne.created_at, ne.description
+FROM notification n
 JOIN "event" ne ON ne.id = n.event_id
-WHERE recipient_user_id = $1;
+WHERE n.recipient_user_id = sqlc.arg(recipient_user_id)
+  AND (n.archived_at IS NOT NULL) = sqlc.arg(archived)::boolean
+  AND (NOT sqlc.arg(unread_only)::boolean OR NOT n.is_read)
+  AND (sqlc.narg(event_type)::text IS NULL OR ne.type = sqlc.narg(event_type)::text)
+  AND (sqlc.narg(before_time)::timestamptz IS NULL
+       OR (ne.created_at, n.id) < (sqlc.narg(before_time)::timestamptz, sqlc.narg(before_id)::int))
+ORDER BY ne.created_at DESC, n.id DESC
+LIMIT sqlc.arg(page_size);
 
 
 -- name: InsertEvent :one
@@ -16,7 +25,7 @@ RETURNING id;
 
 -- name: GetUnreadNotificationCount :one
 SELECT COUNT(n.id) FROM notification n
-WHERE n.recipient_user_id = $1 AND n.is_read = false;
+WHERE n.recipient_user_id = $1 AND n.is_read = false AND n.archived_at IS NULL;
 
 -- name: SetUserNotificationsRead :execresult
 UPDATE notification
@@ -40,3 +49,25 @@ JOIN notification n
 ON n.event_id = e.id
 WHERE target_id = $1 AND type = 'request'
 ORDER BY created_at DESC;
+
+-- name: GetPendingEmailNotifications :many
+SELECT n.id, n.recipient_user_id, n.message, e.type, e.description, e.created_at
+FROM notification n
+JOIN "event" e ON e.id = n.event_id
+WHERE n.email_pending AND NOT n.is_read AND n.archived_at IS NULL
+ORDER BY n.recipient_user_id, e.created_at
+FOR UPDATE OF n SKIP LOCKED;
+
+-- name: ClearEmailPending :execrows
+UPDATE notification
+SET email_pending = false
+WHERE id = ANY(sqlc.arg(ids)::int[]) OR (email_pending AND (is_read OR archived_at IS NOT NULL));
+
+-- name: SetNotificationArchived :execrows
+UPDATE notification
+SET archived_at = CASE WHEN sqlc.arg(archived)::boolean THEN COALESCE(archived_at, NOW()) END
+WHERE id = sqlc.arg(id) AND recipient_user_id = sqlc.arg(recipient_user_id);
+
+-- name: DeleteNotification :execrows
+DELETE FROM notification
+WHERE id = $1 AND recipient_user_id = $2;
@@ -0,0 +1,20 @@
+-- name: GetNotificationPreference :one
+-- the preference for the description wins over the one for its whole type
+SELECT in_app, push, email
+FROM notification_preference
+WHERE user_id = $1 AND event_type = $2 AND description IN ($3, '')
+ORDER BY description DESC
+LIMIT 1;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preference
+WHERE user_id = $1
+ORDER BY event_type, description;
+
+-- name: DeleteNotificationPreferences :exec
+DELETE FROM notification_preference
+WHERE user_id = $1;
+
+-- name: InsertNotificationPreference :exec
+INSERT INTO notification_preference (user_id, event_type, description, in_app, push, email)
+VALUES ($1, $2, $3, $4, $5, $6);
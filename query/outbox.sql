@@ -0,0 +1,32 @@
+-- name: EnqueueOutbox :exec
+INSERT INTO outbox (channel, event, payload)
+VALUES ($1, $2, $3);
+
+-- name: ClaimOutbox :many
+-- locks due events so concurrent dispatchers never deliver the same one.
+-- emails and realtime events are claimed apart, so a slow mail server never
+-- holds up realtime events.
+SELECT id, channel, event, payload, attempts
+FROM outbox
+WHERE delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= NOW()
+  AND (channel = 'email') = sqlc.arg(emails)::bool
+ORDER BY id
+LIMIT sqlc.arg(batch_size)
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxDelivered :exec
+UPDATE outbox
+SET delivered_at = NOW(), attempts = attempts + 1
+WHERE id = $1;
+
+-- name: MarkOutboxFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = sqlc.arg(last_error)::text,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    dead_at = CASE WHEN sqlc.arg(dead)::bool THEN NOW() END
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteDeliveredOutbox :execrows
+DELETE FROM outbox
+WHERE delivered_at < sqlc.arg(delivered_before)::timestamptz;
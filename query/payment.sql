@@ -21,3 +21,18 @@ RETURNING id;
 -- name: GetRequestPayment :one
 SELECT * FROM payment
 WHERE service_request_id = $1;
+
+-- name: FreezePayment :execrows
+UPDATE payment
+SET status = 'disputed', updated_at = NOW()
+WHERE service_request_id = $1 AND status = 'holding';
+
+-- name: CountUserDisputes :one
+SELECT count(p.id) FROM payment p
+JOIN service_request sr ON sr.id = p.service_request_id
+WHERE p.status = 'disputed' AND (sr.provider_id = sqlc.arg(user_id) OR sr.requester_id = sqlc.arg(user_id));
+
+-- name: UnfreezePayment :execrows
+UPDATE payment
+SET status = 'holding', updated_at = NOW()
+WHERE service_request_id = $1 AND status = 'disputed';
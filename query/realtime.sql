@@ -0,0 +1,5 @@
+-- name: NotifyRealtime :exec
+SELECT pg_notify('realtime', sqlc.arg(payload)::text);
+
+-- name: ListenRealtime :exec
+LISTEN realtime;
@@ -1,3 +1,38 @@
 -- name: InsertReport :exec
 INSERT INTO report (listing_id,reporter_id,datetime,report_reason,status,additional_detail)
 VALUES ($1,$2,NOW(),$3,'pending',$4);
+
+-- name: GetListingReports :many
+SELECT
+  r.id,
+  r.listing_id,
+  sl.title AS listing_title,
+  sl.posted_by,
+  r.reporter_id,
+  u.full_name AS reporter_full_name,
+  r.datetime,
+  r.report_reason,
+  r.status,
+  r.additional_detail,
+  r.reviewed_by,
+  r.reviewed_at
+FROM report r
+JOIN service_listing sl ON sl.id = r.listing_id
+JOIN "user" u ON u.id = r.reporter_id
+WHERE r.status = $1
+ORDER BY r.datetime, r.id;
+
+-- name: ReviewReport :one
+UPDATE report
+SET status = $1, reviewed_by = $2, reviewed_at = NOW()
+WHERE id = $3 AND status = 'pending'
+RETURNING listing_id;
+
+-- name: ReviewPendingListingReports :execrows
+UPDATE report
+SET status = $1, reviewed_by = $2, reviewed_at = NOW()
+WHERE listing_id = $3 AND status = 'pending';
+
+-- name: CountPendingListingReports :one
+SELECT COUNT(*) FROM report
+WHERE listing_id = $1 AND status = 'pending';
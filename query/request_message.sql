@@ -0,0 +1,34 @@
+-- name: GetMessageThreadAccess :one
+SELECT
+    sr.requester_id,
+    sr.provider_id,
+    sr.activity,
+    (EXISTS (
+        SELECT 1 FROM payment p
+        WHERE p.service_request_id = sr.id AND p.status = 'disputed'
+    ) OR EXISTS (
+        SELECT 1 FROM request_report rr
+        WHERE rr.request_id = sr.id AND rr.status <> 'resolved'
+    ))::bool AS dispute_open
+FROM service_request sr
+WHERE sr.id = $1;
+
+-- name: InsertRequestMessage :one
+INSERT INTO request_message (request_id, sender_id, body)
+VALUES ($1, $2, $3)
+RETURNING id, created_at;
+
+-- name: GetRequestMessages :many
+-- newest first; before is the id of the oldest message already seen
+SELECT m.id, m.request_id, m.sender_id, m.body, m.created_at, m.read_at, u.full_name AS sender_full_name
+FROM request_message m
+JOIN "user" u ON u.id = m.sender_id
+WHERE m.request_id = sqlc.arg(request_id)
+  AND (sqlc.narg(before)::int IS NULL OR m.id < sqlc.narg(before)::int)
+ORDER BY m.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkRequestMessagesRead :execrows
+UPDATE request_message
+SET read_at = NOW()
+WHERE request_id = $1 AND sender_id <> $2 AND read_at IS NULL;
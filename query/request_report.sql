@@ -0,0 +1,56 @@
+-- name: GetRequestReportForUpdate :one
+SELECT * FROM request_report
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetLatestRequestReport :one
+SELECT * FROM request_report
+WHERE request_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT 1;
+
+-- name: GetOpenRequestReportID :one
+SELECT id FROM request_report
+WHERE request_id = $1 AND status <> 'resolved'
+LIMIT 1;
+
+-- name: TouchRequestReport :exec
+UPDATE request_report
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: ResolveRequestReport :exec
+UPDATE request_report
+SET status = 'resolved',
+  resolution = $1,
+  requester_share = $2,
+  resolution_note = $3,
+  resolved_by = $4,
+  resolved_at = NOW(),
+  updated_at = NOW()
+WHERE id = $5;
+
+-- name: InsertRequestReportMessage :one
+INSERT INTO request_report_message (report_id, author_id, body, evidence_urls)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: GetRequestReportMessages :many
+SELECT
+  m.id,
+  m.author_id,
+  u.full_name AS author_full_name,
+  m.body,
+  m.evidence_urls,
+  m.created_at
+FROM request_report_message m
+JOIN "user" u ON u.id = m.author_id
+WHERE m.report_id = $1
+ORDER BY m.created_at, m.id;
+
+-- name: GetRequestReportsByStatus :many
+SELECT rr.*, sl.title FROM request_report rr
+JOIN service_request sr ON sr.id = rr.request_id
+JOIN service_listing sl ON sl.id = sr.listing_id
+WHERE rr.status = $1
+ORDER BY rr.created_at, rr.id;
@@ -22,23 +22,54 @@ ON u.id = sl.posted_by
 LEFT JOIN service_request sr ON sr.listing_id = sl.id AND sr.activity = 'active' AND sr.requester_id = $2
 LEFT JOIN rating r ON r.user_id = sl.posted_by
 LEFT JOIN warning w
-ON w.listing_id = w.id
+ON w.listing_id = sl.id
 WHERE sl.id = $1 and sl.status = 'active';
 
 -- name: GetAllListings :many
+-- the filters are repeated in SearchListings and the two must be kept identical
 with listing_rating as (
 select sl.id as listing_id ,sum(r.rating ) as total_rating,count(r.id )as rating_count from service_listing sl
 join service_request sr
 on sr.listing_id = sl.id
 join review r
 on r.request_id  = sr.id
-group by sl.id)
-SELECT sl.*,u.id uid,u.full_name,coalesce(lr.rating_count,0) as total_rating_count ,coalesce(lr.total_rating,0) as total_ratings FROM service_listing sl
+group by sl.id),
+listing_page as (
+SELECT sl.*,u.id uid,u.full_name,coalesce(lr.rating_count,0) as total_rating_count ,coalesce(lr.total_rating,0) as total_ratings,
+  ll.latitude, ll.longitude, ll.service_radius_km,
+  (CASE sqlc.arg(sort)::text
+    WHEN 'rating' THEN coalesce(lr.total_rating::float8 / nullif(lr.rating_count, 0), 0)
+    WHEN 'price' THEN -sl.token_reward::float8
+    WHEN 'distance' THEN -coalesce(6371 * 2 * asin(least(1, sqrt(power(sin(radians(ll.latitude - sqlc.narg(near_lat)::float8) / 2), 2) + cos(radians(sqlc.narg(near_lat)::float8)) * cos(radians(ll.latitude)) * power(sin(radians(ll.longitude - sqlc.narg(near_lng)::float8) / 2), 2)))), 0)
+    ELSE extract(epoch FROM sl.posted_at)::float8
+  END)::float8 AS sort_value
+FROM service_listing sl
 JOIN "user" u
 ON u.id = sl.posted_by
 LEFT JOIN listing_rating lr
 ON lr.listing_id = sl.id
-WHERE sl.posted_by != $1 AND sl.status = 'active';
+LEFT JOIN rating pr
+ON pr.user_id = sl.posted_by
+LEFT JOIN listing_location ll
+ON ll.listing_id = sl.id
+WHERE sl.posted_by != sqlc.arg(posted_by) AND sl.status = 'active'
+  AND (sqlc.narg(category)::text IS NULL OR sl.category IN (
+    WITH RECURSIVE subtree AS (
+      SELECT id, slug FROM category WHERE slug = sqlc.narg(category)
+      UNION ALL
+      SELECT c.id, c.slug FROM category c JOIN subtree ON c.parent_id = subtree.id)
+    SELECT slug FROM subtree))
+  AND (sqlc.narg(min_reward)::int IS NULL OR sl.token_reward >= sqlc.narg(min_reward))
+  AND (sqlc.narg(max_reward)::int IS NULL OR sl.token_reward <= sqlc.narg(max_reward))
+  AND (sqlc.narg(min_provider_rating)::float8 IS NULL OR coalesce(pr.total_ratings::float8 / nullif(pr.rating_count, 0), 0) >= sqlc.narg(min_provider_rating))
+  AND (sqlc.narg(posted_after)::timestamptz IS NULL OR sl.posted_at >= sqlc.narg(posted_after))
+  AND (sqlc.narg(posted_before)::timestamptz IS NULL OR sl.posted_at < sqlc.narg(posted_before))
+  AND (sqlc.narg(near_lat)::float8 IS NULL OR (ll.listing_id IS NOT NULL
+    AND 6371 * 2 * asin(least(1, sqrt(power(sin(radians(ll.latitude - sqlc.narg(near_lat)::float8) / 2), 2) + cos(radians(sqlc.narg(near_lat)::float8)) * cos(radians(ll.latitude)) * power(sin(radians(ll.longitude - sqlc.narg(near_lng)::float8) / 2), 2)))) <= least(coalesce(ll.service_radius_km, 'Infinity'::float8), coalesce(sqlc.narg(max_distance_km)::float8, 'Infinity'::float8)))))
+SELECT * FROM listing_page
+WHERE sqlc.narg(cursor_value)::float8 IS NULL OR (sort_value, id) < (sqlc.narg(cursor_value)::float8, sqlc.narg(cursor_id)::int)
+ORDER BY sort_value DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: UpdateListing :execrows
 UPDATE service_listing
@@ -58,3 +89,85 @@ select sl.id,sl.title,sl.token_reward ,sl.posted_at,sl.category,sl.image_url, co
 left join listing_rating lr
 on lr.listing_id = sl.id
 where sl.posted_by = $1 and status = 'active';
+
+
+-- name: GetListingModeration :one
+SELECT sl.posted_by, sl.title, sl.status, w.id AS warning_id
+FROM service_listing sl
+LEFT JOIN warning w ON w.listing_id = sl.id
+WHERE sl.id = $1;
+
+-- name: HideReportedListing :one
+UPDATE service_listing
+SET status = 'under_review'
+WHERE id = sqlc.arg(id) AND status = 'active'
+  AND (SELECT COUNT(*) FROM report WHERE listing_id = sqlc.arg(id) AND status = 'pending') >= sqlc.arg(threshold)::int
+RETURNING posted_by, title;
+
+-- name: UpdateListingStatus :one
+UPDATE service_listing
+SET status = $1
+WHERE id = $2
+RETURNING posted_by, title;
+
+-- name: RestoreListing :execrows
+UPDATE service_listing
+SET status = 'active'
+WHERE id = $1 AND status IN ('under_review', 'removed');
+
+-- name: UnhideListing :execrows
+UPDATE service_listing
+SET status = 'active'
+WHERE id = $1 AND status = 'under_review';
+
+-- name: SearchListings :many
+-- the tsvector expression must match idx_service_listing_search for the index to be used.
+-- the filters are repeated from GetAllListings and the two must be kept identical.
+-- the snippet marks matches with U+E000 and U+E001, stripped from the description
+-- beforehand, for the caller to escape the text and swap in its own tags.
+with listing_rating as (
+select sl.id as listing_id ,sum(r.rating ) as total_rating,count(r.id )as rating_count from service_listing sl
+join service_request sr
+on sr.listing_id = sl.id
+join review r
+on r.request_id  = sr.id
+group by sl.id),
+listing_match as (
+SELECT sl.*,u.id uid,u.full_name,coalesce(lr.rating_count,0) as total_rating_count ,coalesce(lr.total_rating,0) as total_ratings,
+  ll.latitude, ll.longitude, ll.service_radius_km,
+  (CASE sqlc.arg(sort)::text
+    WHEN 'newest' THEN extract(epoch FROM sl.posted_at)::float8
+    WHEN 'rating' THEN coalesce(lr.total_rating::float8 / nullif(lr.rating_count, 0), 0)
+    WHEN 'price' THEN -sl.token_reward::float8
+    ELSE ts_rank_cd((setweight(to_tsvector('english', sl.title), 'A') || setweight(to_tsvector('english', sl.category), 'B') || setweight(to_tsvector('english', sl.description), 'C')), to_tsquery('english', sqlc.arg(query)::text))::float8
+  END)::float8 AS sort_value
+FROM service_listing sl
+JOIN "user" u
+ON u.id = sl.posted_by
+LEFT JOIN listing_rating lr
+ON lr.listing_id = sl.id
+LEFT JOIN rating pr
+ON pr.user_id = sl.posted_by
+LEFT JOIN listing_location ll
+ON ll.listing_id = sl.id
+WHERE (setweight(to_tsvector('english', sl.title), 'A') || setweight(to_tsvector('english', sl.category), 'B') || setweight(to_tsvector('english', sl.description), 'C')) @@ to_tsquery('english', sqlc.arg(query)::text)
+  AND sl.posted_by != sqlc.arg(posted_by) AND sl.status = 'active'
+  AND (sqlc.narg(category)::text IS NULL OR sl.category IN (
+    WITH RECURSIVE subtree AS (
+      SELECT id, slug FROM category WHERE slug = sqlc.narg(category)
+      UNION ALL
+      SELECT c.id, c.slug FROM category c JOIN subtree ON c.parent_id = subtree.id)
+    SELECT slug FROM subtree))
+  AND (sqlc.narg(min_reward)::int IS NULL OR sl.token_reward >= sqlc.narg(min_reward))
+  AND (sqlc.narg(max_reward)::int IS NULL OR sl.token_reward <= sqlc.narg(max_reward))
+  AND (sqlc.narg(min_provider_rating)::float8 IS NULL OR coalesce(pr.total_ratings::float8 / nullif(pr.rating_count, 0), 0) >= sqlc.narg(min_provider_rating))
+  AND (sqlc.narg(posted_after)::timestamptz IS NULL OR sl.posted_at >= sqlc.narg(posted_after))
+  AND (sqlc.narg(posted_before)::timestamptz IS NULL OR sl.posted_at < sqlc.narg(posted_before))
+  AND (sqlc.narg(near_lat)::float8 IS NULL OR (ll.listing_id IS NOT NULL
+    AND 6371 * 2 * asin(least(1, sqrt(power(sin(radians(ll.latitude - sqlc.narg(near_lat)::float8) / 2), 2) + cos(radians(sqlc.narg(near_lat)::float8)) * cos(radians(ll.latitude)) * power(sin(radians(ll.longitude - sqlc.narg(near_lng)::float8) / 2), 2)))) <= least(coalesce(ll.service_radius_km, 'Infinity'::float8), coalesce(sqlc.narg(max_distance_km)::float8, 'Infinity'::float8)))))
+SELECT listing_match.*,
+  ts_headline('english', translate(description, chr(57344) || chr(57345), ''), to_tsquery('english', sqlc.arg(query)::text), 'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', MaxWords=30, MinWords=10, MaxFragments=2')::text AS snippet
+FROM listing_match
+WHERE sqlc.narg(cursor_value)::float8 IS NULL OR (sort_value, id) < (sqlc.narg(cursor_value)::float8, sqlc.narg(cursor_id)::int)
+ORDER BY sort_value DESC, id DESC
+LIMIT sqlc.arg(page_size);
@@ -3,12 +3,15 @@ SELECT
     sr.*,
     requester.full_name AS requester_name,
     provider.full_name  AS provider_name,
-    l.title
+    l.title,
+    rs.starts_at AS slot_starts_at,
+    rs.ends_at AS slot_ends_at
 FROM
     service_request sr
 JOIN "user" requester ON sr.requester_id = requester.id
 JOIN "user" provider  ON sr.provider_id = provider.id
 JOIN service_listing l on sr.listing_id  = l.id
+LEFT JOIN service_request_slot rs ON rs.request_id = sr.id
 WHERE
     (sr.provider_id = $1 OR sr.requester_id = $1)
     AND sr.activity = 'active';
@@ -44,6 +47,7 @@ SELECT
   rr.status as report_status,
   rr.reporter_id,
   rr.updated_at as report_updated_at,
+  p.status as escrow_status,
   COALESCE(sc.requester_completed,false),
   COALESCE(sc.provider_completed,false),
   COALESCE(
@@ -63,14 +67,19 @@ JOIN service_listing sl ON sr.listing_id = sl.id
 JOIN "user" ru ON sr.requester_id = ru.id
 JOIN "user" pu ON sr.provider_id = pu.id
 LEFT JOIN service_request_completion sc ON sr.id = sc.request_id
+LEFT JOIN payment p ON p.service_request_id = sr.id
 LEFT JOIN "event" e ON e.target_id = sr.id
 LEFT JOIN notification n
 ON n.event_id = e.id
-LEFT JOIN request_report rr
-ON rr.request_id = sr.id
+LEFT JOIN LATERAL (
+  SELECT id, reporter_id, request_id, ticket_id, created_at, status, updated_at FROM request_report
+  WHERE request_id = sr.id
+  ORDER BY created_at DESC, id DESC
+  LIMIT 1
+) rr ON true
 WHERE sr.id = $1
 GROUP BY
-  sr.id, sl.id, ru.id, pu.id, sc.requester_completed, sc.provider_completed,rr.id;
+  sr.id, sl.id, ru.id, pu.id, sc.requester_completed, sc.provider_completed,rr.id, rr.status, rr.reporter_id, rr.updated_at, p.status;
 
 -- name: InsertPendingServiceRequest :one
 INSERT INTO service_request (listing_id,requester_id,provider_id,status_detail,activity,created_at,updated_at,token_reward)
@@ -80,7 +89,7 @@ SELECT
     sl.posted_by,
     'pending', 'active', NOW(),NOW(),sl.token_reward
 FROM service_listing sl
-WHERE sl.id = $1 AND sl.posted_by != $2
+WHERE sl.id = $1 AND sl.posted_by != $2 AND sl.status = 'active'
 RETURNING id;
 
 
@@ -101,7 +110,8 @@ WHERE request_id = $1;
 
 -- name: UpdateServiceRequestCompletion :exec
 UPDATE service_request_completion
-SET requester_completed = $1, provider_completed = $2, is_active = $3
+SET requester_completed = $1, provider_completed = $2, is_active = $3,
+    confirmed_at = COALESCE(confirmed_at, CASE WHEN $1 OR $2 THEN NOW() END)
 WHERE request_id = $4;
 
 -- name: GetAllUserRequests :many
@@ -122,8 +132,8 @@ WHERE
 
 
 -- name: InsertRequestReport :one
-INSERT INTO request_report (reporter_id, request_id, ticket_id, created_at,updated_at, "status")
-VALUES ($1, $2, '', NOW(),NOW(), 'ongoing')
+INSERT INTO request_report (reporter_id, request_id, ticket_id, description, evidence_urls, created_at,updated_at, "status")
+VALUES ($1, $2, '', $3, $4, NOW(),NOW(), 'ongoing')
 RETURNING id, created_at;
 
 -- name: UpdateRequestReportWithTicketID :one
@@ -133,19 +143,10 @@ WHERE id = $2
 RETURNING ticket_id;
 
 
--- name: GetRequestReport :one
-SELECT * FROM request_report
-WHERE request_id = $1 AND reporter_id = $2;
 
 
--- name: UpdateExpiredRequest :many
-UPDATE service_request AS sr
-SET status_detail = 'expired', activity = 'inactive', updated_at = NOW()
-FROM service_listing AS sl
-JOIN "user" AS ru ON ru.id = sr.requester_id
-WHERE sl.id = sr.listing_id AND sr.activity = 'active' AND sr.status_detail = 'pending' AND sr.status_detail != 'expired'
-  AND NOW() - sr.updated_at > INTERVAL '36 hour'
-RETURNING
+-- name: GetExpiredRequests :many
+SELECT
   sr.id as request_id,
   sr.listing_id,
   sr.status_detail,
@@ -154,7 +155,26 @@ RETURNING
   sr.provider_id,
   sr.token_reward,
   sl.title AS listing_title,
-  ru.full_name AS requester_full_name;
+  ru.full_name AS requester_full_name
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN "user" AS ru ON ru.id = sr.requester_id
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'pending'
+  AND COALESCE(cp.pending_timeout, pp.pending_timeout, sqlc.arg(pending_timeout)::interval) > INTERVAL '0'
+  AND sr.updated_at + COALESCE(cp.pending_timeout, pp.pending_timeout, sqlc.arg(pending_timeout)::interval) < NOW()
+  AND NOT EXISTS (
+    SELECT 1 FROM payment p
+    WHERE p.service_request_id = sr.id AND p.status = 'disputed'
+  )
+FOR UPDATE OF sr SKIP LOCKED;
+
+-- name: LockServiceRequest :one
+SELECT status_detail FROM service_request
+WHERE id = $1
+FOR UPDATE;
 
 
 -- name: GetProvidingeRequests :many
@@ -173,4 +193,87 @@ join service_listing sl
 on sl.id = sr.listing_id 
 where reporter_id = $1;
 
+-- name: GetDuePendingReminders :many
+-- pending requests that expire within a checkpoint and have not been
+-- reminded at that checkpoint or a later one
+SELECT
+  sr.id AS request_id,
+  sr.provider_id,
+  (sr.updated_at + COALESCE(cp.pending_timeout, pp.pending_timeout, sqlc.arg(pending_timeout)::interval))::timestamptz AS expires_at,
+  sl.title AS listing_title,
+  ru.full_name AS requester_full_name
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN "user" AS ru ON ru.id = sr.requester_id
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'pending'
+  AND COALESCE(cp.pending_timeout, pp.pending_timeout, sqlc.arg(pending_timeout)::interval) > INTERVAL '0'
+  AND sr.updated_at + COALESCE(cp.pending_timeout, pp.pending_timeout, sqlc.arg(pending_timeout)::interval)
+      BETWEEN NOW() AND NOW() + make_interval(mins => sqlc.arg(lead_minutes)::int)
+  AND NOT EXISTS (
+    SELECT 1 FROM pending_request_reminder prr
+    WHERE prr.request_id = sr.id AND prr.lead_minutes <= sqlc.arg(lead_minutes)::int
+  )
+FOR UPDATE OF sr SKIP LOCKED;
+
+-- name: InsertPendingReminder :exec
+INSERT INTO pending_request_reminder (request_id, lead_minutes, sent_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetStalledRequests :many
+-- accepted requests neither side has confirmed within the in-progress
+-- timeout, counted from the end of the booked session if there is one
+SELECT
+  sr.id AS request_id,
+  sr.status_detail,
+  sr.requester_id,
+  sr.provider_id,
+  sl.title AS listing_title,
+  ru.full_name AS requester_full_name
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN "user" AS ru ON ru.id = sr.requester_id
+JOIN service_request_completion AS src ON src.request_id = sr.id
+LEFT JOIN service_request_slot AS rs ON rs.request_id = sr.id AND NOT rs.released
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'in_progress'
+  AND NOT src.requester_completed AND NOT src.provider_completed
+  AND COALESCE(cp.in_progress_timeout, pp.in_progress_timeout, sqlc.arg(in_progress_timeout)::interval) > INTERVAL '0'
+  AND GREATEST(sr.updated_at, rs.ends_at) + COALESCE(cp.in_progress_timeout, pp.in_progress_timeout, sqlc.arg(in_progress_timeout)::interval) < NOW()
+  AND NOT EXISTS (
+    SELECT 1 FROM payment p
+    WHERE p.service_request_id = sr.id AND p.status = 'disputed'
+  )
+FOR UPDATE OF sr SKIP LOCKED;
 
+-- name: GetHalfConfirmedRequests :many
+-- accepted requests one side confirmed and the other left unanswered for
+-- longer than the auto-complete delay
+SELECT
+  sr.id AS request_id,
+  sr.status_detail,
+  sr.requester_id,
+  sr.provider_id,
+  sl.title AS listing_title,
+  src.requester_completed
+FROM service_request AS sr
+JOIN service_listing AS sl ON sl.id = sr.listing_id
+JOIN service_request_completion AS src ON src.request_id = sr.id
+LEFT JOIN category AS c ON c.slug = sl.category
+LEFT JOIN category_expiry_policy AS cp ON cp.category_id = c.id
+LEFT JOIN category_expiry_policy AS pp ON pp.category_id = c.parent_id
+WHERE sr.status_detail = 'in_progress'
+  AND src.is_active
+  AND src.requester_completed <> src.provider_completed
+  AND COALESCE(cp.auto_complete_after, pp.auto_complete_after, sqlc.arg(auto_complete_after)::interval) > INTERVAL '0'
+  AND COALESCE(src.confirmed_at, sr.updated_at) + COALESCE(cp.auto_complete_after, pp.auto_complete_after, sqlc.arg(auto_complete_after)::interval) < NOW()
+  AND NOT EXISTS (
+    SELECT 1 FROM payment p
+    WHERE p.service_request_id = sr.id AND p.status = 'disputed'
+  )
+FOR UPDATE OF sr SKIP LOCKED;
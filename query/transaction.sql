@@ -4,3 +4,7 @@ SELECT
 $1,$2,p.amount_tokens,NOW()
 FROM payment p
 WHERE p.id = sqlc.arg(payment_id);
+
+-- name: InsertTransactionAmount :exec
+INSERT INTO "transaction" (user_id,type,amount,created_at)
+VALUES ($1,$2,$3,NOW());
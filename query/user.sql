@@ -64,20 +64,12 @@ DELETE FROM "user" where id = $1;
 SELECT token_balance FROM "user"
 WHERE id = $1;
 
--- name: AddTokens :one
+-- name: MarkSignupPaid :one
 UPDATE "user"
-SET token_balance = token_balance + $1
-WHERE id = $2
-RETURNING token_balance;
-
--- name: MarkSignupPaidAndAward :one
-UPDATE "user"
-SET
-  is_paid = true,
-  token_balance = token_balance + $2
+SET is_paid = true
 WHERE id = $1
   AND is_paid = false
-RETURNING token_balance;
+RETURNING id;
 
 
 -- name: UpdateAboutMe :exec
@@ -86,20 +78,6 @@ SET about_me = $1
 WHERE id = $2;
 
 
--- name: DeductTokens :execrows
-UPDATE "user"
-SET token_balance = token_balance - s.token_reward
-FROM service_listing s
-WHERE "user".id = sqlc.arg(user_id)
-  AND s.id = sqlc.arg(listing_id)
-  AND "user".token_balance >= s.token_reward;
-
--- name: DeductRewardTokensFromUser :exec
-UPDATE "user"
-SET token_balance = token_balance - r.cost
-FROM reward r
-WHERE "user".id = sqlc.arg(user_id) AND r.id = sqlc.arg(reward_id) AND "user".token_balance >= r.cost;
-
 -- name: UpdateUserFullNmae :execrows
 UPDATE "user"
 SET full_name = $1
@@ -111,3 +89,17 @@ SELECT full_name FROM "user"
 WHERE id = $1;
 
 
+
+-- name: UpdateUserStatus :execrows
+UPDATE "user"
+SET status = $1, suspended_until = $2
+WHERE id = $3;
+
+-- name: GetAccountStanding :one
+SELECT status, suspended_until FROM "user"
+WHERE id = $1;
+
+-- name: LiftExpiredSuspensions :execrows
+UPDATE "user"
+SET status = 'active', suspended_until = NULL
+WHERE status = 'suspended' AND suspended_until <= NOW();
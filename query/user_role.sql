@@ -0,0 +1,12 @@
+-- name: GetUserRoles :many
+SELECT role FROM user_role
+WHERE user_id = $1;
+
+-- name: GrantUserRole :exec
+INSERT INTO user_role (user_id, role, granted_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, role) DO NOTHING;
+
+-- name: RevokeUserRole :execrows
+DELETE FROM user_role
+WHERE user_id = $1 AND role = $2;
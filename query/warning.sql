@@ -0,0 +1,16 @@
+-- name: UpsertListingWarning :one
+-- a milder warning never replaces a severer one, whose takedown it would
+-- misdescribe; the returned severity is the one that holds.
+INSERT INTO warning (user_id, severity, created_at, reason, listing_id)
+SELECT sl.posted_by, $1, NOW(), $2, sl.id
+FROM service_listing sl
+WHERE sl.id = sqlc.arg(listing_id)
+ON CONFLICT (listing_id) DO UPDATE
+SET severity = GREATEST(warning.severity, EXCLUDED.severity),
+    reason = CASE WHEN EXCLUDED.severity >= warning.severity THEN EXCLUDED.reason ELSE warning.reason END,
+    created_at = CASE WHEN EXCLUDED.severity >= warning.severity THEN EXCLUDED.created_at ELSE warning.created_at END
+RETURNING id, user_id, severity;
+
+-- name: DeleteListingWarning :exec
+DELETE FROM warning
+WHERE listing_id = $1;
@@ -25,6 +25,20 @@ SET row_security = off;
 COMMENT ON SCHEMA public IS '';
 
 
+--
+-- Name: btree_gist; Type: EXTENSION; Schema: -; Owner: -
+--
+
+CREATE EXTENSION IF NOT EXISTS btree_gist WITH SCHEMA public;
+
+
+--
+-- Name: EXTENSION btree_gist; Type: COMMENT; Schema: -; Owner: -
+--
+
+COMMENT ON EXTENSION btree_gist IS 'support for indexing common datatypes in GiST';
+
+
 --
 -- Name: account_status; Type: TYPE; Schema: public; Owner: -
 --
@@ -36,6 +50,30 @@ CREATE TYPE public.account_status AS ENUM (
 );
 
 
+--
+-- Name: job_run_status; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.job_run_status AS ENUM (
+    'running',
+    'succeeded',
+    'failed',
+    'abandoned'
+);
+
+
+--
+-- Name: ledger_account_type; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.ledger_account_type AS ENUM (
+    'user_wallet',
+    'escrow',
+    'system_mint',
+    'reward_sink'
+);
+
+
 --
 -- Name: payment_status; Type: TYPE; Schema: public; Owner: -
 --
@@ -44,7 +82,19 @@ CREATE TYPE public.payment_status AS ENUM (
     'initiated',
     'holding',
     'released',
-    'refunded'
+    'refunded',
+    'split',
+    'disputed'
+);
+
+
+--
+-- Name: role; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.role AS ENUM (
+    'moderator',
+    'admin'
 );
 
 
@@ -108,6 +158,19 @@ CREATE TYPE public.warning_severity AS ENUM (
 );
 
 
+--
+-- Name: reject_journal_mutation(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.reject_journal_mutation() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    RAISE EXCEPTION 'journal rows are immutable: % on %', TG_OP, TG_TABLE_NAME;
+END;
+$$;
+
+
 SET default_tablespace = '';
 
 SET default_table_access_method = heap;
@@ -119,7 +182,9 @@ SET default_table_access_method = heap;
 CREATE TABLE public.ads_watching_history (
     id integer NOT NULL,
     user_id text NOT NULL,
-    date_time timestamptz NOT NULL
+    date_time timestamptz NOT NULL,
+    ad_unit text,
+    tokens integer DEFAULT 1 NOT NULL
 );
 
 
@@ -143,6 +208,117 @@ CREATE SEQUENCE public.ads_watching_history_id_seq
 ALTER SEQUENCE public.ads_watching_history_id_seq OWNED BY public.ads_watching_history.id;
 
 
+--
+-- Name: availability_exception; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.availability_exception (
+    id integer NOT NULL,
+    provider_id text NOT NULL,
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    reason text,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    CONSTRAINT availability_exception_range_check CHECK ((starts_at < ends_at))
+);
+
+
+--
+-- Name: availability_exception_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.availability_exception ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.availability_exception_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: availability_rule; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.availability_rule (
+    id integer NOT NULL,
+    provider_id text NOT NULL,
+    weekday smallint NOT NULL,
+    start_time time without time zone NOT NULL,
+    end_time time without time zone NOT NULL,
+    timezone text NOT NULL,
+    CONSTRAINT availability_rule_range_check CHECK ((start_time < end_time)),
+    CONSTRAINT availability_rule_weekday_check CHECK (((weekday >= 0) AND (weekday <= 6)))
+);
+
+
+--
+-- Name: availability_rule_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.availability_rule ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.availability_rule_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: calendar_feed; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.calendar_feed (
+    user_id text NOT NULL,
+    token text NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: category; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.category (
+    id integer NOT NULL,
+    slug text NOT NULL,
+    name text NOT NULL,
+    parent_id integer,
+    icon text,
+    sort_order integer DEFAULT 0 NOT NULL
+);
+
+
+--
+-- Name: category_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.category ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.category_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: category_expiry_policy; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.category_expiry_policy (
+    category_id integer NOT NULL,
+    pending_timeout interval,
+    in_progress_timeout interval,
+    auto_complete_after interval,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+
 --
 -- Name: coupon_code; Type: TABLE; Schema: public; Owner: -
 --
@@ -188,6 +364,177 @@ CREATE TABLE public.event (
 );
 
 
+--
+-- Name: idempotency_key; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.idempotency_key (
+    user_id text NOT NULL,
+    key text NOT NULL,
+    request_hash text NOT NULL,
+    status_code integer,
+    content_type text,
+    response_body bytea,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    completed_at timestamptz
+);
+
+
+--
+-- Name: job_run; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.job_run (
+    id bigint NOT NULL,
+    job text NOT NULL,
+    instance text NOT NULL,
+    status public.job_run_status DEFAULT 'running'::public.job_run_status NOT NULL,
+    started_at timestamptz DEFAULT now() NOT NULL,
+    finished_at timestamptz,
+    error text
+);
+
+
+--
+-- Name: job_run_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.job_run ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.job_run_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: journal_entry; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.journal_entry (
+    id bigint NOT NULL,
+    kind text NOT NULL,
+    memo text NOT NULL,
+    reference_type text,
+    reference_id integer,
+    created_at timestamptz NOT NULL
+);
+
+
+--
+-- Name: journal_entry_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.journal_entry ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.journal_entry_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: journal_line; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.journal_line (
+    id bigint NOT NULL,
+    entry_id bigint NOT NULL,
+    account_id integer NOT NULL,
+    amount integer NOT NULL,
+    CONSTRAINT journal_line_amount_check CHECK ((amount <> 0))
+);
+
+
+--
+-- Name: journal_line_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.journal_line ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.journal_line_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: ledger_account; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ledger_account (
+    id integer NOT NULL,
+    code text NOT NULL,
+    type public.ledger_account_type NOT NULL,
+    user_id text,
+    created_at timestamptz NOT NULL
+);
+
+
+--
+-- Name: ledger_account_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.ledger_account ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY (
+    SEQUENCE NAME public.ledger_account_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: listing_appeal; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.listing_appeal (
+    id integer NOT NULL,
+    listing_id integer NOT NULL,
+    appellant_id text NOT NULL,
+    reason text NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    decided_by text,
+    decision_note text,
+    decided_at timestamptz
+);
+
+
+--
+-- Name: listing_appeal_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.listing_appeal ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.listing_appeal_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
+--
+-- Name: listing_location; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.listing_location (
+    listing_id integer NOT NULL,
+    latitude double precision NOT NULL,
+    longitude double precision NOT NULL,
+    service_radius_km double precision,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+
 --
 -- Name: notification; Type: TABLE; Schema: public; Owner: -
 --
@@ -198,7 +545,9 @@ CREATE TABLE public.notification (
     recipient_user_id text NOT NULL,
     action_user_id text,
     is_read boolean NOT NULL,
-    event_id bigint NOT NULL
+    event_id bigint NOT NULL,
+    email_pending boolean DEFAULT false NOT NULL,
+    archived_at timestamptz
 );
 
 
@@ -230,6 +579,54 @@ ALTER TABLE public.notification ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDEN
 );
 
 
+--
+-- Name: notification_preference; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.notification_preference (
+    user_id text NOT NULL,
+    event_type text NOT NULL,
+    description text DEFAULT ''::text NOT NULL,
+    in_app boolean NOT NULL,
+    push boolean NOT NULL,
+    email boolean NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL,
+    CONSTRAINT notification_preference_channels_check CHECK (((in_app OR ((NOT push) AND (NOT email)))))
+);
+
+
+--
+-- Name: outbox; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.outbox (
+    id bigint NOT NULL,
+    channel text NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt_at timestamptz DEFAULT now() NOT NULL,
+    last_error text,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    delivered_at timestamptz,
+    dead_at timestamptz
+);
+
+
+--
+-- Name: outbox_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.outbox ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.outbox_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
 --
 -- Name: payment; Type: TABLE; Schema: public; Owner: -
 --
@@ -265,6 +662,17 @@ CREATE SEQUENCE public.payments_id_seq
 ALTER SEQUENCE public.payments_id_seq OWNED BY public.payment.id;
 
 
+--
+-- Name: pending_request_reminder; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.pending_request_reminder (
+    request_id integer NOT NULL,
+    lead_minutes integer NOT NULL,
+    sent_at timestamptz NOT NULL
+);
+
+
 --
 -- Name: rating; Type: TABLE; Schema: public; Owner: -
 --
@@ -321,7 +729,9 @@ CREATE TABLE public.report (
     datetime timestamptz NOT NULL,
     report_reason text,
     status text NOT NULL,
-    additional_detail text
+    additional_detail text,
+    reviewed_by text,
+    reviewed_at timestamptz
 );
 
 
@@ -339,6 +749,34 @@ ALTER TABLE public.report ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY (
 );
 
 
+--
+-- Name: request_message; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.request_message (
+    id integer NOT NULL,
+    request_id integer NOT NULL,
+    sender_id text NOT NULL,
+    body text NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    read_at timestamptz
+);
+
+
+--
+-- Name: request_message_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.request_message ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.request_message_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
 --
 -- Name: request_report; Type: TABLE; Schema: public; Owner: -
 --
@@ -350,7 +788,14 @@ CREATE TABLE public.request_report (
     ticket_id text NOT NULL,
     created_at timestamptz NOT NULL,
     status text NOT NULL,
-    updated_at timestamptz NOT NULL
+    updated_at timestamptz NOT NULL,
+    description text DEFAULT ''::text NOT NULL,
+    evidence_urls text[] DEFAULT '{}'::text[] NOT NULL,
+    resolution text,
+    requester_share integer,
+    resolution_note text,
+    resolved_by text,
+    resolved_at timestamptz
 );
 
 
@@ -374,6 +819,34 @@ CREATE SEQUENCE public.request_issues_id_seq
 ALTER SEQUENCE public.request_issues_id_seq OWNED BY public.request_report.id;
 
 
+--
+-- Name: request_report_message; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.request_report_message (
+    id integer NOT NULL,
+    report_id integer NOT NULL,
+    author_id text NOT NULL,
+    body text NOT NULL,
+    evidence_urls text[] DEFAULT '{}'::text[] NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: request_report_message_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+ALTER TABLE public.request_report_message ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public.request_report_message_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+
 --
 -- Name: requests_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
@@ -520,7 +993,22 @@ CREATE TABLE public.service_request_completion (
     request_id integer NOT NULL,
     requester_completed boolean NOT NULL,
     provider_completed boolean NOT NULL,
-    is_active boolean NOT NULL
+    is_active boolean NOT NULL,
+    confirmed_at timestamptz
+);
+
+
+--
+-- Name: service_request_slot; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.service_request_slot (
+    request_id integer NOT NULL,
+    provider_id text NOT NULL,
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    released boolean DEFAULT false NOT NULL,
+    reminded_at timestamptz
 );
 
 
@@ -596,7 +1084,34 @@ CREATE TABLE public."user" (
     is_email_signedup boolean NOT NULL,
     full_name text NOT NULL,
     is_paid boolean NOT NULL,
-    about_me text
+    about_me text,
+    suspended_until timestamptz
+);
+
+
+--
+-- Name: user_location; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.user_location (
+    user_id text NOT NULL,
+    latitude double precision NOT NULL,
+    longitude double precision NOT NULL,
+    service_radius_km double precision,
+    source text NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: user_role; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.user_role (
+    user_id text NOT NULL,
+    role public.role NOT NULL,
+    granted_by text,
+    granted_at timestamptz DEFAULT now() NOT NULL
 );
 
 
@@ -697,70 +1212,206 @@ ALTER TABLE ONLY public.review ALTER COLUMN id SET DEFAULT nextval('public.revie
 
 
 --
--- Name: reward id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: reward id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.reward ALTER COLUMN id SET DEFAULT nextval('public.rewards_id_seq'::regclass);
+
+
+--
+-- Name: service_listing id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.service_listing ALTER COLUMN id SET DEFAULT nextval('public.service_listings_id_seq'::regclass);
+
+
+--
+-- Name: service_request_completion id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.service_request_completion ALTER COLUMN id SET DEFAULT nextval('public.services_request_completion_id_seq'::regclass);
+
+
+--
+-- Name: transaction id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.transaction ALTER COLUMN id SET DEFAULT nextval('public.transactions_id_seq'::regclass);
+
+
+--
+-- Name: warning id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.warning ALTER COLUMN id SET DEFAULT nextval('public.warning_id_seq'::regclass);
+
+
+--
+-- Name: ads_watching_history ads_watching_history_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ads_watching_history
+    ADD CONSTRAINT ads_watching_history_pk PRIMARY KEY (id);
+
+
+--
+-- Name: availability_exception availability_exception_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.availability_exception
+    ADD CONSTRAINT availability_exception_pk PRIMARY KEY (id);
+
+
+--
+-- Name: availability_rule availability_rule_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.availability_rule
+    ADD CONSTRAINT availability_rule_pk PRIMARY KEY (id);
+
+
+--
+-- Name: calendar_feed calendar_feed_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.calendar_feed
+    ADD CONSTRAINT calendar_feed_pk PRIMARY KEY (user_id);
+
+
+--
+-- Name: calendar_feed calendar_feed_token_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.calendar_feed
+    ADD CONSTRAINT calendar_feed_token_key UNIQUE (token);
+
+
+--
+-- Name: category category_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.category
+    ADD CONSTRAINT category_pk PRIMARY KEY (id);
+
+
+--
+-- Name: category category_slug_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.category
+    ADD CONSTRAINT category_slug_key UNIQUE (slug);
+
+
+--
+-- Name: category_expiry_policy category_expiry_policy_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.category_expiry_policy
+    ADD CONSTRAINT category_expiry_policy_pk PRIMARY KEY (category_id);
+
+
+--
+-- Name: coupon_code coupon_codes_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.coupon_code
+    ADD CONSTRAINT coupon_codes_pk PRIMARY KEY (id);
+
+
+--
+-- Name: event notification_events_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event
+    ADD CONSTRAINT notification_events_pk PRIMARY KEY (id);
+
+
+--
+-- Name: idempotency_key idempotency_key_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.idempotency_key
+    ADD CONSTRAINT idempotency_key_pk PRIMARY KEY (user_id, key);
+
+
+--
+-- Name: job_run job_run_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.job_run
+    ADD CONSTRAINT job_run_pk PRIMARY KEY (id);
+
+
+--
+-- Name: journal_entry journal_entry_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.reward ALTER COLUMN id SET DEFAULT nextval('public.rewards_id_seq'::regclass);
+ALTER TABLE ONLY public.journal_entry
+    ADD CONSTRAINT journal_entry_pk PRIMARY KEY (id);
 
 
 --
--- Name: service_listing id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: journal_line journal_line_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.service_listing ALTER COLUMN id SET DEFAULT nextval('public.service_listings_id_seq'::regclass);
+ALTER TABLE ONLY public.journal_line
+    ADD CONSTRAINT journal_line_pk PRIMARY KEY (id);
 
 
 --
--- Name: service_request_completion id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: ledger_account ledger_account_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.service_request_completion ALTER COLUMN id SET DEFAULT nextval('public.services_request_completion_id_seq'::regclass);
+ALTER TABLE ONLY public.ledger_account
+    ADD CONSTRAINT ledger_account_pk PRIMARY KEY (id);
 
 
 --
--- Name: transaction id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: ledger_account ledger_account_code_unique; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.transaction ALTER COLUMN id SET DEFAULT nextval('public.transactions_id_seq'::regclass);
+ALTER TABLE ONLY public.ledger_account
+    ADD CONSTRAINT ledger_account_code_unique UNIQUE (code);
 
 
 --
--- Name: warning id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: listing_appeal listing_appeal_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.warning ALTER COLUMN id SET DEFAULT nextval('public.warning_id_seq'::regclass);
+ALTER TABLE ONLY public.listing_appeal
+    ADD CONSTRAINT listing_appeal_pk PRIMARY KEY (id);
 
 
 --
--- Name: ads_watching_history ads_watching_history_pk; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: listing_location listing_location_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.ads_watching_history
-    ADD CONSTRAINT ads_watching_history_pk PRIMARY KEY (id);
+ALTER TABLE ONLY public.listing_location
+    ADD CONSTRAINT listing_location_pk PRIMARY KEY (listing_id);
 
 
 --
--- Name: coupon_code coupon_codes_pk; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: notification notifications_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.coupon_code
-    ADD CONSTRAINT coupon_codes_pk PRIMARY KEY (id);
+ALTER TABLE ONLY public.notification
+    ADD CONSTRAINT notifications_pk PRIMARY KEY (id);
 
 
 --
--- Name: event notification_events_pk; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: notification_preference notification_preference_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.event
-    ADD CONSTRAINT notification_events_pk PRIMARY KEY (id);
+ALTER TABLE ONLY public.notification_preference
+    ADD CONSTRAINT notification_preference_pk PRIMARY KEY (user_id, event_type, description);
 
 
 --
--- Name: notification notifications_pk; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: outbox outbox_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.notification
-    ADD CONSTRAINT notifications_pk PRIMARY KEY (id);
+ALTER TABLE ONLY public.outbox
+    ADD CONSTRAINT outbox_pk PRIMARY KEY (id);
 
 
 --
@@ -771,6 +1422,14 @@ ALTER TABLE ONLY public.payment
     ADD CONSTRAINT payments_pk PRIMARY KEY (id);
 
 
+--
+-- Name: pending_request_reminder pending_request_reminder_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pending_request_reminder
+    ADD CONSTRAINT pending_request_reminder_pk PRIMARY KEY (request_id, lead_minutes);
+
+
 --
 -- Name: rating ratings_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -803,6 +1462,14 @@ ALTER TABLE ONLY public.report
     ADD CONSTRAINT reports_unique UNIQUE (reporter_id, listing_id);
 
 
+--
+-- Name: request_message request_message_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_message
+    ADD CONSTRAINT request_message_pk PRIMARY KEY (id);
+
+
 --
 -- Name: request_report request_issues_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -819,6 +1486,14 @@ ALTER TABLE ONLY public.request_report
     ADD CONSTRAINT request_issues_unique UNIQUE (ticket_id);
 
 
+--
+-- Name: request_report_message request_report_message_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_report_message
+    ADD CONSTRAINT request_report_message_pk PRIMARY KEY (id);
+
+
 --
 -- Name: service_request requests_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -859,6 +1534,22 @@ ALTER TABLE ONLY public.service_listing
     ADD CONSTRAINT service_listings_pk PRIMARY KEY (id);
 
 
+--
+-- Name: service_request_slot service_request_slot_no_overlap; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.service_request_slot
+    ADD CONSTRAINT service_request_slot_no_overlap EXCLUDE USING gist (provider_id WITH =, tstzrange(starts_at, ends_at) WITH &&) WHERE ((NOT released));
+
+
+--
+-- Name: service_request_slot service_request_slot_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.service_request_slot
+    ADD CONSTRAINT service_request_slot_pk PRIMARY KEY (request_id);
+
+
 --
 -- Name: service_request_completion services_request_completion_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -899,6 +1590,22 @@ ALTER TABLE ONLY public."user"
     ADD CONSTRAINT users_unique UNIQUE (id);
 
 
+--
+-- Name: user_location user_location_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_location
+    ADD CONSTRAINT user_location_pk PRIMARY KEY (user_id);
+
+
+--
+-- Name: user_role user_role_pk; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_role
+    ADD CONSTRAINT user_role_pk PRIMARY KEY (user_id, role);
+
+
 --
 -- Name: warning warning_pk; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -907,6 +1614,27 @@ ALTER TABLE ONLY public.warning
     ADD CONSTRAINT warning_pk PRIMARY KEY (id);
 
 
+--
+-- Name: idx_ads_watching_history_user_id_date_time; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ads_watching_history_user_id_date_time ON public.ads_watching_history USING btree (user_id, date_time);
+
+
+--
+-- Name: idx_availability_exception_provider_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_availability_exception_provider_id ON public.availability_exception USING btree (provider_id, starts_at);
+
+
+--
+-- Name: idx_availability_rule_provider_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_availability_rule_provider_id ON public.availability_rule USING btree (provider_id);
+
+
 --
 -- Name: idx_events_target_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -914,6 +1642,48 @@ ALTER TABLE ONLY public.warning
 CREATE INDEX idx_events_target_id ON public.event USING btree (target_id);
 
 
+--
+-- Name: idx_idempotency_key_created_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_idempotency_key_created_at ON public.idempotency_key USING btree (created_at);
+
+
+--
+-- Name: idx_job_run_job_started_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_job_run_job_started_at ON public.job_run USING btree (job, started_at DESC);
+
+
+--
+-- Name: idx_journal_line_account_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_journal_line_account_id ON public.journal_line USING btree (account_id);
+
+
+--
+-- Name: idx_journal_line_entry_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_journal_line_entry_id ON public.journal_line USING btree (entry_id);
+
+
+--
+-- Name: idx_ledger_account_user_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_ledger_account_user_id ON public.ledger_account USING btree (user_id);
+
+
+--
+-- Name: idx_listing_appeal_pending; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_listing_appeal_pending ON public.listing_appeal USING btree (listing_id) WHERE (status = 'pending'::text);
+
+
 --
 -- Name: idx_notification_recipient_user_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -921,6 +1691,34 @@ CREATE INDEX idx_events_target_id ON public.event USING btree (target_id);
 CREATE INDEX idx_notification_recipient_user_id ON public.notification USING btree (recipient_user_id);
 
 
+--
+-- Name: idx_notification_email_pending; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_notification_email_pending ON public.notification USING btree (recipient_user_id) WHERE email_pending;
+
+
+--
+-- Name: idx_outbox_pending; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_outbox_pending ON public.outbox USING btree (next_attempt_at) WHERE ((delivered_at IS NULL) AND (dead_at IS NULL));
+
+
+--
+-- Name: idx_request_message_request_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_request_message_request_id ON public.request_message USING btree (request_id, id DESC);
+
+
+--
+-- Name: idx_request_report_message_report_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_request_report_message_report_id ON public.request_report_message USING btree (report_id);
+
+
 --
 -- Name: idx_service_completion_request_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -928,6 +1726,27 @@ CREATE INDEX idx_notification_recipient_user_id ON public.notification USING btr
 CREATE INDEX idx_service_completion_request_id ON public.service_request_completion USING btree (request_id);
 
 
+--
+-- Name: idx_service_listing_active_posted_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_service_listing_active_posted_at ON public.service_listing USING btree (posted_at DESC, id DESC) WHERE (status = 'active'::text);
+
+
+--
+-- Name: idx_service_listing_search; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_service_listing_search ON public.service_listing USING gin (((setweight(to_tsvector('english'::regconfig, title), 'A'::"char") || setweight(to_tsvector('english'::regconfig, category), 'B'::"char")) || setweight(to_tsvector('english'::regconfig, description), 'C'::"char")));
+
+
+--
+-- Name: idx_service_request_slot_reminder; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_service_request_slot_reminder ON public.service_request_slot USING btree (starts_at) WHERE ((NOT released) AND (reminded_at IS NULL));
+
+
 --
 -- Name: idx_service_requests_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -956,6 +1775,20 @@ CREATE INDEX idx_service_requests_provider_id ON public.service_request USING bt
 CREATE INDEX idx_service_requests_requester_id ON public.service_request USING btree (requester_id);
 
 
+--
+-- Name: journal_entry journal_entry_immutable; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER journal_entry_immutable BEFORE DELETE OR UPDATE ON public.journal_entry FOR EACH ROW EXECUTE FUNCTION public.reject_journal_mutation();
+
+
+--
+-- Name: journal_line journal_line_immutable; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER journal_line_immutable BEFORE DELETE OR UPDATE ON public.journal_line FOR EACH ROW EXECUTE FUNCTION public.reject_journal_mutation();
+
+
 --
 -- Name: ads_watching_history ads_watching_history_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -964,6 +1797,46 @@ ALTER TABLE ONLY public.ads_watching_history
     ADD CONSTRAINT ads_watching_history_users_fk FOREIGN KEY (user_id) REFERENCES public."user"(id) ON DELETE CASCADE;
 
 
+--
+-- Name: availability_exception availability_exception_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.availability_exception
+    ADD CONSTRAINT availability_exception_users_fk FOREIGN KEY (provider_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
+--
+-- Name: availability_rule availability_rule_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.availability_rule
+    ADD CONSTRAINT availability_rule_users_fk FOREIGN KEY (provider_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
+--
+-- Name: calendar_feed calendar_feed_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.calendar_feed
+    ADD CONSTRAINT calendar_feed_users_fk FOREIGN KEY (user_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
+--
+-- Name: category category_parent_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.category
+    ADD CONSTRAINT category_parent_fk FOREIGN KEY (parent_id) REFERENCES public.category(id);
+
+
+--
+-- Name: category_expiry_policy category_expiry_policy_category_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.category_expiry_policy
+    ADD CONSTRAINT category_expiry_policy_category_fk FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
 --
 -- Name: coupon_code coupon_codes_rewards_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -972,6 +1845,54 @@ ALTER TABLE ONLY public.coupon_code
     ADD CONSTRAINT coupon_codes_rewards_fk FOREIGN KEY (reward_id) REFERENCES public.reward(id) ON UPDATE CASCADE ON DELETE CASCADE;
 
 
+--
+-- Name: journal_line journal_line_journal_entry_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.journal_line
+    ADD CONSTRAINT journal_line_journal_entry_fk FOREIGN KEY (entry_id) REFERENCES public.journal_entry(id);
+
+
+--
+-- Name: journal_line journal_line_ledger_account_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.journal_line
+    ADD CONSTRAINT journal_line_ledger_account_fk FOREIGN KEY (account_id) REFERENCES public.ledger_account(id);
+
+
+--
+-- Name: ledger_account ledger_account_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ledger_account
+    ADD CONSTRAINT ledger_account_users_fk FOREIGN KEY (user_id) REFERENCES public."user"(id) ON DELETE SET NULL;
+
+
+--
+-- Name: listing_appeal listing_appeal_service_listing_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.listing_appeal
+    ADD CONSTRAINT listing_appeal_service_listing_fk FOREIGN KEY (listing_id) REFERENCES public.service_listing(id) ON DELETE CASCADE;
+
+
+--
+-- Name: listing_appeal listing_appeal_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.listing_appeal
+    ADD CONSTRAINT listing_appeal_users_fk FOREIGN KEY (appellant_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
+--
+-- Name: listing_location listing_location_service_listing_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.listing_location
+    ADD CONSTRAINT listing_location_service_listing_fk FOREIGN KEY (listing_id) REFERENCES public.service_listing(id) ON DELETE CASCADE;
+
+
 --
 -- Name: notification notifications_notification_events_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -996,6 +1917,14 @@ ALTER TABLE ONLY public.notification
     ADD CONSTRAINT notifications_users_recipient_fk FOREIGN KEY (recipient_user_id) REFERENCES public."user"(id) ON DELETE CASCADE;
 
 
+--
+-- Name: notification_preference notification_preference_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.notification_preference
+    ADD CONSTRAINT notification_preference_users_fk FOREIGN KEY (user_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
 --
 -- Name: payment payments_service_requests_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1012,6 +1941,14 @@ ALTER TABLE ONLY public.payment
     ADD CONSTRAINT payments_users_fk FOREIGN KEY (payer_id) REFERENCES public."user"(id) ON DELETE CASCADE;
 
 
+--
+-- Name: pending_request_reminder pending_request_reminder_service_request_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pending_request_reminder
+    ADD CONSTRAINT pending_request_reminder_service_request_fk FOREIGN KEY (request_id) REFERENCES public.service_request(id) ON DELETE CASCADE;
+
+
 --
 -- Name: rating ratings_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1060,6 +1997,22 @@ ALTER TABLE ONLY public.report
     ADD CONSTRAINT reports_users_fk FOREIGN KEY (reporter_id) REFERENCES public."user"(id) ON UPDATE CASCADE ON DELETE CASCADE;
 
 
+--
+-- Name: request_message request_message_service_request_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_message
+    ADD CONSTRAINT request_message_service_request_fk FOREIGN KEY (request_id) REFERENCES public.service_request(id) ON DELETE CASCADE;
+
+
+--
+-- Name: request_message request_message_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_message
+    ADD CONSTRAINT request_message_users_fk FOREIGN KEY (sender_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
 --
 -- Name: request_report request_issues_service_requests_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1076,6 +2029,22 @@ ALTER TABLE ONLY public.request_report
     ADD CONSTRAINT request_issues_users_fk FOREIGN KEY (reporter_id) REFERENCES public."user"(id) ON UPDATE CASCADE ON DELETE CASCADE;
 
 
+--
+-- Name: request_report_message request_report_message_request_report_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_report_message
+    ADD CONSTRAINT request_report_message_request_report_fk FOREIGN KEY (report_id) REFERENCES public.request_report(id) ON DELETE CASCADE;
+
+
+--
+-- Name: request_report_message request_report_message_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_report_message
+    ADD CONSTRAINT request_report_message_users_fk FOREIGN KEY (author_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
 --
 -- Name: review reviews_service_requests_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1100,6 +2069,14 @@ ALTER TABLE ONLY public.review
     ADD CONSTRAINT reviews_users_fk_1 FOREIGN KEY (reviewee_id) REFERENCES public."user"(id) ON DELETE CASCADE;
 
 
+--
+-- Name: service_listing service_listing_category_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.service_listing
+    ADD CONSTRAINT service_listing_category_fk FOREIGN KEY (category) REFERENCES public.category(slug) ON UPDATE CASCADE;
+
+
 --
 -- Name: service_listing service_listings_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1124,6 +2101,14 @@ ALTER TABLE ONLY public.service_request
     ADD CONSTRAINT service_requests_users_fk_1 FOREIGN KEY (requester_id) REFERENCES public."user"(id) ON DELETE CASCADE;
 
 
+--
+-- Name: service_request_slot service_request_slot_service_request_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.service_request_slot
+    ADD CONSTRAINT service_request_slot_service_request_fk FOREIGN KEY (request_id) REFERENCES public.service_request(id) ON DELETE CASCADE;
+
+
 --
 -- Name: service_request_completion services_request_completion_service_requests_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1140,6 +2125,22 @@ ALTER TABLE ONLY public.transaction
     ADD CONSTRAINT transactions_users_fk FOREIGN KEY (user_id) REFERENCES public."user"(id) ON DELETE CASCADE;
 
 
+--
+-- Name: user_location user_location_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_location
+    ADD CONSTRAINT user_location_users_fk FOREIGN KEY (user_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
+--
+-- Name: user_role user_role_users_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_role
+    ADD CONSTRAINT user_role_users_fk FOREIGN KEY (user_id) REFERENCES public."user"(id) ON DELETE CASCADE;
+
+
 --
 -- Name: warning warning_service_listing_fk; Type: FK CONSTRAINT; Schema: public; Owner: -
 --